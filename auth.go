@@ -0,0 +1,204 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/auth"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// principalHeader carries the principal authenticated by a verified client certificate to the MCP handlers.
+//
+// The header is always removed from incoming requests before authentication, so clients cannot set it.
+const principalHeader = "Mcp-Sequential-Thinking-Principal"
+
+// tokenLifetime is the expiration reported for static bearer tokens, which do not expire themselves.
+const tokenLifetime = time.Hour
+
+// authConfig authenticates the clients of the streamable HTTP endpoint.
+type authConfig struct {
+	// tokens maps the SHA-256 digest of each accepted bearer token to its principal.
+	tokens map[[sha256.Size]byte]string
+
+	certFile  string
+	keyFile   string
+	clientCAs *x509.CertPool
+}
+
+// loadAuthConfig loads the bearer tokens at tokensPath and the client CA certificates at clientCAPath.
+// Every argument is optional, but a key file must come with a certificate file and client
+// certificate verification requires TLS.
+func loadAuthConfig(tokensPath, certFile, keyFile, clientCAPath string) (*authConfig, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("-tls-cert and -tls-key must be set together")
+	}
+	if clientCAPath != "" && certFile == "" {
+		return nil, errors.New("-tls-client-ca requires -tls-cert and -tls-key")
+	}
+
+	ac := &authConfig{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if tokensPath != "" {
+		f, err := os.Open(tokensPath)
+		if err != nil {
+			return nil, fmt.Errorf("open auth tokens: %w", err)
+		}
+		defer f.Close()
+
+		ac.tokens, err = readTokens(f)
+		if err != nil {
+			return nil, fmt.Errorf("read auth tokens %q: %w", tokensPath, err)
+		}
+	}
+	if clientCAPath != "" {
+		data, err := os.ReadFile(clientCAPath)
+		if err != nil {
+			return nil, fmt.Errorf("read client CA: %w", err)
+		}
+		ac.clientCAs = x509.NewCertPool()
+		if !ac.clientCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("read client CA %q: no PEM certificates", clientCAPath)
+		}
+	}
+
+	return ac, nil
+}
+
+// readTokens reads bearer tokens, one "<principal> <token>" pair per line.
+// Empty lines and lines starting with # are ignored.
+func readTokens(r io.Reader) (map[[sha256.Size]byte]string, error) {
+	tokens := make(map[[sha256.Size]byte]string)
+	sc := bufio.NewScanner(r)
+	for lineno := 1; sc.Scan(); lineno++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want \"<principal> <token>\"", lineno)
+		}
+		digest := sha256.Sum256([]byte(fields[1]))
+		if _, ok := tokens[digest]; ok {
+			return nil, fmt.Errorf("line %d: duplicate token", lineno)
+		}
+		tokens[digest] = fields[0]
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("no tokens")
+	}
+	return tokens, nil
+}
+
+// tls reports whether the endpoint is served over TLS.
+func (ac *authConfig) tls() bool {
+	return ac.certFile != ""
+}
+
+// tlsConfig returns the TLS configuration of the endpoint, requiring verified client certificates if client CAs are set.
+func (ac *authConfig) tlsConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if ac.clientCAs != nil {
+		cfg.ClientCAs = ac.clientCAs
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg
+}
+
+// verifyToken is an [auth.TokenVerifier] accepting the configured static tokens.
+// The principal of the token is reported as the user ID, which the SDK also uses to bind the MCP session to it.
+func (ac *authConfig) verifyToken(_ context.Context, token string, _ *http.Request) (*auth.TokenInfo, error) {
+	principal, ok := ac.tokens[sha256.Sum256([]byte(token))]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown bearer token", auth.ErrInvalidToken)
+	}
+
+	return &auth.TokenInfo{
+		UserID:     principal,
+		Expiration: time.Now().Add(tokenLifetime),
+	}, nil
+}
+
+// handler wraps h with the configured authentication.
+//
+// With tokens, requests without a known bearer token are rejected with 401 Unauthorized.
+// With client CAs, the TLS handshake already rejected unverified clients and the principal of the
+// certificate is passed on in [principalHeader].
+func (ac *authConfig) handler(h http.Handler) http.Handler {
+	if ac.tokens != nil {
+		h = auth.RequireBearerToken(ac.verifyToken, nil)(h)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del(principalHeader)
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			if principal := certPrincipal(r.TLS.VerifiedChains[0][0]); principal != "" {
+				r.Header.Set(principalHeader, principal)
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// certPrincipal returns the principal named by a client certificate: its subject common name,
+// or else its first URI, DNS or email subject alternative name.
+func certPrincipal(cert *x509.Certificate) string {
+	switch {
+	case cert.Subject.CommonName != "":
+		return cert.Subject.CommonName
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0]
+	}
+	return ""
+}
+
+// requestPrincipal returns the principal authenticated for the request, or "" if the request is not authenticated.
+//
+// A bearer token takes precedence over a client certificate.
+func requestPrincipal[P mcp.Params](request *mcp.ServerRequest[P]) string {
+	if request == nil || request.Extra == nil {
+		return ""
+	}
+	if ti := request.Extra.TokenInfo; ti != nil && ti.UserID != "" {
+		return ti.UserID
+	}
+	return request.Extra.Header.Get(principalHeader)
+}
@@ -0,0 +1,475 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/modelcontextprotocol/go-sdk/auth"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// headerTransport sets header on every request before sending it with base.
+type headerTransport struct {
+	base   http.RoundTripper
+	header http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.header {
+		req.Header[k] = v
+	}
+	return t.base.RoundTrip(req)
+}
+
+// connectAuthClient connects to the MCP endpoint with httpClient.
+func connectAuthClient(t *testing.T, endpoint string, httpClient *http.Client) (*mcp.ClientSession, error) {
+	t.Helper()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "v0.0.0"}, nil)
+	cs, err := client.Connect(t.Context(), &mcp.StreamableClientTransport{
+		Endpoint:             endpoint,
+		HTTPClient:           httpClient,
+		DisableStandaloneSSE: true,
+	}, nil)
+	if err == nil {
+		t.Cleanup(func() { cs.Close() })
+	}
+	return cs, err
+}
+
+// newAuthHandler returns the streamable HTTP handler serving thinking behind ac.
+func newAuthHandler(t *testing.T, thinking *SequentialThinkingServer, ac *authConfig) http.Handler {
+	t.Helper()
+
+	srv, err := newServer(slog.New(slog.DiscardHandler), thinking, serverOptions{})
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	return ac.handler(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return srv }, nil))
+}
+
+// principals returns the principal recorded on each thought of the only session of thinking.
+func principals(t *testing.T, thinking *SequentialThinkingServer) []string {
+	t.Helper()
+
+	ids := thinking.SessionIDs()
+	if diff := cmp.Diff(1, len(ids)); diff != "" {
+		t.Fatalf("sessions length mismatch (-want +got):\n%s", diff)
+	}
+	snap, _ := thinking.Snapshot(ids[0])
+	got := []string{snap.Principal}
+	for _, record := range snap.Thoughts {
+		got = append(got, record.Principal)
+	}
+	return got
+}
+
+func TestReadTokens(t *testing.T) {
+	tests := map[string]struct {
+		data    string
+		want    map[string]string
+		wantErr string
+	}{
+		"success: tokens with comments": {
+			data: "# agents\nalice s3cret\n\n  bob   t0ken  \n",
+			want: map[string]string{"s3cret": "alice", "t0ken": "bob"},
+		},
+		"error: missing token": {
+			data:    "alice\n",
+			wantErr: "line 1",
+		},
+		"error: duplicate token": {
+			data:    "alice s3cret\nbob s3cret\n",
+			wantErr: "line 2: duplicate token",
+		},
+		"error: empty": {
+			data:    "# nothing\n",
+			wantErr: "no tokens",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := readTokens(strings.NewReader(tt.data))
+			if diff := cmp.Diff(tt.wantErr != "", err != nil); diff != "" {
+				t.Fatalf("error presence mismatch (-want +got):\n%s", diff)
+			}
+			if err != nil {
+				if diff := cmp.Diff(true, strings.Contains(err.Error(), tt.wantErr)); diff != "" {
+					t.Fatalf("error text mismatch %q (-want +got):\n%s", err, diff)
+				}
+				return
+			}
+			want := make(map[[sha256.Size]byte]string)
+			for token, principal := range tt.want {
+				want[sha256.Sum256([]byte(token))] = principal
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatalf("tokens mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadAuthConfig(t *testing.T) {
+	tests := map[string]struct {
+		tokens, cert, key, clientCA string
+		wantErr                     string
+	}{
+		"success: no auth": {},
+		"error: cert without key": {
+			cert:    "server.crt",
+			wantErr: "must be set together",
+		},
+		"error: client CA without TLS": {
+			clientCA: "ca.crt",
+			wantErr:  "requires -tls-cert",
+		},
+		"error: missing tokens file": {
+			tokens:  filepath.Join(t.TempDir(), "missing"),
+			wantErr: "open auth tokens",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := loadAuthConfig(tt.tokens, tt.cert, tt.key, tt.clientCA)
+			if diff := cmp.Diff(tt.wantErr != "", err != nil); diff != "" {
+				t.Fatalf("error presence mismatch %v (-want +got):\n%s", err, diff)
+			}
+			if err != nil {
+				if diff := cmp.Diff(true, strings.Contains(err.Error(), tt.wantErr)); diff != "" {
+					t.Fatalf("error text mismatch %q (-want +got):\n%s", err, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestCertPrincipal(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://example.org/agent")
+
+	tests := map[string]struct {
+		cert *x509.Certificate
+		want string
+	}{
+		"success: common name": {
+			cert: &x509.Certificate{Subject: pkix.Name{CommonName: "agent-1"}, DNSNames: []string{"agent.example.org"}},
+			want: "agent-1",
+		},
+		"success: URI SAN": {
+			cert: &x509.Certificate{URIs: []*url.URL{spiffe}, DNSNames: []string{"agent.example.org"}},
+			want: "spiffe://example.org/agent",
+		},
+		"success: DNS SAN": {
+			cert: &x509.Certificate{DNSNames: []string{"agent.example.org"}},
+			want: "agent.example.org",
+		},
+		"success: anonymous": {
+			cert: &x509.Certificate{},
+			want: "",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, certPrincipal(tt.cert)); diff != "" {
+				t.Fatalf("principal mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAuthBearerToken(t *testing.T) {
+	tokensPath := filepath.Join(t.TempDir(), "tokens")
+	if err := os.WriteFile(tokensPath, []byte("alice s3cret\n"), 0o600); err != nil {
+		t.Fatalf("write tokens: %v", err)
+	}
+	ac, err := loadAuthConfig(tokensPath, "", "", "")
+	if err != nil {
+		t.Fatalf("load auth config: %v", err)
+	}
+	thinking := NewSequentialThinkingServer()
+	ts := httptest.NewServer(newAuthHandler(t, thinking, ac))
+	t.Cleanup(ts.Close)
+
+	tests := map[string]struct {
+		header  http.Header
+		wantErr bool
+	}{
+		"error: no token": {
+			wantErr: true,
+		},
+		"error: unknown token": {
+			header:  http.Header{"Authorization": {"Bearer guess"}},
+			wantErr: true,
+		},
+		"error: spoofed principal header": {
+			header:  http.Header{principalHeader: {"alice"}},
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := connectAuthClient(t, ts.URL, &http.Client{Transport: &headerTransport{base: http.DefaultTransport, header: tt.header}})
+			if diff := cmp.Diff(tt.wantErr, err != nil); diff != "" {
+				t.Fatalf("error presence mismatch %v (-want +got):\n%s", err, diff)
+			}
+		})
+	}
+
+	cs, err := connectAuthClient(t, ts.URL, &http.Client{Transport: &headerTransport{
+		base:   http.DefaultTransport,
+		header: http.Header{"Authorization": {"Bearer s3cret"}, principalHeader: {"mallory"}},
+	}})
+	if err != nil {
+		t.Fatalf("connect with token: %v", err)
+	}
+	recordThoughts(t, cs, resourceThoughts[0])
+	if diff := cmp.Diff([]string{"alice", "alice"}, principals(t, thinking)); diff != "" {
+		t.Fatalf("principals mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAuthPrincipalHeaderStripped(t *testing.T) {
+	thinking := NewSequentialThinkingServer()
+	ts := httptest.NewServer(newAuthHandler(t, thinking, &authConfig{}))
+	t.Cleanup(ts.Close)
+
+	cs, err := connectAuthClient(t, ts.URL, &http.Client{Transport: &headerTransport{
+		base:   http.DefaultTransport,
+		header: http.Header{principalHeader: {"mallory"}},
+	}})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	recordThoughts(t, cs, resourceThoughts[0])
+	if diff := cmp.Diff([]string{"", ""}, principals(t, thinking)); diff != "" {
+		t.Fatalf("principals mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// newTestCA returns a self-signed CA and a client certificate for commonName issued by it.
+func newTestCA(t *testing.T, commonName string) (*x509.CertPool, tls.Certificate) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create CA certificate: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("parse CA certificate: %v", err)
+	}
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate client key: %v", err)
+	}
+	clientTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTmpl, caCert, &clientKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create client certificate: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	return pool, tls.Certificate{Certificate: [][]byte{clientDER}, PrivateKey: clientKey}
+}
+
+func TestAuthClientCertificate(t *testing.T) {
+	pool, clientCert := newTestCA(t, "agent-1")
+	ac := &authConfig{certFile: "server.crt", keyFile: "server.key", clientCAs: pool}
+	thinking := NewSequentialThinkingServer()
+	ts := httptest.NewUnstartedServer(newAuthHandler(t, thinking, ac))
+	ts.TLS = ac.tlsConfig()
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+
+	// the handshake fails without a client certificate
+	if _, err := connectAuthClient(t, ts.URL, ts.Client()); err == nil {
+		t.Fatal("connect without client certificate succeeded")
+	}
+
+	base := ts.Client().Transport.(*http.Transport).Clone()
+	base.TLSClientConfig.Certificates = []tls.Certificate{clientCert}
+	cs, err := connectAuthClient(t, ts.URL, &http.Client{Transport: &headerTransport{
+		base:   base,
+		header: http.Header{principalHeader: {"mallory"}},
+	}})
+	if err != nil {
+		t.Fatalf("connect with client certificate: %v", err)
+	}
+	recordThoughts(t, cs, resourceThoughts[0])
+	if diff := cmp.Diff([]string{"agent-1", "agent-1"}, principals(t, thinking)); diff != "" {
+		t.Fatalf("principals mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSequentialThinkingServerPrincipalMismatch(t *testing.T) {
+	thinking := NewSequentialThinkingServer()
+	request := func(principal string) *mcp.CallToolRequest {
+		return &mcp.CallToolRequest{Extra: &mcp.RequestExtra{TokenInfo: &auth.TokenInfo{UserID: principal}}}
+	}
+
+	tests := []struct {
+		principal  string
+		wantReason string
+	}{
+		{principal: "alice"},
+		{principal: "bob", wantReason: reasonPrincipalMismatch},
+		{principal: "", wantReason: reasonPrincipalMismatch},
+		{principal: "alice"},
+	}
+	for i, tt := range tests {
+		result, _, err := thinking.ProcessThought(t.Context(), request(tt.principal), ThoughtData{Thought: "t", ThoughtNumber: i + 1, TotalThoughts: 4})
+		if err != nil {
+			t.Fatalf("call %d: process thought: %v", i, err)
+		}
+		if diff := cmp.Diff(tt.wantReason != "", result.IsError); diff != "" {
+			t.Fatalf("call %d: IsError mismatch (-want +got):\n%s", i, diff)
+		}
+		if tt.wantReason != "" {
+			if diff := cmp.Diff(true, strings.Contains(resultText(t, result), tt.wantReason)); diff != "" {
+				t.Fatalf("call %d: reason missing (-want +got):\n%s", i, diff)
+			}
+		}
+	}
+	if diff := cmp.Diff(2, len(thinking.History(defaultSessionID))); diff != "" {
+		t.Fatalf("history length mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadToolsPrincipalMismatch(t *testing.T) {
+	request := func(principal string) *mcp.CallToolRequest {
+		return &mcp.CallToolRequest{Extra: &mcp.RequestExtra{TokenInfo: &auth.TokenInfo{UserID: principal}}}
+	}
+	type readTool func(s *SequentialThinkingServer, request *mcp.CallToolRequest) (*mcp.CallToolResult, error)
+
+	tests := map[string]struct {
+		call readTool
+	}{
+		"get_thought": {
+			call: func(s *SequentialThinkingServer, request *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				result, _, err := s.GetThought(t.Context(), request, GetThoughtInput{ThoughtNumber: 1})
+				return result, err
+			},
+		},
+		"list_thoughts": {
+			call: func(s *SequentialThinkingServer, request *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				result, _, err := s.ListThoughts(t.Context(), request, ListThoughtsInput{})
+				return result, err
+			},
+		},
+		"list_branches": {
+			call: func(s *SequentialThinkingServer, request *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				result, _, err := s.ListBranches(t.Context(), request, ListBranchesInput{})
+				return result, err
+			},
+		},
+		"get_session_summary": {
+			call: func(s *SequentialThinkingServer, request *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				result, _, err := s.GetSessionSummary(t.Context(), request, GetSessionSummaryInput{})
+				return result, err
+			},
+		},
+		"export_session": {
+			call: func(s *SequentialThinkingServer, request *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				result, _, err := s.ExportSession(t.Context(), request, ExportSessionInput{Format: exportJSON})
+				return result, err
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			thinking := NewSequentialThinkingServer()
+			result, _, err := thinking.ProcessThought(t.Context(), request("alice"), ThoughtData{Thought: "alice's secret", ThoughtNumber: 1, TotalThoughts: 2, NextThoughtNeeded: true})
+			if err != nil {
+				t.Fatalf("process thought: %v", err)
+			}
+			if result.IsError {
+				t.Fatalf("tool error: %s", resultText(t, result))
+			}
+
+			result, err = tt.call(thinking, request("alice"))
+			if err != nil {
+				t.Fatalf("owner call: %v", err)
+			}
+			if diff := cmp.Diff(false, result.IsError); diff != "" {
+				t.Fatalf("owner IsError mismatch %s (-want +got):\n%s", resultText(t, result), diff)
+			}
+
+			for _, principal := range []string{"bob", ""} {
+				result, err := tt.call(thinking, request(principal))
+				if err != nil {
+					t.Fatalf("call as %q: %v", principal, err)
+				}
+				if diff := cmp.Diff(true, result.IsError); diff != "" {
+					t.Fatalf("call as %q: IsError mismatch (-want +got):\n%s", principal, diff)
+				}
+				text := resultText(t, result)
+				if diff := cmp.Diff(true, strings.Contains(text, reasonPrincipalMismatch)); diff != "" {
+					t.Fatalf("call as %q: reason missing from %s (-want +got):\n%s", principal, text, diff)
+				}
+				if diff := cmp.Diff(false, strings.Contains(text, "secret")); diff != "" {
+					t.Fatalf("call as %q: thought leaked in %s (-want +got):\n%s", principal, text, diff)
+				}
+			}
+		})
+	}
+}
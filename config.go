@@ -0,0 +1,468 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/bytedance/sonic"
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix prefixes the environment variables overriding the configuration.
+const envPrefix = "MCP_SEQTHINK_"
+
+// configEnv names the configuration file when -config is not given.
+const configEnv = envPrefix + "CONFIG"
+
+// deprecatedLogThoughtsEnv is the former, misspelled variable that printed every thought as a frame
+// on stderr, now MCP_SEQTHINK_LOGGING_CONSOLE=frame.
+const deprecatedLogThoughtsEnv = "ENABLE_SEQUENTIA_LTHINKING_LOG"
+
+// Config is the server configuration.
+//
+// It is loaded from the defaults, a TOML, YAML or JSON configuration file, the MCP_SEQTHINK_*
+// environment variables and the command line flags, each overriding the previous.
+type Config struct {
+	Transport TransportOptions `json:"transport"`
+	Auth      AuthOptions      `json:"auth"`
+	Storage   StorageOptions   `json:"storage"`
+	Limits    LimitsOptions    `json:"limits"`
+	RateLimit RateLimitOptions `json:"rateLimit"`
+	Logging   LoggingOptions   `json:"logging"`
+	Telemetry TelemetryOptions `json:"telemetry"`
+	Tool      ToolOptions      `json:"tool"`
+	Prompts   PromptsOptions   `json:"prompts"`
+
+	// deprecated holds a warning for every deprecated setting the configuration was loaded from.
+	deprecated []string
+}
+
+// TransportOptions configures the MCP transports.
+type TransportOptions struct {
+	Mode            string   `json:"mode"`
+	HTTP            string   `json:"http"`
+	Unix            string   `json:"unix"`
+	UnixMode        fileMode `json:"unixMode"`
+	ShutdownTimeout duration `json:"shutdownTimeout"`
+}
+
+// AuthOptions configures the authentication of HTTP clients.
+type AuthOptions struct {
+	Tokens      string `json:"tokens"`
+	TLSCert     string `json:"tlsCert"`
+	TLSKey      string `json:"tlsKey"`
+	TLSClientCA string `json:"tlsClientCA"`
+}
+
+// StorageOptions configures the session journals.
+type StorageOptions struct {
+	Path string `json:"path"`
+}
+
+// LimitsOptions bounds the memory held by the thinking sessions. A zero limit is unlimited.
+type LimitsOptions struct {
+	MaxSessions           int      `json:"maxSessions"`
+	MaxThoughtsPerSession int      `json:"maxThoughtsPerSession"`
+	MaxThoughtBytes       int      `json:"maxThoughtBytes"`
+	MaxSessionBytes       int      `json:"maxSessionBytes"`
+	IdleTTL               duration `json:"idleTTL"`
+	SessionPolicy         string   `json:"sessionPolicy"`
+}
+
+// RateLimitOptions configures the token bucket rate limits of the sequentialthinking tool, in calls
+// per second. A zero rate is unlimited, and a zero burst is one second of calls.
+type RateLimitOptions struct {
+	Global         float64 `json:"global"`
+	GlobalBurst    int     `json:"globalBurst"`
+	Session        float64 `json:"session"`
+	SessionBurst   int     `json:"sessionBurst"`
+	Principal      float64 `json:"principal"`
+	PrincipalBurst int     `json:"principalBurst"`
+}
+
+// LoggingOptions configures the server log.
+type LoggingOptions struct {
+	Path         string `json:"path"`
+	Format       string `json:"format"`
+	Level        string `json:"level"`
+	Console      string `json:"console"`
+	ConsoleWidth int    `json:"consoleWidth"`
+}
+
+// TelemetryOptions configures metrics and tracing.
+type TelemetryOptions struct {
+	Metrics bool `json:"metrics"`
+	// MetricsNoAuth serves the metrics without the authentication of the MCP endpoint.
+	MetricsNoAuth bool   `json:"metricsNoAuth"`
+	OTLPEndpoint  string `json:"otlpEndpoint"`
+	TraceFile     string `json:"traceFile"`
+}
+
+// ToolOptions configures the sequentialthinking tool.
+type ToolOptions struct {
+	Name            string                 `json:"name"`
+	Description     string                 `json:"description"`
+	DescriptionFile string                 `json:"descriptionFile"`
+	Annotations     ToolAnnotationsOptions `json:"annotations"`
+	// FieldDescriptions overrides the descriptions of the input fields, by field name.
+	FieldDescriptions map[string]string `json:"fieldDescriptions,omitempty"`
+	Numbering         string            `json:"numbering"`
+	LogThoughts       bool              `json:"logThoughts"`
+	LogThoughtText    bool              `json:"logThoughtText"`
+}
+
+// ToolAnnotationsOptions are the hints about the sequentialthinking tool given to clients.
+type ToolAnnotationsOptions struct {
+	Title           string `json:"title"`
+	ReadOnlyHint    bool   `json:"readOnlyHint"`
+	DestructiveHint bool   `json:"destructiveHint"`
+	IdempotentHint  bool   `json:"idempotentHint"`
+	OpenWorldHint   bool   `json:"openWorldHint"`
+}
+
+// PromptsOptions configures the server instructions and prompts.
+type PromptsOptions struct {
+	Instructions     string `json:"instructions"`
+	InstructionsFile string `json:"instructionsFile"`
+	Dir              string `json:"dir"`
+}
+
+// defaultConfig returns the configuration used when nothing is set.
+func defaultConfig() *Config {
+	return &Config{
+		Transport: TransportOptions{
+			UnixMode:        "0600",
+			ShutdownTimeout: duration(10 * time.Second),
+		},
+		Limits: LimitsOptions{
+			SessionPolicy: sessionPolicyLRU,
+		},
+		Logging: LoggingOptions{
+			Format: logFormatText,
+		},
+		Tool: ToolOptions{
+			Name:      defaultToolName,
+			Numbering: numberingClient,
+			Annotations: ToolAnnotationsOptions{
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+				OpenWorldHint:  true,
+			},
+		},
+	}
+}
+
+// setting is a configuration value settable from the environment and the command line.
+type setting struct {
+	key   string
+	flag  string
+	env   string
+	usage string
+	value func(c *Config) flag.Value
+}
+
+// settings lists every configuration value, in the order of the configuration file.
+var settings = []setting{
+	{"transport.mode", "transport", "TRANSPORT_MODE", "transport to serve: stdio, http or both (default http if -http or -unix is set, stdio otherwise)", func(c *Config) flag.Value { return (*stringValue)(&c.Transport.Mode) }},
+	{"transport.http", "http", "TRANSPORT_HTTP", "if set, use streamable HTTP at this address, instead of stdin/stdout", func(c *Config) flag.Value { return (*stringValue)(&c.Transport.HTTP) }},
+	{"transport.unix", "unix", "TRANSPORT_UNIX", "if set, also serve streamable HTTP on a Unix domain socket at this path", func(c *Config) flag.Value { return (*stringValue)(&c.Transport.Unix) }},
+	{"transport.unixMode", "unix-mode", "TRANSPORT_UNIX_MODE", "file mode of the -unix socket", func(c *Config) flag.Value { return (*stringValue)(&c.Transport.UnixMode) }},
+	{"transport.shutdownTimeout", "shutdown-timeout", "TRANSPORT_SHUTDOWN_TIMEOUT", "how long to wait for in-flight tool calls and open streams on shutdown", func(c *Config) flag.Value { return &c.Transport.ShutdownTimeout }},
+	{"auth.tokens", "auth-tokens", "AUTH_TOKENS", "if set, require HTTP clients to send one of the bearer tokens in this file, one \"<principal> <token>\" per line", func(c *Config) flag.Value { return (*stringValue)(&c.Auth.Tokens) }},
+	{"auth.tlsCert", "tls-cert", "AUTH_TLS_CERT", "if set with -tls-key, serve HTTP over TLS with this certificate file", func(c *Config) flag.Value { return (*stringValue)(&c.Auth.TLSCert) }},
+	{"auth.tlsKey", "tls-key", "AUTH_TLS_KEY", "if set with -tls-cert, serve HTTP over TLS with this key file", func(c *Config) flag.Value { return (*stringValue)(&c.Auth.TLSKey) }},
+	{"auth.tlsClientCA", "tls-client-ca", "AUTH_TLS_CLIENT_CA", "if set, require HTTP clients to present a certificate signed by a CA in this PEM file", func(c *Config) flag.Value { return (*stringValue)(&c.Auth.TLSClientCA) }},
+	{"storage.path", "store", "STORAGE_PATH", "if set, persist thoughts as per-session journals in this directory and replay them on startup", func(c *Config) flag.Value { return (*stringValue)(&c.Storage.Path) }},
+	{"limits.maxSessions", "max-sessions", "LIMITS_MAX_SESSIONS", "if set, the most thinking sessions held at once, see -session-policy", func(c *Config) flag.Value { return (*intValue)(&c.Limits.MaxSessions) }},
+	{"limits.maxThoughtsPerSession", "max-thoughts-per-session", "LIMITS_MAX_THOUGHTS_PER_SESSION", "if set, the most thoughts a session records", func(c *Config) flag.Value { return (*intValue)(&c.Limits.MaxThoughtsPerSession) }},
+	{"limits.maxThoughtBytes", "max-thought-bytes", "LIMITS_MAX_THOUGHT_BYTES", "if set, the largest thought text in bytes", func(c *Config) flag.Value { return (*intValue)(&c.Limits.MaxThoughtBytes) }},
+	{"limits.maxSessionBytes", "max-session-bytes", "LIMITS_MAX_SESSION_BYTES", "if set, the most bytes of thought text a session records", func(c *Config) flag.Value { return (*intValue)(&c.Limits.MaxSessionBytes) }},
+	{"limits.idleTTL", "session-idle-ttl", "LIMITS_IDLE_TTL", "if set, evict the sessions that recorded no thought for this long", func(c *Config) flag.Value { return &c.Limits.IdleTTL }},
+	{"limits.sessionPolicy", "session-policy", "LIMITS_SESSION_POLICY", "what a new session does at -max-sessions: lru evicts the least recently used session, reject fails the tool call", func(c *Config) flag.Value { return (*stringValue)(&c.Limits.SessionPolicy) }},
+	{"rateLimit.global", "rate-limit", "RATE_LIMIT_GLOBAL", "if set, the most sequentialthinking calls per second across all sessions", func(c *Config) flag.Value { return (*floatValue)(&c.RateLimit.Global) }},
+	{"rateLimit.globalBurst", "rate-limit-burst", "RATE_LIMIT_GLOBAL_BURST", "the most calls in a burst over -rate-limit (default one second of calls)", func(c *Config) flag.Value { return (*intValue)(&c.RateLimit.GlobalBurst) }},
+	{"rateLimit.session", "session-rate-limit", "RATE_LIMIT_SESSION", "if set, the most sequentialthinking calls per second in a session", func(c *Config) flag.Value { return (*floatValue)(&c.RateLimit.Session) }},
+	{"rateLimit.sessionBurst", "session-rate-limit-burst", "RATE_LIMIT_SESSION_BURST", "the most calls in a burst over -session-rate-limit (default one second of calls)", func(c *Config) flag.Value { return (*intValue)(&c.RateLimit.SessionBurst) }},
+	{"rateLimit.principal", "principal-rate-limit", "RATE_LIMIT_PRINCIPAL", "if set, the most sequentialthinking calls per second of an authenticated principal", func(c *Config) flag.Value { return (*floatValue)(&c.RateLimit.Principal) }},
+	{"rateLimit.principalBurst", "principal-rate-limit-burst", "RATE_LIMIT_PRINCIPAL_BURST", "the most calls in a burst over -principal-rate-limit (default one second of calls)", func(c *Config) flag.Value { return (*intValue)(&c.RateLimit.PrincipalBurst) }},
+	{"logging.path", "logpath", "LOGGING_PATH", "if set, write the server log to this file instead of stderr", func(c *Config) flag.Value { return (*stringValue)(&c.Logging.Path) }},
+	{"logging.format", "log-format", "LOGGING_FORMAT", "format of the server log: text or json", func(c *Config) flag.Value { return (*stringValue)(&c.Logging.Format) }},
+	{"logging.level", "log-level", "LOGGING_LEVEL", "minimum level of the server log: debug, info, warn or error (default warn, or info with -log-thoughts)", func(c *Config) flag.Value { return (*stringValue)(&c.Logging.Level) }},
+	{"logging.console", "console", "LOGGING_CONSOLE", "if set, render the thoughts on stderr: frame draws every thought as a frame, tree draws the sessions as trees of their thoughts", func(c *Config) flag.Value { return (*stringValue)(&c.Logging.Console) }},
+	{"logging.consoleWidth", "console-width", "LOGGING_CONSOLE_WIDTH", "width the -console wraps to (default the terminal width, or 80 if stderr is not a terminal)", func(c *Config) flag.Value { return (*intValue)(&c.Logging.ConsoleWidth) }},
+	{"telemetry.metrics", "metrics", "TELEMETRY_METRICS", "if set, serve Prometheus metrics at " + metricsPath + " on the HTTP listeners", func(c *Config) flag.Value { return (*boolValue)(&c.Telemetry.Metrics) }},
+	{"telemetry.metricsNoAuth", "metrics-no-auth", "TELEMETRY_METRICS_NO_AUTH", "if set, serve the -metrics without the bearer token authentication of the MCP endpoint", func(c *Config) flag.Value { return (*boolValue)(&c.Telemetry.MetricsNoAuth) }},
+	{"telemetry.otlpEndpoint", "trace-otlp-endpoint", "TELEMETRY_OTLP_ENDPOINT", "if set, export tool call spans to this OTLP/HTTP collector URL, such as http://localhost:4318", func(c *Config) flag.Value { return (*stringValue)(&c.Telemetry.OTLPEndpoint) }},
+	{"telemetry.traceFile", "trace-file", "TELEMETRY_TRACE_FILE", "if set, append tool call spans as OTLP JSON lines to this file", func(c *Config) flag.Value { return (*stringValue)(&c.Telemetry.TraceFile) }},
+	{"tool.name", "tool-name", "TOOL_NAME", "name of the sequential thinking tool", func(c *Config) flag.Value { return (*stringValue)(&c.Tool.Name) }},
+	{"tool.description", "tool-description", "TOOL_DESCRIPTION", "description of the tool: full (the default), compact, or the description text", func(c *Config) flag.Value { return (*stringValue)(&c.Tool.Description) }},
+	{"tool.descriptionFile", "tool-description-file", "TOOL_DESCRIPTION_FILE", "if set, read the -tool-description from this file", func(c *Config) flag.Value { return (*stringValue)(&c.Tool.DescriptionFile) }},
+	{"tool.annotations.title", "tool-title", "TOOL_ANNOTATIONS_TITLE", "if set, the human-readable title of the tool", func(c *Config) flag.Value { return (*stringValue)(&c.Tool.Annotations.Title) }},
+	{"tool.annotations.readOnlyHint", "tool-read-only-hint", "TOOL_ANNOTATIONS_READ_ONLY_HINT", "hint that the tool does not modify its environment", func(c *Config) flag.Value { return (*boolValue)(&c.Tool.Annotations.ReadOnlyHint) }},
+	{"tool.annotations.destructiveHint", "tool-destructive-hint", "TOOL_ANNOTATIONS_DESTRUCTIVE_HINT", "hint that the tool may perform destructive updates", func(c *Config) flag.Value { return (*boolValue)(&c.Tool.Annotations.DestructiveHint) }},
+	{"tool.annotations.idempotentHint", "tool-idempotent-hint", "TOOL_ANNOTATIONS_IDEMPOTENT_HINT", "hint that repeated calls with the same arguments have no additional effect", func(c *Config) flag.Value { return (*boolValue)(&c.Tool.Annotations.IdempotentHint) }},
+	{"tool.annotations.openWorldHint", "tool-open-world-hint", "TOOL_ANNOTATIONS_OPEN_WORLD_HINT", "hint that the tool interacts with external entities", func(c *Config) flag.Value { return (*boolValue)(&c.Tool.Annotations.OpenWorldHint) }},
+	{"tool.numbering", "numbering", "TOOL_NUMBERING", "thought numbering: client trusts the numbers of the client, check reports duplicates, gaps and regressions, assign also replaces them with the expected numbers", func(c *Config) flag.Value { return (*stringValue)(&c.Tool.Numbering) }},
+	{"tool.logThoughts", "log-thoughts", "TOOL_LOG_THOUGHTS", "if set, log an event for every recorded thought", func(c *Config) flag.Value { return (*boolValue)(&c.Tool.LogThoughts) }},
+	{"tool.logThoughtText", "log-thought-text", "TOOL_LOG_THOUGHT_TEXT", "if set with -log-thoughts, include the thought text in the events", func(c *Config) flag.Value { return (*boolValue)(&c.Tool.LogThoughtText) }},
+	{"prompts.instructions", "instructions", "PROMPTS_INSTRUCTIONS", "if set, the instructions sent to clients on initialization, instead of the built-in instructions", func(c *Config) flag.Value { return (*stringValue)(&c.Prompts.Instructions) }},
+	{"prompts.instructionsFile", "instructions-file", "PROMPTS_INSTRUCTIONS_FILE", "if set, read the -instructions from this file", func(c *Config) flag.Value { return (*stringValue)(&c.Prompts.InstructionsFile) }},
+	{"prompts.dir", "prompts-dir", "PROMPTS_DIR", "if set, load prompt templates from the .md and .tmpl files of this directory, reloaded when they change", func(c *Config) flag.Value { return (*stringValue)(&c.Prompts.Dir) }},
+}
+
+// flagOverrides holds the raw values of the setting flags given on the command line, keyed by flag name.
+type flagOverrides map[string]string
+
+// register defines a flag for every setting on fs, recording the given values in o.
+func (o flagOverrides) register(fs *flag.FlagSet) {
+	for _, s := range settings {
+		fs.Var(&overrideFlag{overrides: o, setting: s}, s.flag, s.usage)
+	}
+}
+
+// overrideFlag is the [flag.Value] of a setting flag.
+// It validates and records the given value, which [loadConfig] applies over the file and the environment.
+type overrideFlag struct {
+	overrides flagOverrides
+	setting   setting
+}
+
+func (f *overrideFlag) String() string {
+	if f.setting.value == nil {
+		return ""
+	}
+	if v, ok := f.overrides[f.setting.flag]; ok {
+		return v
+	}
+	return f.setting.value(defaultConfig()).String()
+}
+
+func (f *overrideFlag) Set(v string) error {
+	if err := f.setting.value(defaultConfig()).Set(v); err != nil {
+		return err
+	}
+	f.overrides[f.setting.flag] = v
+	return nil
+}
+
+func (f *overrideFlag) IsBoolFlag() bool {
+	if f.setting.value == nil {
+		return false
+	}
+	_, ok := f.setting.value(defaultConfig()).(*boolValue)
+	return ok
+}
+
+// loadConfig loads the configuration from the file at path, or named by MCP_SEQTHINK_CONFIG if path is empty,
+// then applies the environment variables found by lookupEnv and the flag overrides.
+func loadConfig(path string, lookupEnv func(string) (string, bool), overrides flagOverrides) (*Config, error) {
+	cfg := defaultConfig()
+
+	if path == "" {
+		path, _ = lookupEnv(configEnv)
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := lookupEnv(deprecatedLogThoughtsEnv); ok {
+		// the former variable treated any invalid value as false
+		if enabled, _ := strconv.ParseBool(v); enabled {
+			cfg.Logging.Console = consoleFrame
+		}
+		cfg.deprecated = append(cfg.deprecated, deprecatedLogThoughtsEnv+" is deprecated, use "+envPrefix+"LOGGING_CONSOLE="+consoleFrame)
+	}
+	for _, s := range settings {
+		v, ok := lookupEnv(envPrefix + s.env)
+		if !ok {
+			continue
+		}
+		if err := s.value(cfg).Set(v); err != nil {
+			return nil, fmt.Errorf("%s%s: %w", envPrefix, s.env, err)
+		}
+	}
+
+	for _, s := range settings {
+		v, ok := overrides[s.flag]
+		if !ok {
+			continue
+		}
+		if err := s.value(cfg).Set(v); err != nil {
+			return nil, fmt.Errorf("-%s: %w", s.flag, err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// strictJSON rejects configuration files with unknown keys.
+var strictJSON = sonic.Config{DisallowUnknownFields: true}.Froze()
+
+// loadFile overlays the configuration file at path, decoded by the format of its extension.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	switch ext := filepath.Ext(path); ext {
+	case ".json":
+	case ".yaml", ".yml":
+		var m map[string]any
+		if err := yaml.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("parse config %q: %w", path, err)
+		}
+		if data, err = sonic.ConfigStd.Marshal(m); err != nil {
+			return fmt.Errorf("parse config %q: %w", path, err)
+		}
+	case ".toml":
+		var m map[string]any
+		if err := toml.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("parse config %q: %w", path, err)
+		}
+		if data, err = sonic.ConfigStd.Marshal(m); err != nil {
+			return fmt.Errorf("parse config %q: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("config %q: unknown format %q, must be .toml, .yaml, .yml or .json", path, ext)
+	}
+
+	if err := strictJSON.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("parse config %q: %w", path, err)
+	}
+	return nil
+}
+
+// write writes c to w as an indented JSON configuration file.
+func (c *Config) write(w io.Writer) error {
+	data, err := sonic.ConfigStd.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// duration is a [time.Duration] written as a string such as "10s" in configuration files.
+type duration time.Duration
+
+func (d duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d *duration) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		return fmt.Errorf("duration must be a string such as \"10s\": %s", data)
+	}
+	return d.Set(s)
+}
+
+// fileMode is an octal file mode such as "0660".
+//
+// Configuration files may write it as a string or as a number. YAML reads an unquoted 0660 and
+// TOML reads 0o660 as the integer value of the permission bits, so a number is converted back to
+// its octal string; a JSON number is likewise the value of the bits, not their octal digits.
+type fileMode string
+
+func (m *fileMode) UnmarshalJSON(data []byte) error {
+	if s, err := strconv.Unquote(string(data)); err == nil {
+		*m = fileMode(s)
+		return nil
+	}
+	v, err := strconv.ParseUint(string(data), 10, 32)
+	if err != nil {
+		return fmt.Errorf("file mode must be octal permission bits such as \"0600\": %s", data)
+	}
+	*m = fileMode("0" + strconv.FormatUint(v, 8))
+	return nil
+}
+
+// stringValue is a [flag.Value] setting a string.
+type stringValue string
+
+func (v *stringValue) String() string { return string(*v) }
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+// intValue is a [flag.Value] setting an int.
+type intValue int
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", s)
+	}
+	*v = intValue(n)
+	return nil
+}
+
+// floatValue is a [flag.Value] setting a float64.
+type floatValue float64
+
+func (v *floatValue) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", s)
+	}
+	*v = floatValue(f)
+	return nil
+}
+
+// boolValue is a [flag.Value] setting a bool.
+type boolValue bool
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("invalid boolean %q", s)
+	}
+	*v = boolValue(b)
+	return nil
+}
@@ -0,0 +1,411 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	testConfigTOML = `
+[transport]
+http = "127.0.0.1:8080"
+shutdownTimeout = "3s"
+
+[storage]
+path = "/var/lib/thinking"
+
+[telemetry]
+metrics = true
+`
+	testConfigYAML = `
+transport:
+  http: 127.0.0.1:8080
+  shutdownTimeout: 3s
+storage:
+  path: /var/lib/thinking
+telemetry:
+  metrics: true
+`
+	testConfigJSON = `{
+  "transport": {"http": "127.0.0.1:8080", "shutdownTimeout": "3s"},
+  "storage": {"path": "/var/lib/thinking"},
+  "telemetry": {"metrics": true}
+}`
+)
+
+// writeConfigFile writes data to a configuration file named name in a temporary directory.
+func writeConfigFile(t *testing.T, name, data string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+// testConfig returns the configuration of the test configuration files.
+func testConfig() *Config {
+	cfg := defaultConfig()
+	cfg.Transport.HTTP = "127.0.0.1:8080"
+	cfg.Transport.ShutdownTimeout = duration(3 * time.Second)
+	cfg.Storage.Path = "/var/lib/thinking"
+	cfg.Telemetry.Metrics = true
+	return cfg
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := map[string]struct {
+		file      string
+		data      string
+		configEnv bool
+		env       map[string]string
+		overrides flagOverrides
+		want      func() *Config
+		wantErr   string
+	}{
+		"success: defaults": {
+			want: defaultConfig,
+		},
+		"success: toml": {
+			file: "config.toml",
+			data: testConfigTOML,
+			want: testConfig,
+		},
+		"success: yaml": {
+			file: "config.yaml",
+			data: testConfigYAML,
+			want: testConfig,
+		},
+		"success: json": {
+			file: "config.json",
+			data: testConfigJSON,
+			want: testConfig,
+		},
+		"success: file named by environment": {
+			file:      "config.yml",
+			data:      testConfigYAML,
+			configEnv: true,
+			want:      testConfig,
+		},
+		"success: environment overrides file": {
+			file: "config.toml",
+			data: testConfigTOML,
+			env: map[string]string{
+				"MCP_SEQTHINK_TRANSPORT_HTTP":             ":9090",
+				"MCP_SEQTHINK_TRANSPORT_SHUTDOWN_TIMEOUT": "1m",
+				"MCP_SEQTHINK_TELEMETRY_METRICS":          "false",
+			},
+			want: func() *Config {
+				cfg := testConfig()
+				cfg.Transport.HTTP = ":9090"
+				cfg.Transport.ShutdownTimeout = duration(time.Minute)
+				cfg.Telemetry.Metrics = false
+				return cfg
+			},
+		},
+		"success: flags override environment": {
+			file: "config.toml",
+			data: testConfigTOML,
+			env: map[string]string{
+				"MCP_SEQTHINK_TRANSPORT_HTTP": ":9090",
+				"MCP_SEQTHINK_STORAGE_PATH":   "/srv/thinking",
+			},
+			overrides: flagOverrides{"http": ":7070", "metrics": "false"},
+			want: func() *Config {
+				cfg := testConfig()
+				cfg.Transport.HTTP = ":7070"
+				cfg.Storage.Path = "/srv/thinking"
+				cfg.Telemetry.Metrics = false
+				return cfg
+			},
+		},
+		"success: deprecated log env": {
+			env: map[string]string{deprecatedLogThoughtsEnv: "true"},
+			want: func() *Config {
+				cfg := defaultConfig()
+				cfg.Logging.Console = consoleFrame
+				cfg.deprecated = []string{"ENABLE_SEQUENTIA_LTHINKING_LOG is deprecated, use MCP_SEQTHINK_LOGGING_CONSOLE=frame"}
+				return cfg
+			},
+		},
+		"success: new log env overrides deprecated env": {
+			env: map[string]string{
+				deprecatedLogThoughtsEnv:       "true",
+				"MCP_SEQTHINK_LOGGING_CONSOLE": consoleTree,
+			},
+			want: func() *Config {
+				cfg := defaultConfig()
+				cfg.Logging.Console = consoleTree
+				cfg.deprecated = []string{"ENABLE_SEQUENTIA_LTHINKING_LOG is deprecated, use MCP_SEQTHINK_LOGGING_CONSOLE=frame"}
+				return cfg
+			},
+		},
+		"success: limits from environment": {
+			env: map[string]string{
+				"MCP_SEQTHINK_LIMITS_MAX_SESSIONS":      "64",
+				"MCP_SEQTHINK_LIMITS_IDLE_TTL":          "30m",
+				"MCP_SEQTHINK_LIMITS_SESSION_POLICY":    "reject",
+				"MCP_SEQTHINK_LIMITS_MAX_THOUGHT_BYTES": "4096",
+			},
+			want: func() *Config {
+				cfg := defaultConfig()
+				cfg.Limits.MaxSessions = 64
+				cfg.Limits.IdleTTL = duration(30 * time.Minute)
+				cfg.Limits.SessionPolicy = sessionPolicyReject
+				cfg.Limits.MaxThoughtBytes = 4096
+				return cfg
+			},
+		},
+		"success: rate limits from flags": {
+			overrides: flagOverrides{"session-rate-limit": "2.5", "session-rate-limit-burst": "10", "principal-rate-limit": "20"},
+			want: func() *Config {
+				cfg := defaultConfig()
+				cfg.RateLimit.Session = 2.5
+				cfg.RateLimit.SessionBurst = 10
+				cfg.RateLimit.Principal = 20
+				return cfg
+			},
+		},
+		"success: tool from environment and flags": {
+			env: map[string]string{
+				"MCP_SEQTHINK_TOOL_NAME":                        "think",
+				"MCP_SEQTHINK_TOOL_ANNOTATIONS_OPEN_WORLD_HINT": "false",
+			},
+			overrides: flagOverrides{"tool-description": "compact", "tool-title": "Think"},
+			want: func() *Config {
+				cfg := defaultConfig()
+				cfg.Tool.Name = "think"
+				cfg.Tool.Description = descriptionCompact
+				cfg.Tool.Annotations.Title = "Think"
+				cfg.Tool.Annotations.OpenWorldHint = false
+				return cfg
+			},
+		},
+		"success: numbering from environment": {
+			env: map[string]string{"MCP_SEQTHINK_TOOL_NUMBERING": "assign"},
+			want: func() *Config {
+				cfg := defaultConfig()
+				cfg.Tool.Numbering = numberingAssign
+				return cfg
+			},
+		},
+		"success: tool field descriptions from file": {
+			file: "config.yaml",
+			data: "tool:\n  fieldDescriptions:\n    thought: One step of the analysis\n",
+			want: func() *Config {
+				cfg := defaultConfig()
+				cfg.Tool.FieldDescriptions = map[string]string{"thought": "One step of the analysis"}
+				return cfg
+			},
+		},
+		"success: unquoted yaml unix mode": {
+			file: "config.yaml",
+			data: "transport:\n  unixMode: 0660\n",
+			want: func() *Config {
+				cfg := defaultConfig()
+				cfg.Transport.UnixMode = "0660"
+				return cfg
+			},
+		},
+		"success: toml octal unix mode": {
+			file: "config.toml",
+			data: "[transport]\nunixMode = 0o660\n",
+			want: func() *Config {
+				cfg := defaultConfig()
+				cfg.Transport.UnixMode = "0660"
+				return cfg
+			},
+		},
+		"success: quoted toml unix mode": {
+			file: "config.toml",
+			data: "[transport]\nunixMode = \"0640\"\n",
+			want: func() *Config {
+				cfg := defaultConfig()
+				cfg.Transport.UnixMode = "0640"
+				return cfg
+			},
+		},
+		"error: invalid unix mode": {
+			file:    "config.json",
+			data:    `{"transport": {"unixMode": -1}}`,
+			wantErr: "file mode must be octal permission bits",
+		},
+		"error: unknown key": {
+			file:    "config.toml",
+			data:    "[transport]\nhttps = \":443\"\n",
+			wantErr: "parse config",
+		},
+		"error: invalid duration": {
+			file:    "config.json",
+			data:    `{"transport": {"shutdownTimeout": 10}}`,
+			wantErr: "duration must be a string",
+		},
+		"error: unknown format": {
+			file:    "config.ini",
+			data:    "http = :8080\n",
+			wantErr: "unknown format",
+		},
+		"error: invalid rate": {
+			env:     map[string]string{"MCP_SEQTHINK_RATE_LIMIT_GLOBAL": "fast"},
+			wantErr: `MCP_SEQTHINK_RATE_LIMIT_GLOBAL: invalid number "fast"`,
+		},
+		"error: invalid environment value": {
+			env:     map[string]string{"MCP_SEQTHINK_TELEMETRY_METRICS": "sometimes"},
+			wantErr: "MCP_SEQTHINK_TELEMETRY_METRICS",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var path string
+			if tt.file != "" {
+				path = writeConfigFile(t, tt.file, tt.data)
+			}
+			env := map[string]string{}
+			for k, v := range tt.env {
+				env[k] = v
+			}
+			if tt.configEnv {
+				env[configEnv] = path
+				path = ""
+			}
+			lookupEnv := func(key string) (string, bool) {
+				v, ok := env[key]
+				return v, ok
+			}
+
+			got, err := loadConfig(path, lookupEnv, tt.overrides)
+			if diff := cmp.Diff(tt.wantErr != "", err != nil); diff != "" {
+				t.Fatalf("error presence mismatch %v (-want +got):\n%s", err, diff)
+			}
+			if err != nil {
+				if diff := cmp.Diff(true, strings.Contains(err.Error(), tt.wantErr)); diff != "" {
+					t.Fatalf("error text mismatch %q (-want +got):\n%s", err, diff)
+				}
+				return
+			}
+			if diff := cmp.Diff(tt.want(), got, cmp.AllowUnexported(Config{})); diff != "" {
+				t.Fatalf("config mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFlagOverrides(t *testing.T) {
+	tests := map[string]struct {
+		args    []string
+		want    flagOverrides
+		wantErr bool
+	}{
+		"success: no flags": {
+			want: flagOverrides{},
+		},
+		"success: values and bool flag": {
+			args: []string{"-http", ":8080", "-metrics", "-shutdown-timeout=3s"},
+			want: flagOverrides{"http": ":8080", "metrics": "true", "shutdown-timeout": "3s"},
+		},
+		"error: invalid duration": {
+			args:    []string{"-shutdown-timeout", "soon"},
+			wantErr: true,
+		},
+		"error: invalid bool": {
+			args:    []string{"-log-thoughts=maybe"},
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			o := flagOverrides{}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			o.register(fs)
+
+			err := fs.Parse(tt.args)
+			if diff := cmp.Diff(tt.wantErr, err != nil); diff != "" {
+				t.Fatalf("error presence mismatch %v (-want +got):\n%s", err, diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tt.want, o); diff != "" {
+				t.Fatalf("overrides mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConfigWriteRoundTrip(t *testing.T) {
+	want := testConfig()
+	want.Auth.TLSClientCA = "clients-ca.crt"
+	want.Tool.LogThoughts = true
+
+	var buf bytes.Buffer
+	if err := want.write(&buf); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	path := writeConfigFile(t, "config.json", buf.String())
+	got, err := loadConfig(path, func(string) (string, bool) { return "", false }, nil)
+	if err != nil {
+		t.Fatalf("load written config: %v", err)
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(Config{})); diff != "" {
+		t.Fatalf("config mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMainPrintConfig(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", testConfigTOML)
+	cmd := exec.Command(os.Args[0], "-test.run=TestMainHelperProcess", "-config", path, "-http", ":7070", "-print-config")
+	cmd.Env = append(os.Environ(), "GO_WANT_HELPER_PROCESS=1", "MCP_SEQTHINK_STORAGE_PATH=/srv/thinking", deprecatedLogThoughtsEnv+"=true")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("print config: %v\n%s", err, stderr.String())
+	}
+
+	// the test binary prints its own result after the configuration
+	printed := stdout.String()
+	printed = printed[:strings.LastIndex(printed, "}")+1]
+	got, err := loadConfig(writeConfigFile(t, "printed.json", printed), func(string) (string, bool) { return "", false }, nil)
+	if err != nil {
+		t.Fatalf("load printed config: %v", err)
+	}
+	want := testConfig()
+	want.Transport.HTTP = ":7070"
+	want.Storage.Path = "/srv/thinking"
+	want.Logging.Console = consoleFrame
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(Config{})); diff != "" {
+		t.Fatalf("printed config mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(true, strings.Contains(stderr.String(), deprecatedLogThoughtsEnv+" is deprecated")); diff != "" {
+		t.Fatalf("deprecation warning missing from %q (-want +got):\n%s", stderr.String(), diff)
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/clipperhouse/uax29/v2/graphemes"
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+)
+
+// Console modes selected by -console.
+const (
+	// consoleFrame renders every recorded thought as a frame.
+	consoleFrame = "frame"
+	// consoleTree renders the sessions as trees of their thoughts, redrawn as thoughts are recorded.
+	consoleTree = "tree"
+)
+
+// defaultConsoleWidth is the width of a console that is not a terminal.
+const defaultConsoleWidth = 80
+
+// minConsoleWidth is the narrowest a frame is wrapped to.
+const minConsoleWidth = 20
+
+// ANSI escapes of the console colors.
+const (
+	ansiYellow = "\x1b[33m"
+	ansiGreen  = "\x1b[32m"
+	ansiBlue   = "\x1b[34m"
+	ansiReset  = "\x1b[0m"
+)
+
+// displayWidth measures the cells a string occupies in a terminal.
+//
+// It ignores the locale, so East Asian ambiguous characters such as the box drawing borders are narrow.
+var displayWidth = &runewidth.Condition{}
+
+// consoleStyle is how a console renders thoughts.
+type consoleStyle struct {
+	// width is the display width renderings are wrapped to.
+	width int
+	// height is the number of lines of the terminal, or 0 if the console is not a terminal.
+	height int
+	// tty reports whether the console is a terminal.
+	tty bool
+	// redraw reports whether renderings can be redrawn in place: the console is a terminal nothing else
+	// writes to. Only the renderings shorter than height are.
+	redraw bool
+	// color enables ANSI colors.
+	color bool
+}
+
+// newConsoleStyle returns the style of a console writing to w.
+//
+// A zero width is the width of the terminal w is, or [defaultConsoleWidth] if w is not a terminal.
+// Colors are only enabled on a terminal, and never when noColor is set. Renderings are only redrawn
+// in place on a terminal when exclusive reports that nothing else, such as the server log, writes to w.
+func newConsoleStyle(w io.Writer, width int, noColor, exclusive bool) consoleStyle {
+	tty, height := false, 0
+	if f, ok := w.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		tty = true
+		termWidth, termHeight, err := term.GetSize(int(f.Fd()))
+		if err == nil {
+			height = termHeight
+			if width == 0 {
+				width = termWidth
+			}
+		}
+	}
+	if width <= 0 {
+		width = defaultConsoleWidth
+	}
+	return consoleStyle{
+		width:  max(width, minConsoleWidth),
+		height: height,
+		tty:    tty,
+		redraw: tty && exclusive,
+		color:  tty && !noColor,
+	}
+}
+
+// newConsole returns the [sessionObserver] rendering the thoughts in style for a human watching w in mode,
+// or nil if mode is empty.
+func newConsole(mode string, w io.Writer, style consoleStyle) (sessionObserver, error) {
+	switch mode {
+	case "":
+		return nil, nil
+	case consoleFrame:
+		return &frameConsole{w: w, style: style}, nil
+	case consoleTree:
+		return newTreeConsole(w, style), nil
+	default:
+		return nil, fmt.Errorf("-console: must be %s or %s, got %q", consoleFrame, consoleTree, mode)
+	}
+}
+
+// frameConsole is a [sessionObserver] that writes a frame of every recorded thought to w.
+type frameConsole struct {
+	mu    sync.Mutex
+	w     io.Writer
+	style consoleStyle
+}
+
+var _ sessionObserver = (*frameConsole)(nil)
+
+func (c *frameConsole) sessionOpened(string) {}
+
+func (c *frameConsole) sessionClosed(string) {}
+
+// thoughtAppended implements [sessionObserver].
+func (c *frameConsole) thoughtAppended(record ThoughtRecord, _ bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintln(c.w, formatThought(record, c.style))
+}
+
+// thoughtKind returns the label of the kind of thought, its color and the context following its numbers.
+func thoughtKind(record ThoughtRecord) (label, color, context string) {
+	switch {
+	case record.IsRevision:
+		return "🔄 Revision", ansiYellow, fmt.Sprintf(" (revising thought %d)", record.RevisesThought)
+	case record.BranchFromThought > 0 && record.BranchID != "":
+		return "🌿 Branch", ansiGreen, fmt.Sprintf(" (from thought %d, ID: %s)", record.BranchFromThought, record.BranchID)
+	default:
+		return "💭 Thought", ansiBlue, ""
+	}
+}
+
+// formatThought formats the thought as a frame for the console.
+//
+// The frame fits in style.width display cells, wrapping the header and the thought text as needed.
+func formatThought(record ThoughtRecord, style consoleStyle) string {
+	label, color, context := thoughtKind(record)
+	inner := style.width - 4 // "│ " and " │"
+
+	header := wrapText(fmt.Sprintf("%s %d/%d%s", label, record.ThoughtNumber, record.TotalThoughts, context), inner)
+	body := wrapText(record.Thought, inner)
+
+	content := 0
+	for _, line := range header {
+		content = max(content, displayWidth.StringWidth(line))
+	}
+	for _, line := range body {
+		content = max(content, displayWidth.StringWidth(line))
+	}
+	border := strings.Repeat("─", content+2)
+
+	var b strings.Builder
+	b.WriteString("\n┌" + border + "┐\n")
+	for i, line := range header {
+		padding := strings.Repeat(" ", content-displayWidth.StringWidth(line))
+		if i == 0 && style.color {
+			line = color + label + ansiReset + strings.TrimPrefix(line, label)
+		}
+		b.WriteString("│ " + line + padding + " │\n")
+	}
+	b.WriteString("├" + border + "┤\n")
+	for _, line := range body {
+		b.WriteString("│ " + line + strings.Repeat(" ", content-displayWidth.StringWidth(line)) + " │\n")
+	}
+	b.WriteString("└" + border + "┘")
+	return b.String()
+}
+
+// sanitizeText makes s safe to write to a terminal: tabs become four spaces, carriage returns
+// before a newline are dropped and any other control character, such as the escape starting an
+// ANSI sequence, is replaced by U+FFFD.
+func sanitizeText(s string) string {
+	s = strings.NewReplacer("\r\n", "\n", "\t", "    ").Replace(s)
+	return strings.Map(func(r rune) rune {
+		if r != '\n' && unicode.IsControl(r) {
+			return unicode.ReplacementChar
+		}
+		return r
+	}, s)
+}
+
+// wrapText splits s into lines of at most width display cells.
+//
+// Lines break at embedded newlines and at spaces where possible. Words wider than width are broken
+// between grapheme clusters, and a cluster wider than width gets a line of its own.
+func wrapText(s string, width int) []string {
+	var lines []string
+	for paragraph := range strings.SplitSeq(sanitizeText(s), "\n") {
+		var (
+			line      strings.Builder
+			lineWidth int
+			lastSpace = -1 // byte offset in line of the last space, where it can be broken
+		)
+		g := graphemes.FromString(paragraph)
+		for g.Next() {
+			cluster := g.Value()
+			w := displayWidth.StringWidth(cluster)
+			if lineWidth+w > width && lineWidth > 0 {
+				if cluster == " " {
+					// break at this space
+					lines = append(lines, strings.TrimRight(line.String(), " "))
+					line.Reset()
+					lineWidth, lastSpace = 0, -1
+					continue
+				}
+				text := line.String()
+				line.Reset()
+				if lastSpace >= 0 {
+					lines = append(lines, strings.TrimRight(text[:lastSpace], " "))
+					line.WriteString(text[lastSpace+1:])
+				} else {
+					lines = append(lines, text)
+				}
+				lineWidth, lastSpace = displayWidth.StringWidth(line.String()), -1
+			}
+			if cluster == " " {
+				lastSpace = line.Len()
+			}
+			line.WriteString(cluster)
+			lineWidth += w
+		}
+		lines = append(lines, strings.TrimRight(line.String(), " "))
+	}
+	return lines
+}
@@ -0,0 +1,268 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+// checkGolden compares got with the golden file testdata/name.golden, or rewrites it with -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("update golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file: %v", err)
+	}
+	if diff := cmp.Diff(string(want), got); diff != "" {
+		t.Fatalf("%s mismatch (-want +got):\n%s", path, diff)
+	}
+}
+
+func TestNewConsole(t *testing.T) {
+	tests := map[string]struct {
+		mode    string
+		wantNil bool
+		wantErr bool
+	}{
+		"success: disabled": {
+			wantNil: true,
+		},
+		"success: frame": {
+			mode: consoleFrame,
+		},
+		"success: tree": {
+			mode: consoleTree,
+		},
+		"error: unknown mode": {
+			mode:    "fancy",
+			wantNil: true,
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := newConsole(tt.mode, &strings.Builder{}, consoleStyle{width: defaultConsoleWidth})
+			if diff := cmp.Diff(tt.wantErr, err != nil); diff != "" {
+				t.Fatalf("error presence mismatch %v (-want +got):\n%s", err, diff)
+			}
+			if diff := cmp.Diff(tt.wantNil, got == nil); diff != "" {
+				t.Fatalf("console nil mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewConsoleStyle(t *testing.T) {
+	pipe, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	t.Cleanup(func() {
+		pipe.Close()
+		w.Close()
+	})
+
+	tests := map[string]struct {
+		w         *os.File
+		width     int
+		noColor   bool
+		exclusive bool
+		want      consoleStyle
+	}{
+		"success: not a terminal": {
+			w:    w,
+			want: consoleStyle{width: defaultConsoleWidth},
+		},
+		"success: exclusive but not a terminal": {
+			w:         w,
+			exclusive: true,
+			want:      consoleStyle{width: defaultConsoleWidth},
+		},
+		"success: configured width": {
+			w:     w,
+			width: 120,
+			want:  consoleStyle{width: 120},
+		},
+		"success: narrowest width": {
+			w:     w,
+			width: 3,
+			want:  consoleStyle{width: minConsoleWidth},
+		},
+		"success: no color": {
+			w:       w,
+			noColor: true,
+			want:    consoleStyle{width: defaultConsoleWidth},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := newConsoleStyle(tt.w, tt.width, tt.noColor, tt.exclusive)
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(consoleStyle{})); diff != "" {
+				t.Fatalf("style mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFormatThought(t *testing.T) {
+	tests := map[string]struct {
+		input ThoughtRecord
+		style consoleStyle
+	}{
+		"thought": {
+			input: ThoughtRecord{Thought: "think", ThoughtNumber: 3, TotalThoughts: 3},
+			style: consoleStyle{width: defaultConsoleWidth},
+		},
+		"revision": {
+			input: ThoughtRecord{Thought: "revise", ThoughtNumber: 1, TotalThoughts: 2, IsRevision: true, RevisesThought: 2},
+			style: consoleStyle{width: defaultConsoleWidth},
+		},
+		"branch": {
+			input: ThoughtRecord{Thought: "branch", ThoughtNumber: 2, TotalThoughts: 3, BranchFromThought: 1, BranchID: "b1"},
+			style: consoleStyle{width: defaultConsoleWidth},
+		},
+		"color": {
+			input: ThoughtRecord{Thought: "colored", ThoughtNumber: 1, TotalThoughts: 1},
+			style: consoleStyle{width: defaultConsoleWidth, color: true},
+		},
+		"wide": {
+			input: ThoughtRecord{Thought: "日本語のテキストと絵文字 🧠👩‍💻 を混ぜる", ThoughtNumber: 1, TotalThoughts: 2},
+			style: consoleStyle{width: defaultConsoleWidth},
+		},
+		"multiline": {
+			input: ThoughtRecord{Thought: "first line\n\n\tindented line\r\nlast line", ThoughtNumber: 2, TotalThoughts: 2},
+			style: consoleStyle{width: defaultConsoleWidth},
+		},
+		"wrapped": {
+			input: ThoughtRecord{Thought: strings.Repeat("The quick brown fox jumps over the lazy dog. ", 6) + strings.Repeat("x", 50), ThoughtNumber: 4, TotalThoughts: 9},
+			style: consoleStyle{width: 40},
+		},
+		"wrapped header": {
+			input: ThoughtRecord{Thought: "short", ThoughtNumber: 12, TotalThoughts: 20, BranchFromThought: 11, BranchID: "a-rather-long-branch-identifier"},
+			style: consoleStyle{width: 40, color: true},
+		},
+		"escape sequence": {
+			input: ThoughtRecord{Thought: "clear \x1b[2J the screen", ThoughtNumber: 1, TotalThoughts: 1},
+			style: consoleStyle{width: defaultConsoleWidth},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := formatThought(tt.input, tt.style)
+			checkGolden(t, "frame_"+strings.ReplaceAll(name, " ", "_"), got)
+
+			if !tt.style.color {
+				for line := range strings.Lines(strings.TrimPrefix(got, "\n")) {
+					if w := displayWidth.StringWidth(strings.TrimSuffix(line, "\n")); w > tt.style.width {
+						t.Errorf("line %q is %d cells wide, want at most %d", line, w, tt.style.width)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := map[string]struct {
+		s     string
+		width int
+		want  []string
+	}{
+		"success: empty": {
+			s:     "",
+			width: 10,
+			want:  []string{""},
+		},
+		"success: fits": {
+			s:     "one two",
+			width: 10,
+			want:  []string{"one two"},
+		},
+		"success: breaks at spaces": {
+			s:     "one two three four",
+			width: 9,
+			want:  []string{"one two", "three", "four"},
+		},
+		"success: breaks long words": {
+			s:     "abcdefghij",
+			width: 4,
+			want:  []string{"abcd", "efgh", "ij"},
+		},
+		"success: wide characters": {
+			s:     "日本語テキスト",
+			width: 5,
+			want:  []string{"日本", "語テ", "キス", "ト"},
+		},
+		"success: keeps grapheme clusters": {
+			s:     "👩‍💻👩‍💻👩‍💻",
+			width: 4,
+			want:  []string{"👩‍💻👩‍💻", "👩‍💻"},
+		},
+		"success: embedded newlines": {
+			s:     "a\n\nb",
+			width: 4,
+			want:  []string{"a", "", "b"},
+		},
+		"success: control characters": {
+			s:     "a\tb\x07",
+			width: 10,
+			want:  []string{"a    b�"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, wrapText(tt.s, tt.width)); diff != "" {
+				t.Fatalf("lines mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFrameConsoleThoughtAppended(t *testing.T) {
+	var b strings.Builder
+	thinking := NewSequentialThinkingServer()
+	thinking.observe(&frameConsole{w: &b, style: consoleStyle{width: defaultConsoleWidth}})
+
+	if _, _, err := thinking.ProcessThought(t.Context(), nil, ThoughtData{Thought: "log this", ThoughtNumber: 1, TotalThoughts: 1}); err != nil {
+		t.Fatalf("process thought: %v", err)
+	}
+	for _, want := range []string{"log this", "💭 Thought 1/1"} {
+		if diff := cmp.Diff(true, strings.Contains(b.String(), want)); diff != "" {
+			t.Fatalf("console output %q missing %q (-want +got):\n%s", b.String(), want, diff)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/bytedance/sonic"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Machine-readable reasons reported in [ToolError].
+const (
+	reasonInvalidThought         = "invalid_thought"
+	reasonInvalidThoughtNumber   = "invalid_thought_number"
+	reasonInvalidTotalThoughts   = "invalid_total_thoughts"
+	reasonRevisionMissingTarget  = "revision_missing_target"
+	reasonRevisionWithoutFlag    = "revision_without_flag"
+	reasonRevisionTargetNotFound = "revision_target_not_found"
+	reasonBranchMissingID        = "branch_missing_id"
+	reasonBranchOriginNotFound   = "branch_origin_not_found"
+	reasonBranchNotFound         = "branch_not_found"
+	reasonBranchOriginMismatch   = "branch_origin_mismatch"
+	reasonThoughtNotFound        = "thought_not_found"
+	reasonInvalidRange           = "invalid_range"
+	reasonInvalidFormat          = "invalid_format"
+	reasonPrincipalMismatch      = "principal_mismatch"
+	reasonThoughtTooLarge        = "thought_too_large"
+	reasonSessionThoughtLimit    = "session_thought_limit"
+	reasonSessionBytesLimit      = "session_bytes_limit"
+	reasonSessionLimit           = "session_limit"
+	reasonRateLimited            = "rate_limited"
+)
+
+// ToolError is a rejected tool call reported to the client as a tool error result,
+// so the model can see what was wrong with its input and correct it.
+type ToolError struct {
+	Message string `json:"error"`
+	Reason  string `json:"reason"`
+	// RetryAfterMs is how long to wait before retrying a call rejected by a rate limit, in milliseconds.
+	RetryAfterMs int64 `json:"retryAfterMs,omitzero"`
+}
+
+// newToolError returns a [ToolError] with reason and a formatted message.
+func newToolError(reason, format string, args ...any) *ToolError {
+	return &ToolError{
+		Message: fmt.Sprintf(format, args...),
+		Reason:  reason,
+	}
+}
+
+// Error implements error.
+func (e *ToolError) Error() string {
+	return e.Message
+}
+
+// toolErrorResult returns the IsError tool result reporting e as a JSON text content.
+func toolErrorResult(e *ToolError) (*mcp.CallToolResult, error) {
+	data, err := sonic.ConfigFastest.MarshalToString(e)
+	if err != nil {
+		return nil, fmt.Errorf("marshal tool error: %w", err)
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: data,
+			},
+		},
+		IsError: true,
+	}, nil
+}
@@ -0,0 +1,418 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Export formats of a thinking session.
+const (
+	exportMarkdown = "markdown"
+	exportJSON     = "json"
+	exportMermaid  = "mermaid"
+	exportDOT      = "dot"
+)
+
+// exportFormats lists the export formats with their MIME types.
+var exportFormats = []struct {
+	name     string
+	mimeType string
+}{
+	{exportMarkdown, mimeTypeMarkdown},
+	{exportJSON, mimeTypeJSON},
+	{exportMermaid, "text/vnd.mermaid"},
+	{exportDOT, "text/vnd.graphviz"},
+}
+
+// exportLabelWidth is the maximum number of runes of a thought in a graph node label.
+const exportLabelWidth = 60
+
+// exportVersion is the version of the canonical JSON export document.
+const exportVersion = 1
+
+// exportDocument is the canonical JSON export of a thinking session.
+type exportDocument struct {
+	Version int             `json:"version"`
+	Session SessionSnapshot `json:"session"`
+}
+
+// ExportSessionInput is the input of the export_session tool.
+type ExportSessionInput struct {
+	Format string `json:"format" jsonschema:"Export format: markdown, json (canonical), mermaid (flowchart) or dot (Graphviz)"`
+}
+
+// ExportSessionOutput is the output of the export_session tool.
+type ExportSessionOutput struct {
+	Format   string `json:"format" jsonschema:"Export format"`
+	MIMEType string `json:"mimeType" jsonschema:"MIME type of the content"`
+	Content  string `json:"content" jsonschema:"The exported session"`
+}
+
+// exportMIMEType returns the MIME type of format, or false if format is unknown.
+func exportMIMEType(format string) (string, bool) {
+	for _, f := range exportFormats {
+		if f.name == format {
+			return f.mimeType, true
+		}
+	}
+	return "", false
+}
+
+// exportFormatNames returns the names of the export formats.
+func exportFormatNames() []string {
+	names := make([]string, 0, len(exportFormats))
+	for _, f := range exportFormats {
+		names = append(names, f.name)
+	}
+	return names
+}
+
+// exportSession writes snap to w in format.
+func exportSession(w io.Writer, snap SessionSnapshot, format string) error {
+	switch format {
+	case exportMarkdown:
+		_, err := io.WriteString(w, renderSessionMarkdown(snap))
+		return err
+
+	case exportJSON:
+		return exportSessionJSON(w, snap)
+
+	case exportMermaid:
+		return exportSessionMermaid(w, snap)
+
+	case exportDOT:
+		return exportSessionDOT(w, snap)
+	}
+
+	return fmt.Errorf("unknown export format %q, must be one of %s", format, strings.Join(exportFormatNames(), ", "))
+}
+
+// exportSessionJSON writes snap as an indented [exportDocument] with fields in declaration order and UTC timestamps,
+// so that exporting the same session always yields the same bytes.
+func exportSessionJSON(w io.Writer, snap SessionSnapshot) error {
+	doc := exportDocument{
+		Version: exportVersion,
+		Session: SessionSnapshot{
+			ID:        snap.ID,
+			Principal: snap.Principal,
+			CreatedAt: snap.CreatedAt.UTC(),
+			Thoughts:  make([]ThoughtRecord, 0, len(snap.Thoughts)),
+			Branches:  snap.Branches,
+		},
+	}
+	if doc.Session.Branches == nil {
+		doc.Session.Branches = make([]BranchInfo, 0)
+	}
+	for _, record := range snap.Thoughts {
+		record.Timestamp = record.Timestamp.UTC()
+		doc.Session.Thoughts = append(doc.Session.Thoughts, record)
+	}
+
+	data, err := sonic.ConfigStd.MarshalIndent(&doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal export: %w", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Kinds of edges of the thought graph.
+const (
+	// edgeNext links a thought to the next thought of the same branch, or of the main line.
+	edgeNext = iota
+	// edgeBranch links the origin thought of a branch to the first thought of the branch.
+	edgeBranch
+	// edgeRevises links a revision to the thought it revises.
+	edgeRevises
+)
+
+// thoughtEdge is an edge between two thoughts of the graph, identified by their index in the history.
+type thoughtEdge struct {
+	from, to int
+	kind     int
+	label    string
+}
+
+// lineThought identifies a thought by its branch, "" for the main line, and its number.
+type lineThought struct {
+	branchID string
+	number   int
+}
+
+// thoughtGraph returns the edges between the thoughts of snap in history order.
+//
+// Branches number their thoughts from their origin, so a thought number may be recorded on several
+// branches. A reference from a thought resolves to the number on its own branch, then on the main line,
+// then to the latest thought recorded with the number.
+func thoughtGraph(snap SessionSnapshot) []thoughtEdge {
+	var edges []thoughtEdge
+	numbers := make(map[lineThought]int)
+	latest := make(map[int]int)
+	resolve := func(branchID string, n int) (int, bool) {
+		for _, key := range []lineThought{{branchID, n}, {"", n}} {
+			if i, ok := numbers[key]; ok {
+				return i, true
+			}
+		}
+		i, ok := latest[n]
+		return i, ok
+	}
+	last := make(map[string]int)
+	for i, record := range snap.Thoughts {
+		prev, ok := last[record.BranchID]
+		switch {
+		case ok:
+			edges = append(edges, thoughtEdge{from: prev, to: i, kind: edgeNext})
+		case record.BranchID != "":
+			if origin, ok := resolve(record.BranchID, record.BranchFromThought); ok {
+				edges = append(edges, thoughtEdge{from: origin, to: i, kind: edgeBranch, label: record.BranchID})
+			}
+		}
+		if record.IsRevision {
+			if target, ok := resolve(record.BranchID, record.RevisesThought); ok {
+				edges = append(edges, thoughtEdge{from: i, to: target, kind: edgeRevises, label: "revises"})
+			}
+		}
+		numbers[lineThought{record.BranchID, record.ThoughtNumber}] = i
+		latest[record.ThoughtNumber] = i
+		last[record.BranchID] = i
+	}
+	return edges
+}
+
+// thoughtLabel returns the graph node label of record, with the thought collapsed to one line
+// and shortened to [exportLabelWidth] runes.
+func thoughtLabel(record ThoughtRecord) string {
+	text := strings.Join(strings.Fields(record.Thought), " ")
+	if runes := []rune(text); len(runes) > exportLabelWidth {
+		text = string(runes[:exportLabelWidth-1]) + "…"
+	}
+	return fmt.Sprintf("%d/%d %s", record.ThoughtNumber, record.TotalThoughts, text)
+}
+
+// branchNodes groups the indexes of the branch thoughts of snap by branch ID.
+func branchNodes(snap SessionSnapshot) map[string][]int {
+	nodes := make(map[string][]int)
+	for i, record := range snap.Thoughts {
+		if record.BranchID != "" {
+			nodes[record.BranchID] = append(nodes[record.BranchID], i)
+		}
+	}
+	return nodes
+}
+
+// exportSessionMermaid writes snap as a Mermaid flowchart with one subgraph per branch.
+func exportSessionMermaid(w io.Writer, snap SessionSnapshot) error {
+	var buf bytes.Buffer
+	buf.WriteString("flowchart TD\n")
+	for i, record := range snap.Thoughts {
+		if record.BranchID == "" {
+			fmt.Fprintf(&buf, "  t%d[\"%s\"]\n", i+1, mermaidEscape(thoughtLabel(record)))
+		}
+	}
+	nodes := branchNodes(snap)
+	for bi, br := range snap.Branches {
+		fmt.Fprintf(&buf, "  subgraph b%d[\"branch %s\"]\n", bi+1, mermaidEscape(br.ID))
+		for _, i := range nodes[br.ID] {
+			fmt.Fprintf(&buf, "    t%d[\"%s\"]\n", i+1, mermaidEscape(thoughtLabel(snap.Thoughts[i])))
+		}
+		buf.WriteString("  end\n")
+	}
+	for _, e := range thoughtGraph(snap) {
+		switch e.kind {
+		case edgeNext:
+			fmt.Fprintf(&buf, "  t%d --> t%d\n", e.from+1, e.to+1)
+		case edgeBranch:
+			fmt.Fprintf(&buf, "  t%d -->|\"%s\"| t%d\n", e.from+1, mermaidEscape(e.label), e.to+1)
+		case edgeRevises:
+			fmt.Fprintf(&buf, "  t%d -.->|%s| t%d\n", e.from+1, e.label, e.to+1)
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// mermaidEscape escapes s for a quoted Mermaid label using entity codes.
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(
+		"#", "#35;",
+		`"`, "#quot;",
+		"<", "#lt;",
+		">", "#gt;",
+	).Replace(s)
+}
+
+// exportSessionDOT writes snap as a Graphviz digraph with one cluster per branch.
+func exportSessionDOT(w io.Writer, snap SessionSnapshot) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "digraph %s {\n", strconv.Quote("session "+snap.ID))
+	buf.WriteString("  node [shape=box];\n")
+	for i, record := range snap.Thoughts {
+		if record.BranchID == "" {
+			fmt.Fprintf(&buf, "  t%d [label=%s];\n", i+1, dotQuote(thoughtLabel(record)))
+		}
+	}
+	nodes := branchNodes(snap)
+	for bi, br := range snap.Branches {
+		fmt.Fprintf(&buf, "  subgraph cluster_%d {\n", bi+1)
+		fmt.Fprintf(&buf, "    label=%s;\n", dotQuote("branch "+br.ID))
+		for _, i := range nodes[br.ID] {
+			fmt.Fprintf(&buf, "    t%d [label=%s];\n", i+1, dotQuote(thoughtLabel(snap.Thoughts[i])))
+		}
+		buf.WriteString("  }\n")
+	}
+	for _, e := range thoughtGraph(snap) {
+		switch e.kind {
+		case edgeNext:
+			fmt.Fprintf(&buf, "  t%d -> t%d;\n", e.from+1, e.to+1)
+		case edgeBranch:
+			fmt.Fprintf(&buf, "  t%d -> t%d [label=%s];\n", e.from+1, e.to+1, dotQuote(e.label))
+		case edgeRevises:
+			fmt.Fprintf(&buf, "  t%d -> t%d [style=dashed, label=%s];\n", e.from+1, e.to+1, dotQuote(e.label))
+		}
+	}
+	buf.WriteString("}\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// dotQuote returns s as a DOT double-quoted string.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// registerExportTool adds the export_session tool exporting the caller's session to srv.
+func registerExportTool(srv *mcp.Server, thinking *SequentialThinkingServer) error {
+	tool, err := newQueryTool[ExportSessionInput, ExportSessionOutput]("export_session",
+		"Export the sequential thinking of this session with its revisions and branches as Markdown, canonical JSON, a Mermaid flowchart or a Graphviz DOT graph.")
+	if err != nil {
+		return err
+	}
+	enum := make([]any, 0, len(exportFormats))
+	for _, name := range exportFormatNames() {
+		enum = append(enum, name)
+	}
+	tool.InputSchema.(*jsonschema.Schema).Properties["format"].Enum = enum
+	mcp.AddTool(srv, tool, thinking.ExportSession)
+
+	return nil
+}
+
+// ExportSession exports the caller's session in the requested format.
+func (s *SequentialThinkingServer) ExportSession(ctx context.Context, request *mcp.CallToolRequest, input ExportSessionInput) (*mcp.CallToolResult, any, error) {
+	mimeType, ok := exportMIMEType(input.Format)
+	if !ok {
+		result, err := toolErrorResult(newToolError(reasonInvalidFormat, "invalid format %q: must be one of %s", input.Format, strings.Join(exportFormatNames(), ", ")))
+		return result, nil, err
+	}
+
+	snap, _, terr := s.callerSnapshot(request)
+	if terr != nil {
+		result, err := toolErrorResult(terr)
+		return result, nil, err
+	}
+
+	var sb strings.Builder
+	if err := exportSession(&sb, snap, input.Format); err != nil {
+		return nil, nil, fmt.Errorf("export session: %w", err)
+	}
+
+	return toolResult(ExportSessionOutput{
+		Format:   input.Format,
+		MIMEType: mimeType,
+		Content:  sb.String(),
+	})
+}
+
+// runExport runs the export subcommand, which exports a session stored in a journal to stdout or a file.
+func runExport(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: mcp-sequential-thinking export [flags] <journal.jsonl | store directory>\n\n")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", exportMarkdown, "export format: "+strings.Join(exportFormatNames(), ", "))
+	session := fs.String("session", "", "session to export when the path is a store directory")
+	out := fs.String("o", "", "if set, write the export to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("export: expected one journal path, got %d arguments", fs.NArg())
+	}
+	if _, ok := exportMIMEType(*format); !ok {
+		return fmt.Errorf("export: unknown format %q, must be one of %s", *format, strings.Join(exportFormatNames(), ", "))
+	}
+
+	path := fs.Arg(0)
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	if fi.IsDir() {
+		if *session == "" {
+			return fmt.Errorf("export: -session is required to export from store directory %q", path)
+		}
+		path = (&journalStore{dir: path}).journalPath(*session)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	jn, err := readJournal(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("export: read journal %q: %w", path, err)
+	}
+	if jn.header.Version == 0 {
+		return fmt.Errorf("export: journal %q has no header", path)
+	}
+	snap := jn.session().snapshot()
+
+	if *out == "" {
+		if err := exportSession(stdout, snap, *format); err != nil {
+			return fmt.Errorf("export: %w", err)
+		}
+		return nil
+	}
+	outFile, err := os.Create(*out)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	if err := exportSession(outFile, snap, *format); err != nil {
+		outFile.Close()
+		return fmt.Errorf("export: %w", err)
+	}
+	return outFile.Close()
+}
@@ -0,0 +1,324 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-json-experiment/json"
+	"github.com/google/go-cmp/cmp"
+)
+
+// exportSnapshot returns a session with a revision and a branch recorded at fixed times.
+func exportSnapshot() SessionSnapshot {
+	ts := newThinkingSession("s1", time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	for i, input := range []ThoughtData{
+		{Thought: `frame the "problem"`, ThoughtNumber: 1, TotalThoughts: 3, NextThoughtNeeded: true},
+		{Thought: "try\nanother angle", ThoughtNumber: 2, TotalThoughts: 3, NextThoughtNeeded: true, BranchFromThought: 1, BranchID: "alt"},
+		{Thought: "continue the main line", ThoughtNumber: 2, TotalThoughts: 3, NextThoughtNeeded: true},
+		{Thought: "reframe <it>", ThoughtNumber: 3, TotalThoughts: 3, IsRevision: true, RevisesThought: 1},
+	} {
+		ts.appendThought(newThoughtRecord(input, ts.id, ts.created.Add(time.Duration(i+1)*time.Second)))
+	}
+	ts.principal = "alice"
+	return ts.snapshot()
+}
+
+// collidingSnapshot returns a session whose branch records thought numbers that the main line records too,
+// and whose main line then revises one of them.
+func collidingSnapshot() SessionSnapshot {
+	ts := newThinkingSession("s2", time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	for i, input := range []ThoughtData{
+		{Thought: "frame", ThoughtNumber: 1, TotalThoughts: 4, NextThoughtNeeded: true},
+		{Thought: "main step", ThoughtNumber: 2, TotalThoughts: 4, NextThoughtNeeded: true},
+		{Thought: "branch step", ThoughtNumber: 2, TotalThoughts: 4, NextThoughtNeeded: true, BranchFromThought: 1, BranchID: "alt"},
+		{Thought: "branch revision", ThoughtNumber: 3, TotalThoughts: 4, NextThoughtNeeded: true, IsRevision: true, RevisesThought: 2, BranchID: "alt"},
+		{Thought: "main revision", ThoughtNumber: 3, TotalThoughts: 4, IsRevision: true, RevisesThought: 2},
+	} {
+		ts.appendThought(newThoughtRecord(input, ts.id, ts.created.Add(time.Duration(i+1)*time.Second)))
+	}
+	return ts.snapshot()
+}
+
+func TestExportSession(t *testing.T) {
+	tests := map[string]struct {
+		snap    func() SessionSnapshot
+		format  string
+		want    string
+		wantErr bool
+	}{
+		"success: mermaid": {
+			format: exportMermaid,
+			want: `flowchart TD
+  t1["1/3 frame the #quot;problem#quot;"]
+  t3["2/3 continue the main line"]
+  t4["3/3 reframe #lt;it#gt;"]
+  subgraph b1["branch alt"]
+    t2["2/3 try another angle"]
+  end
+  t1 -->|"alt"| t2
+  t1 --> t3
+  t3 --> t4
+  t4 -.->|revises| t1
+`,
+		},
+		"success: dot": {
+			format: exportDOT,
+			want: `digraph "session s1" {
+  node [shape=box];
+  t1 [label="1/3 frame the \"problem\""];
+  t3 [label="2/3 continue the main line"];
+  t4 [label="3/3 reframe <it>"];
+  subgraph cluster_1 {
+    label="branch alt";
+    t2 [label="2/3 try another angle"];
+  }
+  t1 -> t2 [label="alt"];
+  t1 -> t3;
+  t3 -> t4;
+  t4 -> t1 [style=dashed, label="revises"];
+}
+`,
+		},
+		"success: mermaid with colliding thought numbers": {
+			snap:   collidingSnapshot,
+			format: exportMermaid,
+			want: `flowchart TD
+  t1["1/4 frame"]
+  t2["2/4 main step"]
+  t5["3/4 main revision"]
+  subgraph b1["branch alt"]
+    t3["2/4 branch step"]
+    t4["3/4 branch revision"]
+  end
+  t1 --> t2
+  t1 -->|"alt"| t3
+  t3 --> t4
+  t4 -.->|revises| t3
+  t2 --> t5
+  t5 -.->|revises| t2
+`,
+		},
+		"success: dot with colliding thought numbers": {
+			snap:   collidingSnapshot,
+			format: exportDOT,
+			want: `digraph "session s2" {
+  node [shape=box];
+  t1 [label="1/4 frame"];
+  t2 [label="2/4 main step"];
+  t5 [label="3/4 main revision"];
+  subgraph cluster_1 {
+    label="branch alt";
+    t3 [label="2/4 branch step"];
+    t4 [label="3/4 branch revision"];
+  }
+  t1 -> t2;
+  t1 -> t3 [label="alt"];
+  t3 -> t4;
+  t4 -> t3 [style=dashed, label="revises"];
+  t2 -> t5;
+  t5 -> t2 [style=dashed, label="revises"];
+}
+`,
+		},
+		"error: unknown format": {
+			format:  "pdf",
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			snap := exportSnapshot
+			if tt.snap != nil {
+				snap = tt.snap
+			}
+			var sb strings.Builder
+			err := exportSession(&sb, snap(), tt.format)
+			if diff := cmp.Diff(tt.wantErr, err != nil); diff != "" {
+				t.Fatalf("error presence mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.want, sb.String()); diff != "" {
+				t.Fatalf("export mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExportSessionJSON(t *testing.T) {
+	snap := exportSnapshot()
+
+	var first, second strings.Builder
+	if err := exportSession(&first, snap, exportJSON); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	// the same session in another time zone exports the same bytes
+	local := snap
+	local.CreatedAt = snap.CreatedAt.In(time.FixedZone("JST", 9*60*60))
+	if err := exportSession(&second, local, exportJSON); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if diff := cmp.Diff(first.String(), second.String()); diff != "" {
+		t.Fatalf("canonical export mismatch (-want +got):\n%s", diff)
+	}
+
+	var doc exportDocument
+	if err := json.Unmarshal([]byte(first.String()), &doc); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	if diff := cmp.Diff(exportDocument{Version: exportVersion, Session: snap}, doc); diff != "" {
+		t.Fatalf("export document mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSequentialThinkingServerExportSession(t *testing.T) {
+	cs := connectInMemoryClient(t, NewSequentialThinkingServer())
+	recordThoughts(t, cs, resourceThoughts...)
+
+	tests := map[string]struct {
+		input        ExportSessionInput
+		wantMIMEType string
+		wantContent  string
+	}{
+		"success: markdown": {
+			input:        ExportSessionInput{Format: exportMarkdown},
+			wantMIMEType: mimeTypeMarkdown,
+			wantContent:  "### Thought 3/3 (revises thought 1)",
+		},
+		"success: mermaid": {
+			input:        ExportSessionInput{Format: exportMermaid},
+			wantMIMEType: "text/vnd.mermaid",
+			wantContent:  "t3 -.->|revises| t1",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got ExportSessionOutput
+			if reason := callQueryTool(t, cs, "export_session", tt.input, &got); reason != "" {
+				t.Fatalf("tool error: %s", reason)
+			}
+			if diff := cmp.Diff(tt.wantMIMEType, got.MIMEType); diff != "" {
+				t.Fatalf("MIME type mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(true, strings.Contains(got.Content, tt.wantContent)); diff != "" {
+				t.Fatalf("content missing %q (-want +got):\n%s", tt.wantContent, diff)
+			}
+		})
+	}
+}
+
+func TestSequentialThinkingServerExportSessionInvalidFormat(t *testing.T) {
+	// the input schema rejects unknown formats before the handler is reached over MCP
+	result, out, err := NewSequentialThinkingServer().ExportSession(t.Context(), nil, ExportSessionInput{Format: "pdf"})
+	if err != nil {
+		t.Fatalf("export session: %v", err)
+	}
+	if diff := cmp.Diff(true, result.IsError); diff != "" {
+		t.Fatalf("result IsError mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(nil, out); diff != "" {
+		t.Fatalf("output mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(true, strings.Contains(resultText(t, result), reasonInvalidFormat)); diff != "" {
+		t.Fatalf("reason missing from text (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunExport(t *testing.T) {
+	dir := t.TempDir()
+	js, err := openJournalStore(dir)
+	if err != nil {
+		t.Fatalf("open journal store: %v", err)
+	}
+	snap := exportSnapshot()
+	for _, record := range snap.Thoughts {
+		if err := js.Append(record, snap.CreatedAt); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	if err := js.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	var want strings.Builder
+	if err := exportSession(&want, snap, exportDOT); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	outPath := filepath.Join(t.TempDir(), "session.dot")
+
+	tests := map[string]struct {
+		args    []string
+		wantOut string
+		wantErr string
+	}{
+		"success: journal file": {
+			args:    []string{"-format", "dot", js.journalPath("s1")},
+			wantOut: want.String(),
+		},
+		"success: store directory": {
+			args:    []string{"-format=dot", "-session", "s1", dir},
+			wantOut: want.String(),
+		},
+		"success: output file": {
+			args: []string{"-format=dot", "-o", outPath, js.journalPath("s1")},
+		},
+		"error: store directory without session": {
+			args:    []string{dir},
+			wantErr: "-session is required",
+		},
+		"error: unknown format": {
+			args:    []string{"-format=pdf", dir},
+			wantErr: "unknown format",
+		},
+		"error: missing journal": {
+			args:    []string{filepath.Join(dir, "missing.jsonl")},
+			wantErr: "no such file",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var stdout strings.Builder
+			err := runExport(tt.args, &stdout)
+			if diff := cmp.Diff(tt.wantErr != "", err != nil); diff != "" {
+				t.Fatalf("error presence mismatch %v (-want +got):\n%s", err, diff)
+			}
+			if err != nil {
+				if diff := cmp.Diff(true, strings.Contains(err.Error(), tt.wantErr)); diff != "" {
+					t.Fatalf("error text mismatch %q (-want +got):\n%s", err, diff)
+				}
+				return
+			}
+			if diff := cmp.Diff(tt.wantOut, stdout.String()); diff != "" {
+				t.Fatalf("stdout mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("read output file: %v", err)
+	}
+	if diff := cmp.Diff(want.String(), string(data)); diff != "" {
+		t.Fatalf("output file mismatch (-want +got):\n%s", diff)
+	}
+}
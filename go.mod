@@ -3,13 +3,18 @@ module github.com/zchee/mcp-sequential-thinking
 go 1.26
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/bytedance/sonic v1.15.1-0.20260316072832-3835c030aefd // @main
+	github.com/clipperhouse/uax29/v2 v2.2.0
 	github.com/go-json-experiment/json v0.0.0-20260214004413-d219187c3433
 	github.com/google/go-cmp v0.7.0
 	github.com/google/jsonschema-go v0.4.3-0.20251219210730-8bd57428bbbe // @main
 	github.com/google/uuid v1.6.0
+	github.com/mattn/go-runewidth v0.0.19
 	github.com/modelcontextprotocol/go-sdk v1.4.1-0.20260323073527-755b9ed4dfe2 // @main
 	github.com/zchee/dumper v1.8.1
+	golang.org/x/term v0.41.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
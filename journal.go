@@ -0,0 +1,401 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+)
+
+// journalVersion is the format version written in the header of every journal.
+const journalVersion = 1
+
+// journalExt is the file extension of session journals.
+const journalExt = ".jsonl"
+
+// journalHeader is the first line of a session journal.
+type journalHeader struct {
+	Version   int       `json:"version"`
+	SessionID string    `json:"sessionId"`
+	Principal string    `json:"principal,omitzero"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// journal is the decoded content of a session journal.
+type journal struct {
+	header  journalHeader
+	records []ThoughtRecord
+	// size is the length in bytes of the decoded lines of the journal.
+	size int64
+	// unterminated reports whether the last decoded line lacks its trailing newline.
+	unterminated bool
+}
+
+// journalStore persists accepted thoughts as one append-only JSONL journal per session in a directory.
+//
+// Each journal starts with a [journalHeader] line followed by one [ThoughtRecord] line per thought.
+// Every append is synced to disk before it returns. Once the journal of a session or the whole store
+// is closed, appends fail with [errJournalClosed] until the session is created again.
+type journalStore struct {
+	dir string
+
+	mu    sync.Mutex
+	files map[string]*os.File
+	// created holds the ids of the sessions created by this process whose journal has not been opened
+	// yet. Their first append archives a journal left by an earlier session with the same id.
+	created map[string]bool
+	// closed holds the ids of the sessions whose journal was closed.
+	closed map[string]bool
+	// shut reports whether the store is closed.
+	shut bool
+}
+
+// errJournalClosed is returned by appends to a closed session journal or store.
+var errJournalClosed = errors.New("journal is closed")
+
+// openJournalStore opens the journal store in dir, creating the directory if needed.
+func openJournalStore(dir string) (*journalStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create store directory %q: %w", dir, err)
+	}
+
+	return &journalStore{
+		dir:     dir,
+		files:   make(map[string]*os.File),
+		created: make(map[string]bool),
+		closed:  make(map[string]bool),
+	}, nil
+}
+
+// journalPath returns the path of the journal of the session id.
+func (js *journalStore) journalPath(id string) string {
+	return filepath.Join(js.dir, url.PathEscape(id)+journalExt)
+}
+
+// Create records that this process created the session id: its first append starts a new journal,
+// and a journal closed by an earlier session with the same id accepts appends again.
+func (js *journalStore) Create(id string) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	js.created[id] = true
+	delete(js.closed, id)
+}
+
+// Append appends record to the journal of its session and syncs it to disk.
+//
+// The journal is created with its header on the first append for the session,
+// which also records the principal of record as the principal of the session.
+func (js *journalStore) Append(record ThoughtRecord, created time.Time) error {
+	line, err := sonic.ConfigFastest.Marshal(&record)
+	if err != nil {
+		return fmt.Errorf("marshal thought record: %w", err)
+	}
+
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	if js.shut || js.closed[record.SessionID] {
+		return fmt.Errorf("session %s: %w", record.SessionID, errJournalClosed)
+	}
+	f, err := js.file(record.SessionID, record.Principal, created)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write journal %q: %w", f.Name(), err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("sync journal %q: %w", f.Name(), err)
+	}
+
+	return nil
+}
+
+// file returns the open journal of the session id, opening or creating it as needed.
+//
+// The first open of the journal of a session created by this process moves a journal left by an
+// earlier session with the same id to [archivePath], so the earlier thoughts stay exportable.
+//
+// The caller must hold js.mu.
+func (js *journalStore) file(id, principal string, created time.Time) (*os.File, error) {
+	if f, ok := js.files[id]; ok {
+		return f, nil
+	}
+
+	path := js.journalPath(id)
+	if js.created[id] {
+		if err := os.Rename(path, archivePath(path, time.Now())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("archive journal %q: %w", path, err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open journal %q: %w", path, err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("stat journal %q: %w", path, err)
+	}
+	if fi.Size() == 0 {
+		header, err := sonic.ConfigFastest.Marshal(&journalHeader{
+			Version:   journalVersion,
+			SessionID: id,
+			Principal: principal,
+			CreatedAt: created,
+		})
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("marshal journal header: %w", err)
+		}
+		if _, err := f.Write(append(header, '\n')); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("write journal header %q: %w", path, err)
+		}
+	}
+
+	delete(js.created, id)
+	js.files[id] = f
+	return f, nil
+}
+
+// archivePath returns the path a journal at path is archived to at now. The archived journal lacks
+// the journal extension, so it is not restored on startup.
+func archivePath(path string, now time.Time) string {
+	return path + "." + now.UTC().Format("20060102T150405.000000000Z")
+}
+
+// CloseSession syncs and closes the journal of the session id, if it is open, and fails later appends
+// to it until the session is created again.
+func (js *journalStore) CloseSession(id string) error {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	delete(js.created, id)
+	js.closed[id] = true
+	f, ok := js.files[id]
+	if !ok {
+		return nil
+	}
+	delete(js.files, id)
+
+	return closeJournalFile(f)
+}
+
+// Close syncs and closes all open journals, and fails every later append.
+func (js *journalStore) Close() error {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	js.shut = true
+	var errs []error
+	for id, f := range js.files {
+		errs = append(errs, closeJournalFile(f))
+		delete(js.files, id)
+	}
+	return errors.Join(errs...)
+}
+
+func closeJournalFile(f *os.File) error {
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("sync journal %q: %w", f.Name(), err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close journal %q: %w", f.Name(), err)
+	}
+	return nil
+}
+
+// Load reads every journal in the store in file name order.
+//
+// A truncated final line, left behind by a crash in the middle of an append, is discarded and
+// cut from the file so later appends start on a clean line.
+func (js *journalStore) Load() ([]journal, error) {
+	entries, err := os.ReadDir(js.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read store directory %q: %w", js.dir, err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var journals []journal
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), journalExt) {
+			continue
+		}
+		jn, err := loadJournal(filepath.Join(js.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if jn.header.Version == 0 {
+			// crashed while writing the header; the journal is rewritten on the next append
+			continue
+		}
+		journals = append(journals, jn)
+	}
+	return journals, nil
+}
+
+// loadJournal reads the journal at path, truncating an incomplete final line.
+func loadJournal(path string) (journal, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return journal{}, fmt.Errorf("open journal %q: %w", path, err)
+	}
+	defer f.Close()
+
+	jn, err := readJournal(f)
+	if err != nil {
+		return journal{}, fmt.Errorf("read journal %q: %w", path, err)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return journal{}, fmt.Errorf("stat journal %q: %w", path, err)
+	}
+	if fi.Size() > jn.size {
+		if err := f.Truncate(jn.size); err != nil {
+			return journal{}, fmt.Errorf("truncate journal %q: %w", path, err)
+		}
+	}
+	if jn.unterminated {
+		if _, err := f.WriteAt([]byte{'\n'}, jn.size); err != nil {
+			return journal{}, fmt.Errorf("terminate journal %q: %w", path, err)
+		}
+	}
+
+	return jn, nil
+}
+
+// readJournal decodes a journal from r.
+//
+// A final line without a trailing newline that fails to decode is a torn write: it is ignored and not
+// counted in the returned size. Any other malformed line is an error. A journal whose header line is
+// torn is returned with a zero header.
+func readJournal(r io.Reader) (journal, error) {
+	var jn journal
+	br := bufio.NewReader(r)
+	for lineno := 1; ; lineno++ {
+		line, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return journal{}, err
+		}
+		complete := err == nil
+		if len(bytes.TrimSpace(line)) == 0 {
+			if !complete {
+				break
+			}
+			jn.size += int64(len(line))
+			continue
+		}
+
+		if err := jn.decodeLine(line); err != nil {
+			if !complete {
+				// torn write of the final line
+				break
+			}
+			return journal{}, fmt.Errorf("line %d: %w", lineno, err)
+		}
+
+		jn.size += int64(len(line))
+		if !complete {
+			jn.unterminated = true
+			break
+		}
+	}
+
+	if jn.header.Version == 0 && jn.size > 0 {
+		return journal{}, errors.New("missing journal header")
+	}
+	return jn, nil
+}
+
+// decodeLine decodes the next non-empty line of the journal into jn.
+func (jn *journal) decodeLine(line []byte) error {
+	if jn.header.Version == 0 {
+		var header journalHeader
+		if err := sonic.ConfigFastest.Unmarshal(line, &header); err != nil {
+			return err
+		}
+		if header.Version != journalVersion {
+			return fmt.Errorf("unsupported journal version %d", header.Version)
+		}
+		jn.header = header
+		return nil
+	}
+
+	var record ThoughtRecord
+	if err := sonic.ConfigFastest.Unmarshal(line, &record); err != nil {
+		return err
+	}
+	jn.records = append(jn.records, record)
+	return nil
+}
+
+// session replays the journal into a new thinking session.
+func (jn journal) session() *thinkingSession {
+	ts := newThinkingSession(jn.header.SessionID, jn.header.CreatedAt)
+	ts.principal = jn.header.Principal
+	for _, record := range jn.records {
+		ts.appendThought(record)
+	}
+	return ts
+}
+
+// restore rebuilds the sessions recorded in js and persists every thought accepted afterwards to it.
+//
+// The session limits apply to the replayed sessions as to live ones: the sessions they evict are
+// not held in memory, and their journals stay in the store.
+func (s *SequentialThinkingServer) restore(js *journalStore) error {
+	journals, err := js.Load()
+	if err != nil {
+		return err
+	}
+
+	sessions := make([]*thinkingSession, 0, len(journals))
+	for _, jn := range journals {
+		sessions = append(sessions, jn.session())
+	}
+	kept, evicted := s.limits.restorable(sessions, time.Now())
+
+	s.mu.Lock()
+	for _, ts := range kept {
+		s.sessions[ts.id] = ts
+	}
+	s.journal = js
+	s.mu.Unlock()
+
+	// the observers never saw the evicted sessions open
+	for _, reason := range []string{evictionIdle, evictionOversize, evictionLRU} {
+		s.evicted(evicted[reason], reason, nil)
+	}
+	return nil
+}
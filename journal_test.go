@@ -0,0 +1,484 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadJournal(t *testing.T) {
+	const header = `{"version":1,"sessionId":"s1","createdAt":"2025-01-02T03:04:05Z"}` + "\n"
+	const first = `{"thought":"first","thoughtNumber":1,"totalThoughts":2,"nextThoughtNeeded":true,"sessionId":"s1","timestamp":"2025-01-02T03:04:06Z"}` + "\n"
+
+	tests := map[string]struct {
+		data             string
+		wantErr          string
+		wantThoughts     []string
+		wantSize         int
+		wantUnterminated bool
+	}{
+		"success: complete journal": {
+			data:         header + first,
+			wantThoughts: []string{"first"},
+			wantSize:     len(header + first),
+		},
+		"success: truncated final line is ignored": {
+			data:         header + first + `{"thought":"sec`,
+			wantThoughts: []string{"first"},
+			wantSize:     len(header + first),
+		},
+		"success: unterminated final line is kept": {
+			data:             header + strings.TrimSuffix(first, "\n"),
+			wantThoughts:     []string{"first"},
+			wantSize:         len(header+first) - 1,
+			wantUnterminated: true,
+		},
+		"success: torn header": {
+			data:     `{"version":1,"sess`,
+			wantSize: 0,
+		},
+		"error: corrupted middle line": {
+			data:    header + "not-json\n" + first,
+			wantErr: "line 2",
+		},
+		"error: unsupported version": {
+			data:    `{"version":2,"sessionId":"s1"}` + "\n",
+			wantErr: "unsupported journal version 2",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			jn, err := readJournal(strings.NewReader(tt.data))
+			if diff := cmp.Diff(tt.wantErr != "", err != nil); diff != "" {
+				t.Fatalf("error presence mismatch (-want +got):\n%s", diff)
+			}
+			if err != nil {
+				if diff := cmp.Diff(true, strings.Contains(err.Error(), tt.wantErr)); diff != "" {
+					t.Fatalf("error text mismatch %q (-want +got):\n%s", err, diff)
+				}
+				return
+			}
+
+			var thoughts []string
+			for _, record := range jn.records {
+				thoughts = append(thoughts, record.Thought)
+			}
+			if diff := cmp.Diff(tt.wantThoughts, thoughts); diff != "" {
+				t.Fatalf("thoughts mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(int64(tt.wantSize), jn.size); diff != "" {
+				t.Fatalf("size mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantUnterminated, jn.unterminated); diff != "" {
+				t.Fatalf("unterminated mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestJournalStoreAppendLoad(t *testing.T) {
+	dir := t.TempDir()
+	js, err := openJournalStore(dir)
+	if err != nil {
+		t.Fatalf("open journal store: %v", err)
+	}
+
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	records := []ThoughtRecord{
+		{Thought: "a1", ThoughtNumber: 1, TotalThoughts: 1, SessionID: "a", Timestamp: created},
+		{Thought: "b1", ThoughtNumber: 1, TotalThoughts: 2, SessionID: "b/1", Timestamp: created},
+		{Thought: "a2", ThoughtNumber: 2, TotalThoughts: 2, SessionID: "a", Timestamp: created},
+	}
+	for _, record := range records {
+		if err := js.Append(record, created); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	if err := js.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	// simulate a crash in the middle of an append
+	f, err := os.OpenFile(js.journalPath("a"), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("open journal: %v", err)
+	}
+	if _, err := f.WriteString(`{"thought":"a3","thoughtNum`); err != nil {
+		t.Fatalf("write torn line: %v", err)
+	}
+	f.Close()
+
+	journals, err := js.Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	got := make(map[string][]string)
+	for _, jn := range journals {
+		if diff := cmp.Diff(created, jn.header.CreatedAt); diff != "" {
+			t.Fatalf("created mismatch (-want +got):\n%s", diff)
+		}
+		for _, record := range jn.records {
+			got[jn.header.SessionID] = append(got[jn.header.SessionID], record.Thought)
+		}
+	}
+	want := map[string][]string{
+		"a":   {"a1", "a2"},
+		"b/1": {"b1"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("journals mismatch (-want +got):\n%s", diff)
+	}
+
+	// the torn line was cut, so the next append lands on a clean line
+	js, err = openJournalStore(dir)
+	if err != nil {
+		t.Fatalf("reopen journal store: %v", err)
+	}
+	if err := js.Append(ThoughtRecord{Thought: "a3", ThoughtNumber: 3, TotalThoughts: 3, SessionID: "a", Timestamp: created}, created); err != nil {
+		t.Fatalf("append after load: %v", err)
+	}
+	if err := js.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	jn, err := loadJournal(filepath.Join(dir, "a"+journalExt))
+	if err != nil {
+		t.Fatalf("load journal: %v", err)
+	}
+	if diff := cmp.Diff(3, len(jn.records)); diff != "" {
+		t.Fatalf("records length mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestJournalStoreReusedSessionID(t *testing.T) {
+	js, err := openJournalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("open journal store: %v", err)
+	}
+	t.Cleanup(func() { js.Close() })
+
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := js.Append(ThoughtRecord{Thought: "old", ThoughtNumber: 1, TotalThoughts: 1, SessionID: "a", Timestamp: created}, created); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if err := js.CloseSession("a"); err != nil {
+		t.Fatalf("close session: %v", err)
+	}
+
+	// a new session with the same id created by this process starts a new journal
+	js.Create("a")
+	reused := created.Add(time.Hour)
+	if err := js.Append(ThoughtRecord{Thought: "new", ThoughtNumber: 1, TotalThoughts: 1, SessionID: "a", Timestamp: reused}, reused); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	jn, err := loadJournal(js.journalPath("a"))
+	if err != nil {
+		t.Fatalf("load journal: %v", err)
+	}
+	if diff := cmp.Diff(reused, jn.header.CreatedAt); diff != "" {
+		t.Fatalf("created mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]ThoughtRecord{{Thought: "new", ThoughtNumber: 1, TotalThoughts: 1, SessionID: "a", Timestamp: reused}}, jn.records); diff != "" {
+		t.Fatalf("records mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"old"}, archivedThoughts(t, js, "a")); diff != "" {
+		t.Fatalf("archived thoughts mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestJournalStoreAppendClosed(t *testing.T) {
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	first := ThoughtRecord{Thought: "first", ThoughtNumber: 1, TotalThoughts: 2, SessionID: "a", Timestamp: created}
+	late := ThoughtRecord{Thought: "late", ThoughtNumber: 2, TotalThoughts: 2, SessionID: "a", Timestamp: created}
+
+	tests := map[string]struct {
+		close func(js *journalStore) error
+	}{
+		"error: session closed": {
+			close: func(js *journalStore) error { return js.CloseSession("a") },
+		},
+		"error: store closed": {
+			close: func(js *journalStore) error { return js.Close() },
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			js, err := openJournalStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("open journal store: %v", err)
+			}
+			t.Cleanup(func() { js.Close() })
+			js.Create("a")
+			if err := js.Append(first, created); err != nil {
+				t.Fatalf("append: %v", err)
+			}
+			if err := tt.close(js); err != nil {
+				t.Fatalf("close: %v", err)
+			}
+
+			// a call still in flight when its session closed must not reopen, and truncate, the journal
+			if err := js.Append(late, created); !errors.Is(err, errJournalClosed) {
+				t.Fatalf("append after close: got %v, want %v", err, errJournalClosed)
+			}
+			jn, err := loadJournal(js.journalPath("a"))
+			if err != nil {
+				t.Fatalf("load journal: %v", err)
+			}
+			if diff := cmp.Diff([]ThoughtRecord{first}, jn.records); diff != "" {
+				t.Fatalf("records mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSequentialThinkingServerRestore(t *testing.T) {
+	dir := t.TempDir()
+	inputs := []ThoughtData{
+		{Thought: "first", ThoughtNumber: 1, TotalThoughts: 3, NextThoughtNeeded: true},
+		{Thought: "fork", ThoughtNumber: 2, TotalThoughts: 3, NextThoughtNeeded: true, BranchFromThought: 1, BranchID: "alt"},
+		{Thought: "revise", ThoughtNumber: 3, TotalThoughts: 3, IsRevision: true, RevisesThought: 1},
+	}
+
+	js, err := openJournalStore(dir)
+	if err != nil {
+		t.Fatalf("open journal store: %v", err)
+	}
+	before := NewSequentialThinkingServer()
+	if err := before.restore(js); err != nil {
+		t.Fatalf("restore empty store: %v", err)
+	}
+	for _, input := range inputs {
+		result, _, err := before.ProcessThought(t.Context(), nil, input)
+		if err != nil {
+			t.Fatalf("process thought: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("tool error: %s", resultText(t, result))
+		}
+	}
+	if err := js.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	js, err = openJournalStore(dir)
+	if err != nil {
+		t.Fatalf("reopen journal store: %v", err)
+	}
+	t.Cleanup(func() { js.Close() })
+	after := NewSequentialThinkingServer()
+	if err := after.restore(js); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+
+	if diff := cmp.Diff(before.History(defaultSessionID), after.History(defaultSessionID)); diff != "" {
+		t.Fatalf("history mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(before.Branches(defaultSessionID), after.Branches(defaultSessionID)); diff != "" {
+		t.Fatalf("branches mismatch (-want +got):\n%s", diff)
+	}
+
+	// the restored session keeps accepting thoughts that reference replayed history
+	result, _, err := after.ProcessThought(t.Context(), nil, ThoughtData{
+		Thought:       "continue",
+		ThoughtNumber: 3,
+		TotalThoughts: 3,
+		BranchID:      "alt",
+	})
+	if err != nil {
+		t.Fatalf("process thought: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("tool error: %s", resultText(t, result))
+	}
+	if diff := cmp.Diff(len(inputs)+1, len(after.History(defaultSessionID))); diff != "" {
+		t.Fatalf("history length mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSequentialThinkingServerRestoreLimits(t *testing.T) {
+	now := time.Now()
+
+	tests := map[string]struct {
+		limits       sessionLimits
+		wantSessions []string
+	}{
+		"success: no limits": {
+			wantSessions: []string{"a", "b", "c"},
+		},
+		"success: keeps most recent sessions": {
+			limits:       sessionLimits{maxSessions: 2},
+			wantSessions: []string{"b", "c"},
+		},
+		"success: evicts idle sessions": {
+			limits:       sessionLimits{idleTTL: 90 * time.Second},
+			wantSessions: []string{"c"},
+		},
+		"success: evicts sessions over the byte limit": {
+			limits:       sessionLimits{maxSessionBytes: 5},
+			wantSessions: []string{"a", "c"},
+		},
+		"success: evicts sessions over the thought limit": {
+			limits:       sessionLimits{maxThoughts: 1},
+			wantSessions: []string{"a", "c"},
+		},
+		"success: limits combine": {
+			limits:       sessionLimits{maxSessions: 1, maxSessionBytes: 5},
+			wantSessions: []string{"c"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			js, err := openJournalStore(dir)
+			if err != nil {
+				t.Fatalf("open journal store: %v", err)
+			}
+			// a, b and c were last used 3, 2 and 1 minutes before now, and b holds two thoughts of 4 bytes
+			for i, id := range []string{"a", "b", "c"} {
+				at := now.Add(time.Duration(i-3) * time.Minute)
+				thoughts := []string{"one"}
+				if id == "b" {
+					thoughts = []string{"one.", "two."}
+				}
+				for n, thought := range thoughts {
+					record := ThoughtRecord{Thought: thought, ThoughtNumber: n + 1, TotalThoughts: len(thoughts), SessionID: id, Timestamp: at}
+					if err := js.Append(record, at); err != nil {
+						t.Fatalf("append: %v", err)
+					}
+				}
+			}
+			if err := js.Close(); err != nil {
+				t.Fatalf("close: %v", err)
+			}
+
+			server := NewSequentialThinkingServer()
+			server.limits = tt.limits
+			if err := server.restore(js); err != nil {
+				t.Fatalf("restore: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.wantSessions, server.SessionIDs()); diff != "" {
+				t.Fatalf("sessions mismatch (-want +got):\n%s", diff)
+			}
+			// the evicted sessions keep their journals
+			if diff := cmp.Diff([]string{"a", "b", "c"}, journalIDs(t, js)); diff != "" {
+				t.Fatalf("journals mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSequentialThinkingServerEvictRestart(t *testing.T) {
+	dir := t.TempDir()
+	js, err := openJournalStore(dir)
+	if err != nil {
+		t.Fatalf("open journal store: %v", err)
+	}
+	server := NewSequentialThinkingServer()
+	server.limits = sessionLimits{idleTTL: time.Minute}
+	if err := server.restore(js); err != nil {
+		t.Fatalf("restore empty store: %v", err)
+	}
+
+	process := func(server *SequentialThinkingServer, thought string) {
+		t.Helper()
+		result, _, err := server.ProcessThought(t.Context(), nil, ThoughtData{Thought: thought, ThoughtNumber: 1, TotalThoughts: 1})
+		if err != nil {
+			t.Fatalf("process thought: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("tool error: %s", resultText(t, result))
+		}
+	}
+
+	process(server, "evicted")
+	server.evictIdle(time.Now().Add(time.Hour))
+	if diff := cmp.Diff([]string{defaultSessionID}, journalIDs(t, js)); diff != "" {
+		t.Fatalf("journals after eviction mismatch (-want +got):\n%s", diff)
+	}
+
+	// the session id is reused by a new session, whose journal does not carry the evicted thought
+	process(server, "fresh")
+	if diff := cmp.Diff([]string{"evicted"}, archivedThoughts(t, js, defaultSessionID)); diff != "" {
+		t.Fatalf("archived thoughts mismatch (-want +got):\n%s", diff)
+	}
+	if err := js.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	js, err = openJournalStore(dir)
+	if err != nil {
+		t.Fatalf("reopen journal store: %v", err)
+	}
+	t.Cleanup(func() { js.Close() })
+	after := NewSequentialThinkingServer()
+	if err := after.restore(js); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	var got []string
+	for _, record := range after.History(defaultSessionID) {
+		got = append(got, record.Thought)
+	}
+	if diff := cmp.Diff([]string{"fresh"}, got); diff != "" {
+		t.Fatalf("restored thoughts mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// archivedThoughts returns the thoughts of the archived journals of the session id in js.
+func archivedThoughts(t *testing.T, js *journalStore, id string) []string {
+	t.Helper()
+
+	paths, err := filepath.Glob(js.journalPath(id) + ".*")
+	if err != nil {
+		t.Fatalf("glob archived journals: %v", err)
+	}
+	var thoughts []string
+	for _, path := range paths {
+		jn, err := loadJournal(path)
+		if err != nil {
+			t.Fatalf("load archived journal: %v", err)
+		}
+		for _, record := range jn.records {
+			thoughts = append(thoughts, record.Thought)
+		}
+	}
+	return thoughts
+}
+
+// journalIDs returns the sorted session ids of the journals in js.
+func journalIDs(t *testing.T, js *journalStore) []string {
+	t.Helper()
+
+	journals, err := js.Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	ids := make([]string, 0, len(journals))
+	for _, jn := range journals {
+		ids = append(ids, jn.header.SessionID)
+	}
+	return ids
+}
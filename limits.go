@@ -0,0 +1,242 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Session policies selected by -session-policy, applied when -max-sessions is reached.
+const (
+	// sessionPolicyLRU evicts the least recently used session to make room for a new one.
+	sessionPolicyLRU = "lru"
+	// sessionPolicyReject rejects new sessions.
+	sessionPolicyReject = "reject"
+)
+
+// Reasons a session is evicted, reported in the server log and the metrics.
+const (
+	evictionIdle = "idle"
+	evictionLRU  = "lru"
+	// evictionOversize is a session replayed from the journal over the per-session limits.
+	evictionOversize = "oversize"
+)
+
+// Bounds of the interval between two idle session sweeps.
+const (
+	minEvictionInterval = time.Second
+	maxEvictionInterval = time.Minute
+)
+
+// sessionLimits bounds the memory held by the thinking sessions. A zero limit is unlimited.
+type sessionLimits struct {
+	maxSessions int
+	// maxThoughts bounds the thoughts recorded by a session.
+	maxThoughts int
+	// maxThoughtBytes bounds the size of the text of a thought.
+	maxThoughtBytes int
+	// maxSessionBytes bounds the total size of the thought texts of a session.
+	maxSessionBytes int
+	// idleTTL evicts the sessions that recorded no thought for this long.
+	idleTTL time.Duration
+	// rejectSessions rejects new sessions over maxSessions instead of evicting the least recently used.
+	rejectSessions bool
+}
+
+// newSessionLimits returns the session limits configured by o.
+func newSessionLimits(o LimitsOptions) (sessionLimits, error) {
+	for _, v := range []struct {
+		flag  string
+		value int
+	}{
+		{"max-sessions", o.MaxSessions},
+		{"max-thoughts-per-session", o.MaxThoughtsPerSession},
+		{"max-thought-bytes", o.MaxThoughtBytes},
+		{"max-session-bytes", o.MaxSessionBytes},
+	} {
+		if v.value < 0 {
+			return sessionLimits{}, fmt.Errorf("-%s: must be >= 0, got %d", v.flag, v.value)
+		}
+	}
+	if o.IdleTTL < 0 {
+		return sessionLimits{}, fmt.Errorf("-session-idle-ttl: must be >= 0, got %s", o.IdleTTL)
+	}
+
+	l := sessionLimits{
+		maxSessions:     o.MaxSessions,
+		maxThoughts:     o.MaxThoughtsPerSession,
+		maxThoughtBytes: o.MaxThoughtBytes,
+		maxSessionBytes: o.MaxSessionBytes,
+		idleTTL:         time.Duration(o.IdleTTL),
+	}
+	switch o.SessionPolicy {
+	case sessionPolicyLRU:
+	case sessionPolicyReject:
+		l.rejectSessions = true
+	default:
+		return sessionLimits{}, fmt.Errorf("-session-policy: must be %s or %s, got %q", sessionPolicyLRU, sessionPolicyReject, o.SessionPolicy)
+	}
+	return l, nil
+}
+
+// checkThoughtBytes returns the tool error of a thought text of n bytes over the limit, or nil.
+func (l sessionLimits) checkThoughtBytes(n int) *ToolError {
+	if l.maxThoughtBytes > 0 && n > l.maxThoughtBytes {
+		return newToolError(reasonThoughtTooLarge, "thought is %d bytes, over the limit of %d bytes per thought (limits.maxThoughtBytes): split it into several thoughts", n, l.maxThoughtBytes)
+	}
+	return nil
+}
+
+// checkSession returns the tool error of the session limit that recording a thought of n bytes in ts
+// would exceed, or nil.
+//
+// The caller must hold ts.mu.
+func (l sessionLimits) checkSession(ts *thinkingSession, n int) *ToolError {
+	if l.maxThoughts > 0 && len(ts.history) >= l.maxThoughts {
+		return newToolError(reasonSessionThoughtLimit, "session %s reached the limit of %d thoughts per session (limits.maxThoughtsPerSession): conclude, or continue in a new session", ts.id, l.maxThoughts)
+	}
+	if l.maxSessionBytes > 0 && ts.bytes+n > l.maxSessionBytes {
+		return newToolError(reasonSessionBytesLimit, "session %s holds %d bytes of thoughts, recording %d more exceeds the limit of %d bytes per session (limits.maxSessionBytes): conclude, or continue in a new session", ts.id, ts.bytes, n, l.maxSessionBytes)
+	}
+	return nil
+}
+
+// restorable splits the sessions replayed from the journal into those the limits keep and those
+// they evict, by reason: the sessions idle for the TTL before now, the sessions over the thought or
+// byte limit of a session, and past the session limit the least recently used.
+func (l sessionLimits) restorable(sessions []*thinkingSession, now time.Time) (kept []*thinkingSession, evicted map[string][]*thinkingSession) {
+	evicted = make(map[string][]*thinkingSession)
+	deadline := now.Add(-l.idleTTL).UnixNano()
+	for _, ts := range sessions {
+		switch {
+		case l.idleTTL > 0 && ts.lastUsed.Load() < deadline:
+			evicted[evictionIdle] = append(evicted[evictionIdle], ts)
+		case l.maxThoughts > 0 && len(ts.history) > l.maxThoughts,
+			l.maxSessionBytes > 0 && ts.bytes > l.maxSessionBytes:
+			evicted[evictionOversize] = append(evicted[evictionOversize], ts)
+		default:
+			kept = append(kept, ts)
+		}
+	}
+
+	if l.maxSessions > 0 && len(kept) > l.maxSessions {
+		slices.SortStableFunc(kept, func(a, b *thinkingSession) int {
+			return cmp.Compare(b.lastUsed.Load(), a.lastUsed.Load())
+		})
+		evicted[evictionLRU] = kept[l.maxSessions:]
+		kept = kept[:l.maxSessions]
+	}
+	return kept, evicted
+}
+
+// evictionObserver is a [sessionObserver] also notified of the sessions evicted by the limits.
+type evictionObserver interface {
+	// sessionEvicted is called when the session id is evicted for reason, before it is closed.
+	sessionEvicted(id, reason string)
+}
+
+// admitSession makes room for a new session under the session limit, evicting the least recently
+// used sessions. The evicted sessions are removed from s.sessions, and must be passed to
+// [SequentialThinkingServer.evicted] once s.mu is released.
+//
+// The caller must hold s.mu.
+func (s *SequentialThinkingServer) admitSession() ([]*thinkingSession, *ToolError) {
+	limit := s.limits.maxSessions
+	if limit <= 0 || len(s.sessions) < limit {
+		return nil, nil
+	}
+	if s.limits.rejectSessions {
+		return nil, newToolError(reasonSessionLimit, "the server holds the limit of %d sessions (limits.maxSessions): retry once a session closes", limit)
+	}
+
+	var evicted []*thinkingSession
+	for len(s.sessions) >= limit {
+		var lru *thinkingSession
+		for _, ts := range s.sessions {
+			if lru == nil || ts.lastUsed.Load() < lru.lastUsed.Load() {
+				lru = ts
+			}
+		}
+		delete(s.sessions, lru.id)
+		evicted = append(evicted, lru)
+	}
+	return evicted, nil
+}
+
+// evictIdle evicts the sessions that recorded no thought since the idle TTL before now.
+func (s *SequentialThinkingServer) evictIdle(now time.Time) {
+	if s.limits.idleTTL <= 0 {
+		return
+	}
+	deadline := now.Add(-s.limits.idleTTL).UnixNano()
+
+	s.mu.Lock()
+	var evicted []*thinkingSession
+	for id, ts := range s.sessions {
+		if ts.lastUsed.Load() < deadline {
+			delete(s.sessions, id)
+			evicted = append(evicted, ts)
+		}
+	}
+	observers := s.observers
+	s.mu.Unlock()
+
+	s.evicted(evicted, evictionIdle, observers)
+}
+
+// runEviction evicts the idle sessions periodically until ctx is done.
+func (s *SequentialThinkingServer) runEviction(ctx context.Context) {
+	if s.limits.idleTTL <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(min(max(s.limits.idleTTL/2, minEvictionInterval), maxEvictionInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.evictIdle(now)
+		}
+	}
+}
+
+// evicted logs the sessions evicted for reason, already removed from s.sessions, and notifies observers.
+//
+// The journals of the evicted sessions stay in the store.
+func (s *SequentialThinkingServer) evicted(sessions []*thinkingSession, reason string, observers []sessionObserver) {
+	for _, ts := range sessions {
+		ts.mu.Lock()
+		thoughts, bytes := len(ts.history), ts.bytes
+		ts.mu.Unlock()
+		slog.Info("evicted thinking session", slog.String(logKeySessionID, ts.id), slog.String("reason", reason),
+			slog.Int("thoughts", thoughts), slog.Int("bytes", bytes), slog.Time("last_used", time.Unix(0, ts.lastUsed.Load())))
+
+		for _, o := range observers {
+			if eo, ok := o.(evictionObserver); ok {
+				eo.sessionEvicted(ts.id, reason)
+			}
+		}
+		s.sessionRemoved(ts.id, observers)
+	}
+}
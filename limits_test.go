@@ -0,0 +1,258 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewSessionLimits(t *testing.T) {
+	tests := map[string]struct {
+		opts    func(o *LimitsOptions)
+		want    sessionLimits
+		wantErr string
+	}{
+		"success: defaults": {
+			opts: func(*LimitsOptions) {},
+		},
+		"success: reject policy": {
+			opts: func(o *LimitsOptions) {
+				o.MaxSessions = 4
+				o.IdleTTL = duration(time.Minute)
+				o.SessionPolicy = sessionPolicyReject
+			},
+			want: sessionLimits{maxSessions: 4, idleTTL: time.Minute, rejectSessions: true},
+		},
+		"error: negative limit": {
+			opts:    func(o *LimitsOptions) { o.MaxThoughtBytes = -1 },
+			wantErr: "-max-thought-bytes: must be >= 0, got -1",
+		},
+		"error: negative idle ttl": {
+			opts:    func(o *LimitsOptions) { o.IdleTTL = duration(-time.Second) },
+			wantErr: "-session-idle-ttl: must be >= 0, got -1s",
+		},
+		"error: unknown policy": {
+			opts:    func(o *LimitsOptions) { o.SessionPolicy = "fifo" },
+			wantErr: `-session-policy: must be lru or reject, got "fifo"`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			o := defaultConfig().Limits
+			tt.opts(&o)
+
+			got, err := newSessionLimits(o)
+			if diff := cmp.Diff(tt.wantErr != "", err != nil); diff != "" {
+				t.Fatalf("error presence mismatch %v (-want +got):\n%s", err, diff)
+			}
+			if err != nil {
+				if diff := cmp.Diff(tt.wantErr, err.Error()); diff != "" {
+					t.Fatalf("error text mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(sessionLimits{})); diff != "" {
+				t.Fatalf("limits mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSequentialThinkingServerProcessThoughtLimits(t *testing.T) {
+	recorded := []ThoughtData{
+		{Thought: "first", ThoughtNumber: 1, TotalThoughts: 3, NextThoughtNeeded: true},
+		{Thought: "second", ThoughtNumber: 2, TotalThoughts: 3, NextThoughtNeeded: true},
+	}
+	next := ThoughtData{Thought: "third", ThoughtNumber: 3, TotalThoughts: 3}
+
+	tests := map[string]struct {
+		limits sessionLimits
+		input  ThoughtData
+		want   ToolError
+	}{
+		"error: thought too large": {
+			limits: sessionLimits{maxThoughtBytes: 8},
+			input:  ThoughtData{Thought: "a long third thought", ThoughtNumber: 3, TotalThoughts: 3},
+			want: ToolError{
+				Message: "thought is 20 bytes, over the limit of 8 bytes per thought (limits.maxThoughtBytes): split it into several thoughts",
+				Reason:  reasonThoughtTooLarge,
+			},
+		},
+		"error: thoughts per session": {
+			limits: sessionLimits{maxThoughts: 2},
+			input:  next,
+			want: ToolError{
+				Message: "session default reached the limit of 2 thoughts per session (limits.maxThoughtsPerSession): conclude, or continue in a new session",
+				Reason:  reasonSessionThoughtLimit,
+			},
+		},
+		"error: bytes per session": {
+			limits: sessionLimits{maxSessionBytes: 15},
+			input:  next,
+			want: ToolError{
+				Message: "session default holds 11 bytes of thoughts, recording 5 more exceeds the limit of 15 bytes per session (limits.maxSessionBytes): conclude, or continue in a new session",
+				Reason:  reasonSessionBytesLimit,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := NewSequentialThinkingServer()
+			server.limits = tt.limits
+			for _, input := range recorded {
+				result, _, err := server.ProcessThought(t.Context(), nil, input)
+				if err != nil {
+					t.Fatalf("process thought: %v", err)
+				}
+				if result.IsError {
+					t.Fatalf("tool error: %s", resultText(t, result))
+				}
+			}
+
+			result, _, err := server.ProcessThought(t.Context(), nil, tt.input)
+			if err != nil {
+				t.Fatalf("process thought: %v", err)
+			}
+			if diff := cmp.Diff(true, result.IsError); diff != "" {
+				t.Fatalf("result IsError mismatch (-want +got):\n%s", diff)
+			}
+			dec := jsontext.NewDecoder(strings.NewReader(resultText(t, result)))
+			var got ToolError
+			if err := json.UnmarshalDecode(dec, &got); err != nil {
+				t.Fatalf("decode tool error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("tool error mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(len(recorded), len(server.History(defaultSessionID))); diff != "" {
+				t.Fatalf("history length mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// seedSessions adds a session for each id to s, each used a minute after the previous one from start.
+func seedSessions(s *SequentialThinkingServer, start time.Time, ids ...string) {
+	for i, id := range ids {
+		s.sessions[id] = newThinkingSession(id, start.Add(time.Duration(i)*time.Minute))
+	}
+}
+
+func TestSequentialThinkingServerSessionLimit(t *testing.T) {
+	tests := map[string]struct {
+		limits        sessionLimits
+		wantReason    string
+		wantSessions  []string
+		wantEvictions map[string]uint64
+	}{
+		"success: under the limit": {
+			limits:        sessionLimits{maxSessions: 4},
+			wantSessions:  []string{"a", "b", "c", defaultSessionID},
+			wantEvictions: map[string]uint64{},
+		},
+		"success: evicts least recently used": {
+			limits:        sessionLimits{maxSessions: 2},
+			wantSessions:  []string{"c", defaultSessionID},
+			wantEvictions: map[string]uint64{evictionLRU: 2},
+		},
+		"error: rejects new session": {
+			limits:        sessionLimits{maxSessions: 3, rejectSessions: true},
+			wantReason:    reasonSessionLimit,
+			wantSessions:  []string{"a", "b", "c"},
+			wantEvictions: map[string]uint64{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := NewSequentialThinkingServer()
+			server.limits = tt.limits
+			m := newMetrics(server)
+			seedSessions(server, time.Now().Add(-time.Hour), "b", "a", "c")
+			server.sessions["a"].lastUsed.Store(time.Now().Add(-2 * time.Hour).UnixNano())
+
+			_, terr := server.session(nil)
+			var reason string
+			if terr != nil {
+				reason = terr.Reason
+			}
+			if diff := cmp.Diff(tt.wantReason, reason); diff != "" {
+				t.Fatalf("reason mismatch (-want +got):\n%s", diff)
+			}
+			got := server.SessionIDs()
+			if diff := cmp.Diff(tt.wantSessions, got); diff != "" {
+				t.Fatalf("sessions mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantEvictions, m.evictions); diff != "" {
+				t.Fatalf("evictions mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSequentialThinkingServerEvictIdle(t *testing.T) {
+	now := time.Now()
+
+	tests := map[string]struct {
+		idleTTL       time.Duration
+		wantSessions  []string
+		wantEvictions map[string]uint64
+	}{
+		"success: disabled": {
+			wantSessions:  []string{"a", "b", "c"},
+			wantEvictions: map[string]uint64{},
+		},
+		"success: evicts idle sessions": {
+			idleTTL:       90 * time.Second,
+			wantSessions:  []string{"c"},
+			wantEvictions: map[string]uint64{evictionIdle: 2},
+		},
+		"success: evicts all": {
+			idleTTL:       time.Second,
+			wantSessions:  []string{},
+			wantEvictions: map[string]uint64{evictionIdle: 3},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := NewSequentialThinkingServer()
+			server.limits = sessionLimits{idleTTL: tt.idleTTL}
+			m := newMetrics(server)
+			// a, b and c were last used 3, 2 and 1 minutes before now
+			seedSessions(server, now.Add(-3*time.Minute), "a", "b", "c")
+
+			server.evictIdle(now)
+
+			got := server.SessionIDs()
+			if diff := cmp.Diff(tt.wantSessions, got); diff != "" {
+				t.Fatalf("sessions mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantEvictions, m.evictions); diff != "" {
+				t.Fatalf("evictions mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// Attribute keys of the thought events. They are part of the log schema and must not change.
+const (
+	logKeySessionID         = "session_id"
+	logKeyPrincipal         = "principal"
+	logKeyThoughtNumber     = "thought_number"
+	logKeyTotalThoughts     = "total_thoughts"
+	logKeyNextThoughtNeeded = "next_thought_needed"
+	logKeyRevisesThought    = "revises_thought"
+	logKeyBranchID          = "branch_id"
+	logKeyBranchFromThought = "branch_from_thought"
+	logKeyThoughtLength     = "thought_length"
+	logKeyThought           = "thought"
+)
+
+// Log formats selected by -log-format.
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// defaultLogLevel is the level of the server log unless -log-level or -log-thoughts is set.
+const defaultLogLevel = "warn"
+
+// logLevel returns the level of the server log set by o. Without -log-level it is [defaultLogLevel],
+// or info with logThoughts so that the thought events show.
+func logLevel(o LoggingOptions, logThoughts bool) string {
+	switch {
+	case o.Level != "":
+		return o.Level
+	case logThoughts:
+		return "info"
+	default:
+		return defaultLogLevel
+	}
+}
+
+// newLogHandler returns a handler writing the records of level and above to w in format.
+func newLogHandler(w io.Writer, format, level string) (slog.Handler, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("-log-level: %w", err)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	switch format {
+	case logFormatText:
+		return slog.NewTextHandler(w, opts), nil
+	case logFormatJSON:
+		return slog.NewJSONHandler(w, opts), nil
+	default:
+		return nil, fmt.Errorf("-log-format: must be %s or %s, got %q", logFormatText, logFormatJSON, format)
+	}
+}
+
+// thoughtLogger is a [sessionObserver] that logs an event for every recorded thought.
+//
+// Thoughts are logged at the info level, the opening and closing of sessions at the debug level.
+type thoughtLogger struct {
+	logger *slog.Logger
+	// withText adds the thought text to the events, which otherwise only carry its length.
+	withText bool
+}
+
+var _ sessionObserver = (*thoughtLogger)(nil)
+
+// sessionOpened implements [sessionObserver].
+func (l *thoughtLogger) sessionOpened(id string) {
+	l.logger.LogAttrs(context.Background(), slog.LevelDebug, "session opened", slog.String(logKeySessionID, id))
+}
+
+// sessionClosed implements [sessionObserver].
+func (l *thoughtLogger) sessionClosed(id string) {
+	l.logger.LogAttrs(context.Background(), slog.LevelDebug, "session closed", slog.String(logKeySessionID, id))
+}
+
+// thoughtAppended implements [sessionObserver].
+//
+// Optional attributes are omitted when unset.
+func (l *thoughtLogger) thoughtAppended(record ThoughtRecord, _ bool) {
+	attrs := []slog.Attr{
+		slog.String(logKeySessionID, record.SessionID),
+		slog.Int(logKeyThoughtNumber, record.ThoughtNumber),
+		slog.Int(logKeyTotalThoughts, record.TotalThoughts),
+		slog.Bool(logKeyNextThoughtNeeded, record.NextThoughtNeeded),
+	}
+	if record.Principal != "" {
+		attrs = append(attrs, slog.String(logKeyPrincipal, record.Principal))
+	}
+	if record.IsRevision {
+		attrs = append(attrs, slog.Int(logKeyRevisesThought, record.RevisesThought))
+	}
+	if record.BranchID != "" {
+		attrs = append(attrs, slog.String(logKeyBranchID, record.BranchID))
+	}
+	if record.BranchFromThought > 0 {
+		attrs = append(attrs, slog.Int(logKeyBranchFromThought, record.BranchFromThought))
+	}
+	attrs = append(attrs, slog.Int(logKeyThoughtLength, len(record.Thought)))
+	if l.withText {
+		attrs = append(attrs, slog.String(logKeyThought, record.Thought))
+	}
+	l.logger.LogAttrs(context.Background(), slog.LevelInfo, "thought recorded", attrs...)
+}
@@ -0,0 +1,183 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-json-experiment/json"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewLogHandler(t *testing.T) {
+	tests := map[string]struct {
+		format  string
+		level   string
+		want    string
+		wantErr bool
+	}{
+		"success: text": {
+			format: logFormatText,
+			level:  "info",
+			want:   "level=INFO msg=shown\n",
+		},
+		"success: json": {
+			format: logFormatJSON,
+			level:  "INFO",
+			want:   `{"level":"INFO","msg":"shown"}` + "\n",
+		},
+		"success: warn hides info": {
+			format: logFormatText,
+			level:  "warn",
+		},
+		"error: unknown format": {
+			format:  "logfmt",
+			level:   "info",
+			wantErr: true,
+		},
+		"error: unknown level": {
+			format:  logFormatText,
+			level:   "verbose",
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			h, err := newLogHandler(&b, tt.format, tt.level)
+			if diff := cmp.Diff(tt.wantErr, err != nil); diff != "" {
+				t.Fatalf("error presence mismatch %v (-want +got):\n%s", err, diff)
+			}
+			if err != nil {
+				return
+			}
+			logger := slog.New(withoutTime(h))
+			logger.Debug("hidden")
+			logger.Info("shown")
+			if diff := cmp.Diff(tt.want, b.String()); diff != "" {
+				t.Fatalf("log output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	tests := map[string]struct {
+		level       string
+		logThoughts bool
+		want        string
+	}{
+		"success: default": {
+			want: defaultLogLevel,
+		},
+		"success: default with thoughts": {
+			logThoughts: true,
+			want:        "info",
+		},
+		"success: explicit level": {
+			level:       "error",
+			logThoughts: true,
+			want:        "error",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := logLevel(LoggingOptions{Level: tt.level}, tt.logThoughts)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("level mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// withoutTime wraps h to drop the time of the records, making the output reproducible.
+func withoutTime(h slog.Handler) slog.Handler {
+	return timelessHandler{h}
+}
+
+type timelessHandler struct{ slog.Handler }
+
+func (h timelessHandler) Handle(ctx context.Context, r slog.Record) error {
+	r.Time = time.Time{}
+	return h.Handler.Handle(ctx, r)
+}
+
+func TestThoughtLogger(t *testing.T) {
+	tests := map[string]struct {
+		withText bool
+		want     []map[string]any
+	}{
+		"success: without text": {
+			want: []map[string]any{
+				{"level": "DEBUG", "msg": "session opened", "session_id": "default"},
+				{"level": "INFO", "msg": "thought recorded", "session_id": "default", "thought_number": 1.0, "total_thoughts": 3.0, "next_thought_needed": true, "thought_length": 5.0},
+				{"level": "INFO", "msg": "thought recorded", "session_id": "default", "thought_number": 2.0, "total_thoughts": 3.0, "next_thought_needed": true, "branch_id": "alt", "branch_from_thought": 1.0, "thought_length": 6.0},
+				{"level": "INFO", "msg": "thought recorded", "session_id": "default", "thought_number": 3.0, "total_thoughts": 3.0, "next_thought_needed": false, "revises_thought": 1.0, "thought_length": 6.0},
+			},
+		},
+		"success: with text": {
+			withText: true,
+			want: []map[string]any{
+				{"level": "DEBUG", "msg": "session opened", "session_id": "default"},
+				{"level": "INFO", "msg": "thought recorded", "session_id": "default", "thought_number": 1.0, "total_thoughts": 3.0, "next_thought_needed": true, "thought_length": 5.0, "thought": "first"},
+				{"level": "INFO", "msg": "thought recorded", "session_id": "default", "thought_number": 2.0, "total_thoughts": 3.0, "next_thought_needed": true, "branch_id": "alt", "branch_from_thought": 1.0, "thought_length": 6.0, "thought": "branch"},
+				{"level": "INFO", "msg": "thought recorded", "session_id": "default", "thought_number": 3.0, "total_thoughts": 3.0, "next_thought_needed": false, "revises_thought": 1.0, "thought_length": 6.0, "thought": "revise"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			h, err := newLogHandler(&b, logFormatJSON, "debug")
+			if err != nil {
+				t.Fatalf("new log handler: %v", err)
+			}
+			thinking := NewSequentialThinkingServer()
+			thinking.observe(&thoughtLogger{logger: slog.New(withoutTime(h)), withText: tt.withText})
+
+			for _, input := range []ThoughtData{
+				{Thought: "first", ThoughtNumber: 1, TotalThoughts: 3, NextThoughtNeeded: true},
+				{Thought: "branch", ThoughtNumber: 2, TotalThoughts: 3, NextThoughtNeeded: true, BranchFromThought: 1, BranchID: "alt"},
+				{Thought: "revise", ThoughtNumber: 3, TotalThoughts: 3, IsRevision: true, RevisesThought: 1},
+			} {
+				if _, _, err := thinking.ProcessThought(t.Context(), nil, input); err != nil {
+					t.Fatalf("process thought: %v", err)
+				}
+			}
+
+			var got []map[string]any
+			for line := range strings.Lines(b.String()) {
+				var event map[string]any
+				if err := json.Unmarshal([]byte(line), &event); err != nil {
+					t.Fatalf("decode event %q: %v", line, err)
+				}
+				got = append(got, event)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("events mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -28,8 +29,8 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/google/uuid"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/zchee/dumper"
@@ -37,71 +38,18 @@ import (
 
 var Version = "0.0.1"
 
-const description = `A detailed tool for dynamic and reflective problem-solving through thoughts.
-This tool helps analyze problems through a flexible thinking process that can adapt and evolve.
-Each thought can build on, question, or revise previous insights as understanding deepens.
-
-When to use this tool:
-- Breaking down complex problems into steps
-- Planning and design with room for revision
-- Analysis that might need course correction
-- Problems where the full scope might not be clear initially
-- Problems that require a multi-step solution
-- Tasks that need to maintain context over multiple steps
-- Situations where irrelevant information needs to be filtered out
-
-Key features:
-- You can adjust total_thoughts up or down as you progress
-- You can question or revise previous thoughts
-- You can add more thoughts even after reaching what seemed like the end
-- You can express uncertainty and explore alternative approaches
-- Not every thought needs to build linearly - you can branch or backtrack
-- Generates a solution hypothesis
-- Verifies the hypothesis based on the Chain of Thought steps
-- Repeats the process until satisfied
-- Provides a correct answer
-
-Parameters explained:
-- thought (string): Required. Your current thinking step, which can include:
-  * Regular analytical steps
-  * Revisions of previous thoughts
-  * Questions about previous decisions
-  * Realizations about needing more analysis
-  * Changes in approach
-  * Hypothesis generation
-  * Hypothesis verification
-- nextThoughtNeeded (boolean): Required. True if you need more thinking, even if at what seemed like the end
-- thoughtNumber (integer): Required. Current number in sequence (can go beyond initial total if needed)
-- totalThoughts (integer): Required. Current estimate of thoughts needed (can be adjusted up/down)
-- isRevision (boolean): Optional. A boolean indicating if this thought revises previous thinking
-- revisesThought (integer): Optional. If is_revision is true, which thought number is being reconsidered
-- branchFromThought (integer): Optional. If branching, which thought number is the branching point
-- branchId (string): Optional. Identifier for the current branch (if any)
-- needsMoreThoughts (boolean): Optional. If reaching end but realizing more thoughts needed
-
-You should:
-1. Start with an initial estimate of needed thoughts, but be ready to adjust
-2. Feel free to question or revise previous thoughts
-3. Don't hesitate to add more thoughts if needed, even at the "end"
-4. Express uncertainty when present
-5. Mark thoughts that revise previous thinking or branch into new paths
-6. Ignore information that is irrelevant to the current step
-7. Generate a solution hypothesis when appropriate
-8. Verify the hypothesis based on the Chain of Thought steps
-9. Repeat the process until satisfied with the solution
-10. Provide a single, ideally correct answer as the final output
-11. Only set nextThoughtNeeded to false when truly done and a satisfactory answer is reached`
-
 var (
-	flagHTTPAddr string
-	flagLogPath  string
+	flagConfigPath  string
+	flagPrintConfig bool
+	flagSettings    = flagOverrides{}
 )
 
 func init() {
 	uuid.EnableRandPool()
 
-	flag.StringVar(&flagHTTPAddr, "http", "", "if set, use streamable HTTP at this address, instead of stdin/stdout")
-	flag.StringVar(&flagLogPath, "logpath", "", "if set, enable sequential thinking tool logging")
+	flag.StringVar(&flagConfigPath, "config", "", "if set, load the configuration from this TOML, YAML or JSON file (default $"+configEnv+")")
+	flag.BoolVar(&flagPrintConfig, "print-config", false, "print the effective configuration as JSON and exit")
+	flagSettings.register(flag.CommandLine)
 }
 
 func main() {
@@ -116,113 +64,336 @@ func main() {
 		OmitZero:     true,
 	}
 
-	if err := run(); err != nil {
+	if flag.Arg(0) == "export" {
+		if err := runExport(flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := loadConfig(flagConfigPath, os.LookupEnv, flagSettings)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	for _, msg := range cfg.deprecated {
+		fmt.Fprintln(os.Stderr, "warning:", msg)
+	}
+	if flagPrintConfig {
+		if err := cfg.write(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := run(cfg); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
-func run() error {
+func run(cfg *Config) error {
 	var f io.WriteCloser
 
-	handler := slog.DiscardHandler
-	if flagLogPath != "" {
+	var w io.Writer = os.Stderr
+	if cfg.Logging.Path != "" {
 		var err error
-		f, err = os.OpenFile(flagLogPath, os.O_RDWR|os.O_CREATE, 0o666)
+		f, err = os.OpenFile(cfg.Logging.Path, os.O_RDWR|os.O_CREATE, 0o666)
 		if err != nil {
-			return fmt.Errorf("open %q file: %w", flagLogPath, err)
+			return fmt.Errorf("open %q file: %w", cfg.Logging.Path, err)
 		}
 		defer f.Close()
-
-		handler = slog.NewTextHandler(f, &slog.HandlerOptions{
-			Level: slog.LevelDebug,
-		})
+		w = f
+	}
+	handler, err := newLogHandler(w, cfg.Logging.Format, logLevel(cfg.Logging, cfg.Tool.LogThoughts))
+	if err != nil {
+		return err
+	}
+	if cfg.Tool.LogThoughts && !handler.Enabled(context.Background(), slog.LevelInfo) {
+		return fmt.Errorf("-log-thoughts: thoughts are logged at the info level, which -log-level %s hides", cfg.Logging.Level)
 	}
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
 
-	srvImpl := &mcp.Implementation{
-		Name:       "sequential-thinking",
-		Version:    Version,
-		WebsiteURL: "https://github.com/zchee/mcp-sequential-thinking",
+	traces, err := newTracer(logger, cfg.Telemetry.OTLPEndpoint, cfg.Telemetry.TraceFile)
+	if err != nil {
+		return err
 	}
-	opts := &mcp.ServerOptions{
-		// TODO(zchee): The [mcp.ServerOptions.Instructions] are usually enough tool description, but set a global prompt such as "Think step by step"
-		// Instructions: `Based on the previous thinking, analyze the step-by-step and try to think more about the critical points.`,
-		Logger:   logger,
-		HasTools: true,
-		GetSessionID: func() string {
-			// Use UUID7 instead of [mcp.randText]
-			return uuid.Must(uuid.NewV7()).String()
-		},
+	if traces != nil {
+		defer traces.shutdown(context.Background())
+	}
+
+	mode, err := transportMode(cfg.Transport.Mode, cfg.Transport.HTTP, cfg.Transport.Unix)
+	if err != nil {
+		return err
+	}
+	unixMode, err := parseFileMode(string(cfg.Transport.UnixMode))
+	if err != nil {
+		return fmt.Errorf("-unix-mode: %w", err)
+	}
+	if cfg.Telemetry.Metrics && mode == transportStdio {
+		return errors.New("-metrics requires an -http address or a -unix socket")
+	}
+	ac, err := loadAuthConfig(cfg.Auth.Tokens, cfg.Auth.TLSCert, cfg.Auth.TLSKey, cfg.Auth.TLSClientCA)
+	if err != nil {
+		return err
 	}
-	srv := mcp.NewServer(srvImpl, opts)
 
-	inputSchema, err := jsonschema.For[ThoughtData](&jsonschema.ForOptions{})
+	if cfg.Logging.ConsoleWidth < 0 {
+		return fmt.Errorf("-console-width: must be >= 0, got %d", cfg.Logging.ConsoleWidth)
+	}
+	// the console only has stderr to itself when the server log goes to a file
+	style := newConsoleStyle(os.Stderr, cfg.Logging.ConsoleWidth, os.Getenv("NO_COLOR") != "", cfg.Logging.Path != "")
+	console, err := newConsole(cfg.Logging.Console, os.Stderr, style)
 	if err != nil {
-		return fmt.Errorf("parse ThoughtData: %w", err)
+		return err
 	}
-	inputSchema.Properties["thoughtNumber"].Minimum = new(float64(1))
-	inputSchema.Properties["totalThoughts"].Minimum = new(float64(1))
-	inputSchema.Properties["revisesThought"].Minimum = new(float64(1))
-	inputSchema.Properties["branchFromThought"].Minimum = new(float64(1))
 
-	outputSchema, err := jsonschema.For[Output](&jsonschema.ForOptions{})
+	limits, err := newSessionLimits(cfg.Limits)
 	if err != nil {
-		return fmt.Errorf("parse Output: %w", err)
+		return err
 	}
 
-	sequentialThinkingTool := &mcp.Tool{
-		Name: "sequentialthinking",
-		Annotations: &mcp.ToolAnnotations{
-			ReadOnlyHint:    true,
-			DestructiveHint: new(false),
-			IdempotentHint:  true,
-			OpenWorldHint:   new(true),
-		},
-		Description:  description,
-		InputSchema:  inputSchema,
-		OutputSchema: outputSchema,
+	rateLimits, err := newRateLimiter(cfg.RateLimit)
+	if err != nil {
+		return err
 	}
-	sequentialThinkServer := NewSequentialThinkingServer()
 
-	mcp.AddTool(srv, sequentialThinkingTool, sequentialThinkServer.ProcessThought)
+	if err := validateNumbering(cfg.Tool.Numbering); err != nil {
+		return err
+	}
+
+	thinking := NewSequentialThinkingServer()
+	thinking.limits = limits
+	thinking.rateLimits = rateLimits
+	thinking.numbering = cfg.Tool.Numbering
+	if cfg.Tool.LogThoughts {
+		thinking.observe(&thoughtLogger{logger: logger, withText: cfg.Tool.LogThoughtText})
+	}
+	if console != nil {
+		thinking.observe(console)
+	}
+	var js *journalStore
+	if cfg.Storage.Path != "" {
+		js, err = openJournalStore(cfg.Storage.Path)
+		if err != nil {
+			return err
+		}
+		defer js.Close()
+
+		if err := thinking.restore(js); err != nil {
+			return fmt.Errorf("restore sessions: %w", err)
+		}
+		logger.Info("restored thinking sessions", slog.String("store", cfg.Storage.Path), slog.Int("sessions", len(thinking.SessionIDs())))
+	}
+
+	// the SDK logs every message and notification at the info level, only wanted when debugging
+	sdkLogger := slog.New(slog.DiscardHandler)
+	if handler.Enabled(context.Background(), slog.LevelDebug) {
+		sdkLogger = logger
+	}
+	instructions, err := loadInstructions(cfg.Prompts)
+	if err != nil {
+		return err
+	}
+	var prompts *promptLibrary
+	if cfg.Prompts.Dir != "" {
+		prompts, err = loadPromptLibrary(cfg.Prompts.Dir)
+		if err != nil {
+			return err
+		}
+	}
+	tool, err := newToolSpec(cfg.Tool)
+	if err != nil {
+		return err
+	}
+	if traces != nil {
+		traces.thinkingTool = tool.name
+	}
+	srv, err := newServer(sdkLogger, thinking, serverOptions{instructions: instructions, prompts: prompts, tool: tool})
+	if err != nil {
+		return err
+	}
+	calls := &callTracker{}
+	srv.AddReceivingMiddleware(calls.middleware)
+	if traces != nil {
+		srv.AddReceivingMiddleware(traces.middleware)
+	}
+	var m *metrics
+	if cfg.Telemetry.Metrics {
+		m = newMetrics(thinking, tool.toolNames()...)
+		srv.AddReceivingMiddleware(m.middleware)
+	}
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	if flagHTTPAddr != "" {
+	// serveCtx outlives the signal, so in-flight tool calls can complete while the server drains.
+	serveCtx, stopServing := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopServing()
+	go thinking.runEviction(serveCtx)
+	if prompts != nil {
+		go prompts.watch(serveCtx, promptReloadInterval)
+	}
+
+	errc := make(chan error, 3)
+	var httpSrv *http.Server
+	if mode != transportStdio {
+		var listeners []net.Listener
+		if cfg.Transport.HTTP != "" {
+			ln, err := net.Listen("tcp", cfg.Transport.HTTP)
+			if err != nil {
+				logger.ErrorContext(ctx, "serve sequential thinking mcp http server", slog.Any("error", err))
+				return fmt.Errorf("serve sequential thinking mcp http server: %w", err)
+			}
+			listeners = append(listeners, ln)
+		}
+		if cfg.Transport.Unix != "" {
+			ln, err := listenUnix(cfg.Transport.Unix, unixMode)
+			if err != nil {
+				for _, ln := range listeners {
+					ln.Close()
+				}
+				logger.ErrorContext(ctx, "serve sequential thinking mcp http server", slog.Any("error", err))
+				return fmt.Errorf("serve sequential thinking mcp http server: %w", err)
+			}
+			listeners = append(listeners, ln)
+		}
+
 		mcpServer := func(*http.Request) *mcp.Server {
 			return srv
 		}
-		handler := mcp.NewStreamableHTTPHandler(mcpServer, nil)
-		httpSrv := &http.Server{
-			Addr:    flagHTTPAddr,
-			Handler: handler,
+		httpSrv = &http.Server{
+			Handler:   httpHandler(ac, mcp.NewStreamableHTTPHandler(mcpServer, nil), m, cfg.Telemetry.MetricsNoAuth),
+			TLSConfig: ac.tlsConfig(),
 			BaseContext: func(net.Listener) context.Context {
-				return ctx
+				return serveCtx
 			},
 		}
-		logger.InfoContext(ctx, "sequential thinking MCP server running", slog.String("addr", "http://"+flagHTTPAddr))
-		if err := httpSrv.ListenAndServe(); err != nil {
-			logger.ErrorContext(ctx, "serve sequential thinking mcp http server", slog.Any("error", err))
-			return fmt.Errorf("serve sequential thinking mcp http server: %w", err)
+		for _, ln := range listeners {
+			logger.InfoContext(ctx, "sequential thinking MCP server running", slog.String("addr", listenerURL(ln, ac.tls())),
+				slog.Bool("bearer_auth", ac.tokens != nil), slog.Bool("client_cert_auth", ac.clientCAs != nil))
+			go func() {
+				serve := httpSrv.Serve
+				if ac.tls() {
+					serve = func(ln net.Listener) error { return httpSrv.ServeTLS(ln, ac.certFile, ac.keyFile) }
+				}
+				if err := serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					logger.ErrorContext(ctx, "serve sequential thinking mcp http server", slog.Any("error", err))
+					errc <- fmt.Errorf("serve sequential thinking mcp http server: %w", err)
+					return
+				}
+				errc <- nil
+			}()
 		}
 	}
 
-	tr := mcp.Transport(&mcp.StdioTransport{})
-	if flagLogPath != "" {
-		tr = &mcp.LoggingTransport{
-			Transport: tr,
-			Writer:    f,
+	if mode != transportHTTP {
+		tr := mcp.Transport(&mcp.StdioTransport{})
+		if cfg.Logging.Path != "" {
+			tr = &mcp.LoggingTransport{
+				Transport: tr,
+				Writer:    f,
+			}
 		}
+
+		logger.InfoContext(ctx, "sequential thinking mcp server running on stdio")
+		go func() {
+			if err := srv.Run(serveCtx, tr); err != nil {
+				logger.ErrorContext(ctx, "serve sequential thinking mcp stdio server", slog.Any("error", err))
+				errc <- fmt.Errorf("serve sequential thinking mcp stdio server: %w", err)
+				return
+			}
+			errc <- nil
+		}()
 	}
 
-	logger.InfoContext(ctx, "sequential thinking mcp server running on stdio")
-	if err := srv.Run(ctx, tr); err != nil {
-		logger.ErrorContext(ctx, "serve sequential thinking mcp stdio server", slog.Any("error", err))
-		return fmt.Errorf("serve sequential thinking mcp stdio server: %w", err)
+	// the first transport to stop, or a signal, shuts the whole server down
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		logger.Info("shutting down", slog.Duration("timeout", time.Duration(cfg.Transport.ShutdownTimeout)))
+	case serveErr = <-errc:
+	}
+	shutdownErr := shutdown(logger, srv, httpSrv, calls, js, time.Duration(cfg.Transport.ShutdownTimeout))
+	stopServing()
+	if traces != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Transport.ShutdownTimeout))
+		defer cancel()
+		if err := traces.shutdown(ctx); err != nil {
+			shutdownErr = errors.Join(shutdownErr, fmt.Errorf("flush traces: %w", err))
+		}
 	}
 
-	return nil
+	return errors.Join(serveErr, shutdownErr)
+}
+
+// serverOptions configures the MCP server returned by [newServer].
+type serverOptions struct {
+	// instructions are the server instructions, or "" for [defaultInstructions].
+	instructions string
+	// prompts are the prompts loaded from a directory, or nil for the built-in prompts.
+	prompts *promptLibrary
+	// tool describes the sequential thinking tool, or is nil for the default tool.
+	tool *toolSpec
+}
+
+// newServer returns the MCP server exposing the sequential thinking tool backed by thinking.
+func newServer(logger *slog.Logger, thinking *SequentialThinkingServer, so serverOptions) (*mcp.Server, error) {
+	spec := so.tool
+	if spec == nil {
+		var err error
+		if spec, err = newToolSpec(defaultConfig().Tool); err != nil {
+			return nil, err
+		}
+	}
+	instructions := so.instructions
+	if instructions == "" {
+		instructions = defaultInstructions(spec.name)
+	}
+
+	srvImpl := &mcp.Implementation{
+		Name:       "sequential-thinking",
+		Version:    Version,
+		WebsiteURL: "https://github.com/zchee/mcp-sequential-thinking",
+	}
+	opts := &mcp.ServerOptions{
+		Instructions:       instructions,
+		Logger:             logger,
+		HasTools:           true,
+		SubscribeHandler:   thinking.subscribeResource,
+		UnsubscribeHandler: unsubscribeResource,
+		GetSessionID: func() string {
+			// Use UUID7 instead of [mcp.randText]
+			return uuid.Must(uuid.NewV7()).String()
+		},
+	}
+	srv := mcp.NewServer(srvImpl, opts)
+
+	sequentialThinkingTool, err := spec.tool()
+	if err != nil {
+		return nil, err
+	}
+	mcp.AddTool(srv, sequentialThinkingTool, thinking.ProcessThought)
+	if err := registerQueryTools(srv, thinking, spec.name); err != nil {
+		return nil, err
+	}
+	if err := registerExportTool(srv, thinking); err != nil {
+		return nil, err
+	}
+	registerResources(srv, thinking)
+	if so.prompts != nil {
+		so.prompts.register(srv, spec.name)
+	} else if err := registerPrompts(srv, spec.name); err != nil {
+		return nil, err
+	}
+
+	return srv, nil
+}
+
+// ptr returns a pointer to v.
+func ptr[T any](v T) *T {
+	return &v
 }
@@ -28,16 +28,12 @@ import (
 	"github.com/google/go-cmp/cmp"
 )
 
-func restoreRunGlobals(t *testing.T) func() {
+// restoreDefaultLogger restores the default logger replaced by run.
+func restoreDefaultLogger(t *testing.T) func() {
 	t.Helper()
 
-	oldAddr := flagHTTPAddr
-	oldLogPath := flagLogPath
 	oldLogger := slog.Default()
-
 	return func() {
-		flagHTTPAddr = oldAddr
-		flagLogPath = oldLogPath
 		slog.SetDefault(oldLogger)
 	}
 }
@@ -105,12 +101,12 @@ func TestRunInvalidHTTPAddr(t *testing.T) {
 
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			t.Cleanup(restoreRunGlobals(t))
+			t.Cleanup(restoreDefaultLogger(t))
 
-			flagHTTPAddr = tt.addr
-			flagLogPath = ""
+			cfg := defaultConfig()
+			cfg.Transport.HTTP = tt.addr
 
-			err := run()
+			err := run(cfg)
 			if diff := cmp.Diff(true, err != nil); diff != "" {
 				t.Fatalf("error presence mismatch (-want +got):\n%s", diff)
 			}
@@ -124,12 +120,12 @@ func TestRunInvalidHTTPAddr(t *testing.T) {
 }
 
 func TestRunLogPathOpenError(t *testing.T) {
-	t.Cleanup(restoreRunGlobals(t))
+	t.Cleanup(restoreDefaultLogger(t))
 
-	flagHTTPAddr = ""
-	flagLogPath = t.TempDir()
+	cfg := defaultConfig()
+	cfg.Logging.Path = t.TempDir()
 
-	err := run()
+	err := run(cfg)
 	if diff := cmp.Diff(true, err != nil); diff != "" {
 		t.Fatalf("error presence mismatch (-want +got):\n%s", diff)
 	}
@@ -140,8 +136,49 @@ func TestRunLogPathOpenError(t *testing.T) {
 	}
 }
 
+func TestRunLoggingOptionsError(t *testing.T) {
+	tests := map[string]struct {
+		logging     LoggingOptions
+		logThoughts bool
+		wantSubstr  string
+	}{
+		"error: unknown format": {
+			logging:    LoggingOptions{Format: "logfmt", Level: "info"},
+			wantSubstr: "-log-format",
+		},
+		"error: unknown level": {
+			logging:    LoggingOptions{Format: logFormatText, Level: "verbose"},
+			wantSubstr: "-log-level",
+		},
+		"error: unknown console": {
+			logging:    LoggingOptions{Format: logFormatText, Level: "info", Console: "fancy"},
+			wantSubstr: "-console",
+		},
+		"error: thoughts hidden by level": {
+			logging:     LoggingOptions{Format: logFormatText, Level: "warn"},
+			logThoughts: true,
+			wantSubstr:  "-log-thoughts: thoughts are logged at the info level, which -log-level warn hides",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Cleanup(restoreDefaultLogger(t))
+
+			cfg := defaultConfig()
+			cfg.Logging = tt.logging
+			cfg.Tool.LogThoughts = tt.logThoughts
+
+			err := run(cfg)
+			if diff := cmp.Diff(true, err != nil && strings.Contains(err.Error(), tt.wantSubstr)); diff != "" {
+				t.Fatalf("unexpected error %v (-want +got):\n%s", err, diff)
+			}
+		})
+	}
+}
+
 func TestRunStdioInvalidInput(t *testing.T) {
-	t.Cleanup(restoreRunGlobals(t))
+	t.Cleanup(restoreDefaultLogger(t))
 
 	tmpDir := t.TempDir()
 	stdinFile, err := os.CreateTemp(tmpDir, "stdin")
@@ -175,10 +212,10 @@ func TestRunStdioInvalidInput(t *testing.T) {
 	os.Stdin = stdinFile
 	os.Stdout = stdoutFile
 
-	flagHTTPAddr = ""
-	flagLogPath = filepath.Join(tmpDir, "server.log")
+	cfg := defaultConfig()
+	cfg.Logging.Path = filepath.Join(tmpDir, "server.log")
 
-	err = run()
+	err = run(cfg)
 	if diff := cmp.Diff(true, err != nil); diff != "" {
 		t.Fatalf("error presence mismatch (-want +got):\n%s", diff)
 	}
@@ -187,7 +224,7 @@ func TestRunStdioInvalidInput(t *testing.T) {
 			t.Fatalf("error text mismatch (-want +got):\n%s", diff)
 		}
 	}
-	if _, statErr := os.Stat(flagLogPath); statErr != nil {
+	if _, statErr := os.Stat(cfg.Logging.Path); statErr != nil {
 		t.Fatalf("expected log file to exist: %v", statErr)
 	}
 }
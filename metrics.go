@@ -0,0 +1,384 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// metricsPath is the HTTP path of the Prometheus metrics endpoint.
+const metricsPath = "/metrics"
+
+// metricsNamespace prefixes the name of every exported metric.
+const metricsNamespace = "mcp_sequential_thinking_"
+
+// metricsContentType is the content type of the Prometheus text exposition format.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// unknownTool labels the calls of tool names the server does not have, so clients cannot add label values.
+const unknownTool = "unknown"
+
+// otherReason labels tool errors that are not a [ToolError], such as arguments rejected by the input schema.
+const otherReason = "other"
+
+// Histogram bucket upper bounds.
+var (
+	thoughtsPerSessionBuckets = []float64{1, 2, 3, 5, 8, 13, 21, 34, 55, 89}
+	thoughtBytesBuckets       = []float64{64, 256, 1024, 4096, 16384, 65536}
+	estimateDriftBuckets      = []float64{0, 1, 2, 3, 5, 8, 13, 21}
+	latencyBuckets            = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+)
+
+// histogram is a Prometheus histogram with fixed buckets.
+type histogram struct {
+	bounds []float64
+	// counts holds the number of observations in each bucket, not cumulated.
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)),
+	}
+}
+
+// observe adds v to the histogram.
+func (h *histogram) observe(v float64) {
+	if i, _ := slices.BinarySearch(h.bounds, v); i < len(h.bounds) {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+}
+
+// sessionMetrics holds what the metrics need to remember about a live session.
+type sessionMetrics struct {
+	thoughts int
+	// estimate is the totalThoughts of the first thought of the session.
+	estimate int
+}
+
+// metrics collects usage metrics of the thinking tools and writes them in the Prometheus text exposition format.
+//
+// Thought metrics are derived from the [sessionObserver] events, tool error and latency metrics from the
+// tools/call requests seen by [metrics.middleware].
+type metrics struct {
+	thinking *SequentialThinkingServer
+
+	mu                 sync.Mutex
+	thoughts           uint64
+	revisions          uint64
+	branches           uint64
+	sessions           map[string]*sessionMetrics
+	thoughtsPerSession *histogram
+	thoughtBytes       *histogram
+	estimateDrift      *histogram
+	// failures counts the tool errors by tool name and reason.
+	failures map[[2]string]uint64
+	// evictions counts the evicted sessions by reason.
+	evictions map[string]uint64
+	// rateLimited counts the calls rejected by the rate limits by scope.
+	rateLimited map[string]uint64
+	// latency holds the tool call duration histograms by tool name, created up front for the
+	// tools of the server and [unknownTool].
+	latency map[string]*histogram
+}
+
+var (
+	_ sessionObserver   = (*metrics)(nil)
+	_ evictionObserver  = (*metrics)(nil)
+	_ rateLimitObserver = (*metrics)(nil)
+)
+
+// newMetrics returns the metrics of thinking, registered as one of its session observers.
+// Tool calls are labeled with the tool name if it is one of tools, and [unknownTool] otherwise.
+func newMetrics(thinking *SequentialThinkingServer, tools ...string) *metrics {
+	m := &metrics{
+		thinking:           thinking,
+		sessions:           make(map[string]*sessionMetrics),
+		thoughtsPerSession: newHistogram(thoughtsPerSessionBuckets),
+		thoughtBytes:       newHistogram(thoughtBytesBuckets),
+		estimateDrift:      newHistogram(estimateDriftBuckets),
+		failures:           make(map[[2]string]uint64),
+		evictions:          make(map[string]uint64),
+		rateLimited:        make(map[string]uint64),
+		latency:            make(map[string]*histogram, len(tools)+1),
+	}
+	for _, tool := range append(tools, unknownTool) {
+		m.latency[tool] = newHistogram(latencyBuckets)
+	}
+	thinking.observe(m)
+	return m
+}
+
+func (m *metrics) sessionOpened(string) {}
+
+// sessionClosed implements [sessionObserver].
+func (m *metrics) sessionClosed(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if sm, ok := m.sessions[id]; ok {
+		m.thoughtsPerSession.observe(float64(sm.thoughts))
+		delete(m.sessions, id)
+	}
+}
+
+// sessionEvicted implements [evictionObserver].
+func (m *metrics) sessionEvicted(_, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.evictions[reason]++
+}
+
+// thoughtRateLimited implements [rateLimitObserver].
+func (m *metrics) thoughtRateLimited(_, scope string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rateLimited[scope]++
+}
+
+// thoughtAppended implements [sessionObserver].
+//
+// The final thought of a session, with nextThoughtNeeded false, records how far its thought number
+// drifted from the totalThoughts estimated by the first thought.
+func (m *metrics) thoughtAppended(record ThoughtRecord, branchOpened bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.thoughts++
+	if record.IsRevision {
+		m.revisions++
+	}
+	if branchOpened {
+		m.branches++
+	}
+	m.thoughtBytes.observe(float64(len(record.Thought)))
+
+	sm, ok := m.sessions[record.SessionID]
+	if !ok {
+		sm = &sessionMetrics{estimate: record.TotalThoughts}
+		m.sessions[record.SessionID] = sm
+	}
+	sm.thoughts++
+	if !record.NextThoughtNeeded {
+		drift := record.ThoughtNumber - sm.estimate
+		m.estimateDrift.observe(float64(max(drift, -drift)))
+	}
+}
+
+// middleware is an [mcp.Middleware] that measures the latency and counts the errors of tool calls.
+func (m *metrics) middleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		params, ok := req.GetParams().(*mcp.CallToolParamsRaw)
+		if method != "tools/call" || !ok {
+			return next(ctx, method, req)
+		}
+
+		start := time.Now()
+		res, err := next(ctx, method, req)
+		elapsed := time.Since(start)
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		tool := params.Name
+		h, ok := m.latency[tool]
+		if !ok {
+			tool = unknownTool
+			h = m.latency[tool]
+		}
+		h.observe(elapsed.Seconds())
+		if result, ok := res.(*mcp.CallToolResult); ok && err == nil && result.IsError {
+			m.failures[[2]string{tool, toolErrorReason(result)}]++
+		}
+
+		return res, err
+	}
+}
+
+// toolErrorReason returns the reason of the [ToolError] reported by result, or [otherReason].
+func toolErrorReason(result *mcp.CallToolResult) string {
+	if len(result.Content) == 0 {
+		return otherReason
+	}
+	text, ok := result.Content[0].(*mcp.TextContent)
+	if !ok {
+		return otherReason
+	}
+	var te ToolError
+	if err := sonic.ConfigFastest.UnmarshalFromString(text.Text, &te); err != nil || te.Reason == "" {
+		return otherReason
+	}
+	return te.Reason
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+	if err := m.writeTo(w); err != nil {
+		slog.WarnContext(r.Context(), "write metrics", slog.Any("error", err))
+	}
+}
+
+// httpHandler returns the handler of the HTTP listeners: mcpHandler behind the authentication of ac,
+// and m at [metricsPath] if it is set, behind the same authentication unless noAuth.
+func httpHandler(ac *authConfig, mcpHandler http.Handler, m *metrics, noAuth bool) http.Handler {
+	handler := ac.handler(mcpHandler)
+	if m == nil {
+		return handler
+	}
+
+	var metricsHandler http.Handler = m
+	if !noAuth {
+		metricsHandler = ac.handler(m)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, metricsHandler)
+	mux.Handle("/", handler)
+	return mux
+}
+
+// writeTo writes the metrics in the Prometheus text exposition format to w.
+func (m *metrics) writeTo(w io.Writer) error {
+	activeSessions := len(m.thinking.SessionIDs())
+
+	bw := bufio.NewWriter(w)
+
+	m.mu.Lock()
+	writeHeader(bw, "thoughts_total", "counter", "Thoughts recorded by the sequentialthinking tool.")
+	writeSample(bw, "thoughts_total", "", float64(m.thoughts))
+
+	writeHeader(bw, "validation_failures_total", "counter", "Tool calls rejected with a tool error, by tool and reason.")
+	keys := slices.SortedFunc(maps.Keys(m.failures), func(a, b [2]string) int {
+		return strings.Compare(a[0]+"\x00"+a[1], b[0]+"\x00"+b[1])
+	})
+	for _, k := range keys {
+		writeSample(bw, "validation_failures_total", labels("tool", k[0], "reason", k[1]), float64(m.failures[k]))
+	}
+
+	writeHeader(bw, "revisions_total", "counter", "Recorded thoughts that revise an earlier thought.")
+	writeSample(bw, "revisions_total", "", float64(m.revisions))
+
+	writeHeader(bw, "branches_total", "counter", "Branches opened.")
+	writeSample(bw, "branches_total", "", float64(m.branches))
+
+	writeHeader(bw, "thoughts_per_session", "histogram", "Thoughts recorded by a session, observed when it closes.")
+	writeHistogram(bw, "thoughts_per_session", "", m.thoughtsPerSession)
+
+	writeHeader(bw, "thought_bytes", "histogram", "Size of the thought text in bytes.")
+	writeHistogram(bw, "thought_bytes", "", m.thoughtBytes)
+
+	writeHeader(bw, "estimate_drift", "histogram", "Absolute difference between the thought number of the final thought and the totalThoughts estimated by the first thought of the session.")
+	writeHistogram(bw, "estimate_drift", "", m.estimateDrift)
+
+	writeHeader(bw, "active_sessions", "gauge", "Live thinking sessions.")
+	writeSample(bw, "active_sessions", "", float64(activeSessions))
+
+	writeHeader(bw, "sessions_evicted_total", "counter", "Sessions evicted by the limits, by reason.")
+	for _, reason := range slices.Sorted(maps.Keys(m.evictions)) {
+		writeSample(bw, "sessions_evicted_total", labels("reason", reason), float64(m.evictions[reason]))
+	}
+
+	writeHeader(bw, "rate_limited_total", "counter", "Calls to the sequentialthinking tool rejected by the rate limits, by scope.")
+	for _, scope := range slices.Sorted(maps.Keys(m.rateLimited)) {
+		writeSample(bw, "rate_limited_total", labels("scope", scope), float64(m.rateLimited[scope]))
+	}
+
+	writeHeader(bw, "tool_call_duration_seconds", "histogram", "Tool call handler latency, by tool.")
+	for _, tool := range slices.Sorted(maps.Keys(m.latency)) {
+		writeHistogram(bw, "tool_call_duration_seconds", labels("tool", tool), m.latency[tool])
+	}
+	m.mu.Unlock()
+
+	return bw.Flush()
+}
+
+// labels formats alternating label names and values as a Prometheus label set without braces.
+func labels(kv ...string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(kv); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(kv[i])
+		b.WriteString(`="`)
+		b.WriteString(labelValueReplacer.Replace(kv[i+1]))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+// labelValueReplacer escapes a label value of the text exposition format.
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeHeader(w *bufio.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s%s %s\n# TYPE %s%s %s\n", metricsNamespace, name, help, metricsNamespace, name, typ)
+}
+
+func writeSample(w *bufio.Writer, name, labels string, v float64) {
+	w.WriteString(metricsNamespace)
+	w.WriteString(name)
+	if labels != "" {
+		w.WriteString("{" + labels + "}")
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+// writeHistogram writes the cumulative buckets, sum and count of h with the extra labels.
+func writeHistogram(w *bufio.Writer, name, extra string, h *histogram) {
+	le := func(bound string) string {
+		if extra == "" {
+			return labels("le", bound)
+		}
+		return extra + "," + labels("le", bound)
+	}
+
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		writeSample(w, name+"_bucket", le(formatFloat(bound)), float64(cumulative))
+	}
+	writeSample(w, name+"_bucket", le("+Inf"), float64(h.count))
+	writeSample(w, name+"_sum", extra, h.sum)
+	writeSample(w, name+"_count", extra, float64(h.count))
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,291 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestMetricsWriteTo(t *testing.T) {
+	m := newMetrics(NewSequentialThinkingServer())
+	m.thoughtAppended(ThoughtRecord{SessionID: "a", Thought: "frame", ThoughtNumber: 1, TotalThoughts: 3, NextThoughtNeeded: true}, false)
+	m.thoughtAppended(ThoughtRecord{SessionID: "a", Thought: strings.Repeat("x", 300), ThoughtNumber: 2, TotalThoughts: 3, NextThoughtNeeded: true, BranchFromThought: 1, BranchID: "alt"}, true)
+	m.thoughtAppended(ThoughtRecord{SessionID: "a", Thought: "revise", ThoughtNumber: 5, TotalThoughts: 5, IsRevision: true, RevisesThought: 1}, false)
+	m.sessionClosed("a")
+	m.failures[[2]string{"sequentialthinking", reasonInvalidThought}] = 2
+	m.failures[[2]string{"get_thought", reasonThoughtNotFound}] = 1
+	m.sessionEvicted("b", evictionLRU)
+	m.sessionEvicted("c", evictionIdle)
+	m.sessionEvicted("d", evictionIdle)
+	m.thoughtRateLimited("b", rateScopeSession)
+	m.thoughtRateLimited("b", rateScopeSession)
+	m.thoughtRateLimited("c", rateScopeGlobal)
+
+	const want = `# HELP mcp_sequential_thinking_thoughts_total Thoughts recorded by the sequentialthinking tool.
+# TYPE mcp_sequential_thinking_thoughts_total counter
+mcp_sequential_thinking_thoughts_total 3
+# HELP mcp_sequential_thinking_validation_failures_total Tool calls rejected with a tool error, by tool and reason.
+# TYPE mcp_sequential_thinking_validation_failures_total counter
+mcp_sequential_thinking_validation_failures_total{tool="get_thought",reason="thought_not_found"} 1
+mcp_sequential_thinking_validation_failures_total{tool="sequentialthinking",reason="invalid_thought"} 2
+# HELP mcp_sequential_thinking_revisions_total Recorded thoughts that revise an earlier thought.
+# TYPE mcp_sequential_thinking_revisions_total counter
+mcp_sequential_thinking_revisions_total 1
+# HELP mcp_sequential_thinking_branches_total Branches opened.
+# TYPE mcp_sequential_thinking_branches_total counter
+mcp_sequential_thinking_branches_total 1
+# HELP mcp_sequential_thinking_thoughts_per_session Thoughts recorded by a session, observed when it closes.
+# TYPE mcp_sequential_thinking_thoughts_per_session histogram
+mcp_sequential_thinking_thoughts_per_session_bucket{le="1"} 0
+mcp_sequential_thinking_thoughts_per_session_bucket{le="2"} 0
+mcp_sequential_thinking_thoughts_per_session_bucket{le="3"} 1
+mcp_sequential_thinking_thoughts_per_session_bucket{le="5"} 1
+mcp_sequential_thinking_thoughts_per_session_bucket{le="8"} 1
+mcp_sequential_thinking_thoughts_per_session_bucket{le="13"} 1
+mcp_sequential_thinking_thoughts_per_session_bucket{le="21"} 1
+mcp_sequential_thinking_thoughts_per_session_bucket{le="34"} 1
+mcp_sequential_thinking_thoughts_per_session_bucket{le="55"} 1
+mcp_sequential_thinking_thoughts_per_session_bucket{le="89"} 1
+mcp_sequential_thinking_thoughts_per_session_bucket{le="+Inf"} 1
+mcp_sequential_thinking_thoughts_per_session_sum 3
+mcp_sequential_thinking_thoughts_per_session_count 1
+# HELP mcp_sequential_thinking_thought_bytes Size of the thought text in bytes.
+# TYPE mcp_sequential_thinking_thought_bytes histogram
+mcp_sequential_thinking_thought_bytes_bucket{le="64"} 2
+mcp_sequential_thinking_thought_bytes_bucket{le="256"} 2
+mcp_sequential_thinking_thought_bytes_bucket{le="1024"} 3
+mcp_sequential_thinking_thought_bytes_bucket{le="4096"} 3
+mcp_sequential_thinking_thought_bytes_bucket{le="16384"} 3
+mcp_sequential_thinking_thought_bytes_bucket{le="65536"} 3
+mcp_sequential_thinking_thought_bytes_bucket{le="+Inf"} 3
+mcp_sequential_thinking_thought_bytes_sum 311
+mcp_sequential_thinking_thought_bytes_count 3
+# HELP mcp_sequential_thinking_estimate_drift Absolute difference between the thought number of the final thought and the totalThoughts estimated by the first thought of the session.
+# TYPE mcp_sequential_thinking_estimate_drift histogram
+mcp_sequential_thinking_estimate_drift_bucket{le="0"} 0
+mcp_sequential_thinking_estimate_drift_bucket{le="1"} 0
+mcp_sequential_thinking_estimate_drift_bucket{le="2"} 1
+mcp_sequential_thinking_estimate_drift_bucket{le="3"} 1
+mcp_sequential_thinking_estimate_drift_bucket{le="5"} 1
+mcp_sequential_thinking_estimate_drift_bucket{le="8"} 1
+mcp_sequential_thinking_estimate_drift_bucket{le="13"} 1
+mcp_sequential_thinking_estimate_drift_bucket{le="21"} 1
+mcp_sequential_thinking_estimate_drift_bucket{le="+Inf"} 1
+mcp_sequential_thinking_estimate_drift_sum 2
+mcp_sequential_thinking_estimate_drift_count 1
+# HELP mcp_sequential_thinking_active_sessions Live thinking sessions.
+# TYPE mcp_sequential_thinking_active_sessions gauge
+mcp_sequential_thinking_active_sessions 0
+# HELP mcp_sequential_thinking_sessions_evicted_total Sessions evicted by the limits, by reason.
+# TYPE mcp_sequential_thinking_sessions_evicted_total counter
+mcp_sequential_thinking_sessions_evicted_total{reason="idle"} 2
+mcp_sequential_thinking_sessions_evicted_total{reason="lru"} 1
+# HELP mcp_sequential_thinking_rate_limited_total Calls to the sequentialthinking tool rejected by the rate limits, by scope.
+# TYPE mcp_sequential_thinking_rate_limited_total counter
+mcp_sequential_thinking_rate_limited_total{scope="global"} 1
+mcp_sequential_thinking_rate_limited_total{scope="session"} 2
+# HELP mcp_sequential_thinking_tool_call_duration_seconds Tool call handler latency, by tool.
+# TYPE mcp_sequential_thinking_tool_call_duration_seconds histogram
+mcp_sequential_thinking_tool_call_duration_seconds_bucket{tool="unknown",le="0.0005"} 0
+mcp_sequential_thinking_tool_call_duration_seconds_bucket{tool="unknown",le="0.001"} 0
+mcp_sequential_thinking_tool_call_duration_seconds_bucket{tool="unknown",le="0.0025"} 0
+mcp_sequential_thinking_tool_call_duration_seconds_bucket{tool="unknown",le="0.005"} 0
+mcp_sequential_thinking_tool_call_duration_seconds_bucket{tool="unknown",le="0.01"} 0
+mcp_sequential_thinking_tool_call_duration_seconds_bucket{tool="unknown",le="0.025"} 0
+mcp_sequential_thinking_tool_call_duration_seconds_bucket{tool="unknown",le="0.05"} 0
+mcp_sequential_thinking_tool_call_duration_seconds_bucket{tool="unknown",le="0.1"} 0
+mcp_sequential_thinking_tool_call_duration_seconds_bucket{tool="unknown",le="0.25"} 0
+mcp_sequential_thinking_tool_call_duration_seconds_bucket{tool="unknown",le="0.5"} 0
+mcp_sequential_thinking_tool_call_duration_seconds_bucket{tool="unknown",le="1"} 0
+mcp_sequential_thinking_tool_call_duration_seconds_bucket{tool="unknown",le="+Inf"} 0
+mcp_sequential_thinking_tool_call_duration_seconds_sum{tool="unknown"} 0
+mcp_sequential_thinking_tool_call_duration_seconds_count{tool="unknown"} 0
+`
+
+	var b strings.Builder
+	if err := m.writeTo(&b); err != nil {
+		t.Fatalf("write metrics: %v", err)
+	}
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Fatalf("exposition mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	tests := map[string]struct {
+		kv   []string
+		want string
+	}{
+		"success: empty": {},
+		"success: pairs": {
+			kv:   []string{"tool", "get_thought", "reason", "invalid_range"},
+			want: `tool="get_thought",reason="invalid_range"`,
+		},
+		"success: escaped": {
+			kv:   []string{"tool", "a\"b\\c\nd"},
+			want: `tool="a\"b\\c\nd"`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, labels(tt.kv...)); diff != "" {
+				t.Fatalf("labels mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	thinking := NewSequentialThinkingServer()
+	m := newMetrics(thinking, append([]string{defaultToolName}, reservedToolNames...)...)
+	srv, err := newServer(slog.New(slog.DiscardHandler), thinking, serverOptions{})
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	srv.AddReceivingMiddleware(m.middleware)
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	ss, err := srv.Connect(t.Context(), serverTransport, nil)
+	if err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+	t.Cleanup(func() { ss.Close() })
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "v0.0.0"}, nil)
+	cs, err := client.Connect(t.Context(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("connect client: %v", err)
+	}
+	t.Cleanup(func() { cs.Close() })
+
+	recordThoughts(t, cs, resourceThoughts...)
+	if _, err := cs.CallTool(t.Context(), &mcp.CallToolParams{
+		Name:      "sequentialthinking",
+		Arguments: ThoughtData{Thought: "revise nothing", ThoughtNumber: 4, TotalThoughts: 4, IsRevision: true, RevisesThought: 9},
+	}); err != nil {
+		t.Fatalf("call tool: %v", err)
+	}
+	if reason := callQueryTool(t, cs, "get_thought", GetThoughtInput{ThoughtNumber: 7}, &GetThoughtOutput{}); reason != reasonThoughtNotFound {
+		t.Fatalf("get_thought reason: got %q, want %q", reason, reasonThoughtNotFound)
+	}
+
+	for _, name := range []string{"no_such_tool", "another_tool"} {
+		if _, err := cs.CallTool(t.Context(), &mcp.CallToolParams{Name: name}); err == nil {
+			t.Fatalf("call unknown tool %s succeeded", name)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", metricsPath, nil))
+	if diff := cmp.Diff(metricsContentType, rec.Header().Get("Content-Type")); diff != "" {
+		t.Fatalf("content type mismatch (-want +got):\n%s", diff)
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		`mcp_sequential_thinking_thoughts_total 3`,
+		`mcp_sequential_thinking_revisions_total 1`,
+		`mcp_sequential_thinking_branches_total 1`,
+		`mcp_sequential_thinking_active_sessions 1`,
+		`mcp_sequential_thinking_validation_failures_total{tool="get_thought",reason="thought_not_found"} 1`,
+		`mcp_sequential_thinking_validation_failures_total{tool="sequentialthinking",reason="revision_target_not_found"} 1`,
+		`mcp_sequential_thinking_tool_call_duration_seconds_count{tool="sequentialthinking"} 4`,
+		`mcp_sequential_thinking_tool_call_duration_seconds_count{tool="get_thought"} 1`,
+		`mcp_sequential_thinking_tool_call_duration_seconds_count{tool="list_branches"} 0`,
+		`mcp_sequential_thinking_tool_call_duration_seconds_count{tool="unknown"} 2`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics missing %q:\n%s", line, body)
+		}
+	}
+	for _, name := range []string{"no_such_tool", "another_tool"} {
+		if strings.Contains(body, name) {
+			t.Errorf("metrics label unknown tool %q:\n%s", name, body)
+		}
+	}
+}
+
+func TestHTTPHandlerMetricsAuth(t *testing.T) {
+	tokensPath := filepath.Join(t.TempDir(), "tokens")
+	if err := os.WriteFile(tokensPath, []byte("prometheus s3cret\n"), 0o600); err != nil {
+		t.Fatalf("write tokens: %v", err)
+	}
+	ac, err := loadAuthConfig(tokensPath, "", "", "")
+	if err != nil {
+		t.Fatalf("load auth config: %v", err)
+	}
+	m := newMetrics(NewSequentialThinkingServer(), defaultToolName)
+	mcpHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("metrics request reached the MCP endpoint: %s", r.URL)
+	})
+
+	tests := map[string]struct {
+		noAuth     bool
+		token      string
+		wantStatus int
+	}{
+		"success: token": {
+			token:      "s3cret",
+			wantStatus: http.StatusOK,
+		},
+		"success: without authentication": {
+			noAuth:     true,
+			wantStatus: http.StatusOK,
+		},
+		"error: no token": {
+			wantStatus: http.StatusUnauthorized,
+		},
+		"error: unknown token": {
+			token:      "guess",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", metricsPath, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			httpHandler(ac, mcpHandler, m, tt.noAuth).ServeHTTP(rec, req)
+			if diff := cmp.Diff(tt.wantStatus, rec.Code); diff != "" {
+				t.Fatalf("status mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunMetricsRequiresHTTP(t *testing.T) {
+	t.Cleanup(restoreDefaultLogger(t))
+
+	cfg := defaultConfig()
+	cfg.Telemetry.Metrics = true
+
+	err := run(cfg)
+	if diff := cmp.Diff(true, err != nil && strings.Contains(err.Error(), "-metrics requires")); diff != "" {
+		t.Fatalf("unexpected error %v (-want +got):\n%s", err, diff)
+	}
+}
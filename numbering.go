@@ -0,0 +1,127 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import "fmt"
+
+// Numbering modes selected by -numbering.
+const (
+	// numberingClient trusts the thought numbers of the client.
+	numberingClient = "client"
+	// numberingCheck records the thought numbers of the client and reports duplicates, gaps and regressions.
+	numberingCheck = "check"
+	// numberingAssign replaces the thought numbers of the client with the expected ones.
+	numberingAssign = "assign"
+)
+
+// Numbering issues of a requested thought number, relative to the thoughts of its line.
+const (
+	// numberingDuplicate is a number already recorded on the line.
+	numberingDuplicate = "duplicate"
+	// numberingGap is a number past the expected one, skipping numbers.
+	numberingGap = "gap"
+	// numberingRegression is a number below the expected one, not recorded on the line.
+	numberingRegression = "regression"
+)
+
+// Numbering is the server's view of the numbering of a thought, reported in [Output]
+// unless the thought numbers of the client are trusted.
+type Numbering struct {
+	Mode              string                `json:"mode" jsonschema:"Numbering mode of the server: check or assign"`
+	BranchID          string                `json:"branchId,omitzero" jsonschema:"Branch the thought was numbered on, empty for the main line"`
+	Issue             string                `json:"issue,omitzero" jsonschema:"Problem with the requested thoughtNumber: duplicate, gap or regression"`
+	Message           string                `json:"message,omitzero" jsonschema:"Description of the issue"`
+	Corrections       []NumberingCorrection `json:"corrections,omitempty" jsonschema:"Fields of the thought changed by the server"`
+	NextThoughtNumber int                   `json:"nextThoughtNumber" jsonschema:"Thought number the server expects next on the same line"`
+}
+
+// NumberingCorrection is a field of a thought changed by the server.
+type NumberingCorrection struct {
+	Field string `json:"field" jsonschema:"Corrected field: thoughtNumber or totalThoughts"`
+	From  int    `json:"from" jsonschema:"Value requested by the client"`
+	To    int    `json:"to" jsonschema:"Value recorded by the server"`
+}
+
+// validateNumbering reports whether m is a numbering mode.
+func validateNumbering(m string) error {
+	switch m {
+	case numberingClient, numberingCheck, numberingAssign:
+		return nil
+	}
+	return fmt.Errorf("-numbering: must be %s, %s or %s, got %q", numberingClient, numberingCheck, numberingAssign, m)
+}
+
+// lineNumbers returns the thought numbers recorded on the line of record: the branch it continues
+// or opens, or the main line without a branch. expected is the number the next thought of the line
+// should have: one past the highest number of the line, or past the branch origin on a new branch.
+// recorded must not be modified.
+//
+// The caller must hold ts.mu.
+func (ts *thinkingSession) lineNumbers(record ThoughtRecord) (recorded map[int]bool, expected int) {
+	if record.BranchID == "" {
+		return ts.mainLine.numbers, ts.mainLine.highest + 1
+	}
+	br, ok := ts.branches[record.BranchID]
+	if !ok {
+		return nil, record.BranchFromThought + 1
+	}
+	return br.line.numbers, br.line.highest + 1
+}
+
+// number checks the thought number of record against its line in mode, which is not
+// [numberingClient], and assigns the expected number in [numberingAssign] mode.
+// It reconciles totalThoughts with the recorded number, and returns the numbering reported to the client.
+//
+// The caller must hold ts.mu.
+func (ts *thinkingSession) number(record *ThoughtRecord, mode string) *Numbering {
+	recorded, expected := ts.lineNumbers(*record)
+	n := record.ThoughtNumber
+	numbering := &Numbering{
+		Mode:     mode,
+		BranchID: record.BranchID,
+	}
+	line := "the main line"
+	if record.BranchID != "" {
+		line = fmt.Sprintf("branch %q", record.BranchID)
+	}
+	switch {
+	case n == expected:
+	case n == expected+1:
+		numbering.Issue = numberingGap
+		numbering.Message = fmt.Sprintf("thought %d skips thought %d of %s", n, expected, line)
+	case n > expected:
+		numbering.Issue = numberingGap
+		numbering.Message = fmt.Sprintf("thought %d skips thoughts %d to %d of %s", n, expected, n-1, line)
+	case recorded[n]:
+		numbering.Issue = numberingDuplicate
+		numbering.Message = fmt.Sprintf("thought %d was already recorded on %s, expected %d", n, line, expected)
+	default:
+		numbering.Issue = numberingRegression
+		numbering.Message = fmt.Sprintf("thought %d goes back from thought %d of %s", n, expected-1, line)
+	}
+
+	if numbering.Issue != "" && mode == numberingAssign {
+		record.ThoughtNumber = expected
+		numbering.Corrections = append(numbering.Corrections, NumberingCorrection{Field: "thoughtNumber", From: n, To: expected})
+	}
+	if record.ThoughtNumber > record.TotalThoughts {
+		numbering.Corrections = append(numbering.Corrections, NumberingCorrection{Field: "totalThoughts", From: record.TotalThoughts, To: record.ThoughtNumber})
+		record.TotalThoughts = record.ThoughtNumber
+	}
+	numbering.NextThoughtNumber = max(expected, record.ThoughtNumber+1)
+	return numbering
+}
@@ -0,0 +1,207 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidateNumbering(t *testing.T) {
+	tests := map[string]struct {
+		mode    string
+		wantErr string
+	}{
+		"success: client": {mode: numberingClient},
+		"success: check":  {mode: numberingCheck},
+		"success: assign": {mode: numberingAssign},
+		"error: unknown mode": {
+			mode:    "strict",
+			wantErr: `-numbering: must be client, check or assign, got "strict"`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateNumbering(tt.mode)
+			if diff := cmp.Diff(tt.wantErr != "", err != nil); diff != "" {
+				t.Fatalf("error presence mismatch %v (-want +got):\n%s", err, diff)
+			}
+			if err != nil {
+				if diff := cmp.Diff(tt.wantErr, err.Error()); diff != "" {
+					t.Fatalf("error text mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestSequentialThinkingServerNumbering(t *testing.T) {
+	recorded := []ThoughtData{
+		{Thought: "frame", ThoughtNumber: 1, TotalThoughts: 4, NextThoughtNeeded: true},
+		{Thought: "approach", ThoughtNumber: 2, TotalThoughts: 4, NextThoughtNeeded: true},
+		{Thought: "alternative", ThoughtNumber: 2, TotalThoughts: 4, NextThoughtNeeded: true, BranchFromThought: 1, BranchID: "alt"},
+	}
+
+	tests := map[string]struct {
+		mode          string
+		input         ThoughtData
+		wantNumbering *Numbering
+		wantNumber    int
+		wantTotal     int
+	}{
+		"success: client numbers are trusted": {
+			mode:       numberingClient,
+			input:      ThoughtData{Thought: "leap", ThoughtNumber: 7, TotalThoughts: 4, NextThoughtNeeded: true},
+			wantNumber: 7,
+			wantTotal:  7,
+		},
+		"success: check in sequence": {
+			mode:          numberingCheck,
+			input:         ThoughtData{Thought: "detail", ThoughtNumber: 3, TotalThoughts: 4, NextThoughtNeeded: true},
+			wantNumbering: &Numbering{Mode: numberingCheck, NextThoughtNumber: 4},
+			wantNumber:    3,
+			wantTotal:     4,
+		},
+		"success: check reports gap": {
+			mode:  numberingCheck,
+			input: ThoughtData{Thought: "leap", ThoughtNumber: 6, TotalThoughts: 4, NextThoughtNeeded: true},
+			wantNumbering: &Numbering{
+				Mode:              numberingCheck,
+				Issue:             numberingGap,
+				Message:           "thought 6 skips thoughts 3 to 5 of the main line",
+				Corrections:       []NumberingCorrection{{Field: "totalThoughts", From: 4, To: 6}},
+				NextThoughtNumber: 7,
+			},
+			wantNumber: 6,
+			wantTotal:  6,
+		},
+		"success: check reports duplicate": {
+			mode:  numberingCheck,
+			input: ThoughtData{Thought: "approach again", ThoughtNumber: 2, TotalThoughts: 4, NextThoughtNeeded: true},
+			wantNumbering: &Numbering{
+				Mode:              numberingCheck,
+				Issue:             numberingDuplicate,
+				Message:           "thought 2 was already recorded on the main line, expected 3",
+				NextThoughtNumber: 3,
+			},
+			wantNumber: 2,
+			wantTotal:  4,
+		},
+		"success: assign corrects gap": {
+			mode:  numberingAssign,
+			input: ThoughtData{Thought: "leap", ThoughtNumber: 4, TotalThoughts: 4, NextThoughtNeeded: true},
+			wantNumbering: &Numbering{
+				Mode:              numberingAssign,
+				Issue:             numberingGap,
+				Message:           "thought 4 skips thought 3 of the main line",
+				Corrections:       []NumberingCorrection{{Field: "thoughtNumber", From: 4, To: 3}},
+				NextThoughtNumber: 4,
+			},
+			wantNumber: 3,
+			wantTotal:  4,
+		},
+		"success: assign corrects duplicate on branch": {
+			mode:  numberingAssign,
+			input: ThoughtData{Thought: "alternative detail", ThoughtNumber: 2, TotalThoughts: 2, NextThoughtNeeded: true, BranchID: "alt"},
+			wantNumbering: &Numbering{
+				Mode:     numberingAssign,
+				BranchID: "alt",
+				Issue:    numberingDuplicate,
+				Message:  `thought 2 was already recorded on branch "alt", expected 3`,
+				Corrections: []NumberingCorrection{
+					{Field: "thoughtNumber", From: 2, To: 3},
+					{Field: "totalThoughts", From: 2, To: 3},
+				},
+				NextThoughtNumber: 4,
+			},
+			wantNumber: 3,
+			wantTotal:  3,
+		},
+		"success: assign corrects regression on new branch": {
+			mode:  numberingAssign,
+			input: ThoughtData{Thought: "restart", ThoughtNumber: 1, TotalThoughts: 4, NextThoughtNeeded: true, BranchFromThought: 2, BranchID: "restart"},
+			wantNumbering: &Numbering{
+				Mode:              numberingAssign,
+				BranchID:          "restart",
+				Issue:             numberingRegression,
+				Message:           `thought 1 goes back from thought 2 of branch "restart"`,
+				Corrections:       []NumberingCorrection{{Field: "thoughtNumber", From: 1, To: 3}},
+				NextThoughtNumber: 4,
+			},
+			wantNumber: 3,
+			wantTotal:  4,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := NewSequentialThinkingServer()
+			server.numbering = tt.mode
+			for _, input := range recorded {
+				result, _, err := server.ProcessThought(t.Context(), nil, input)
+				if err != nil {
+					t.Fatalf("process thought: %v", err)
+				}
+				if result.IsError {
+					t.Fatalf("tool error: %s", resultText(t, result))
+				}
+			}
+
+			result, out, err := server.ProcessThought(t.Context(), nil, tt.input)
+			if err != nil {
+				t.Fatalf("process thought: %v", err)
+			}
+			if result.IsError {
+				t.Fatalf("tool error: %s", resultText(t, result))
+			}
+			output := out.(Output)
+			if diff := cmp.Diff(tt.wantNumbering, output.Numbering); diff != "" {
+				t.Fatalf("numbering mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff([2]int{tt.wantNumber, tt.wantTotal}, [2]int{output.ThoughtNumber, output.TotalThoughts}); diff != "" {
+				t.Fatalf("output thought number and total mismatch (-want +got):\n%s", diff)
+			}
+			history := server.History(defaultSessionID)
+			last := history[len(history)-1]
+			if diff := cmp.Diff([2]int{tt.wantNumber, tt.wantTotal}, [2]int{last.ThoughtNumber, last.TotalThoughts}); diff != "" {
+				t.Fatalf("recorded thought number and total mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNumberingOutputSchema(t *testing.T) {
+	thinking := NewSequentialThinkingServer()
+	thinking.numbering = numberingAssign
+	cs := connectInMemoryClient(t, thinking)
+
+	callThought(t, cs, ThoughtData{Thought: "frame", ThoughtNumber: 1, TotalThoughts: 2, NextThoughtNeeded: true})
+	got := callThought(t, cs, ThoughtData{Thought: "frame again", ThoughtNumber: 1, TotalThoughts: 2, NextThoughtNeeded: true})
+
+	want := &Numbering{
+		Mode:              numberingAssign,
+		Issue:             numberingDuplicate,
+		Message:           "thought 1 was already recorded on the main line, expected 2",
+		Corrections:       []NumberingCorrection{{Field: "thoughtNumber", From: 1, To: 2}},
+		NextThoughtNumber: 3,
+	}
+	if diff := cmp.Diff(want, got.Numbering); diff != "" {
+		t.Fatalf("numbering mismatch (-want +got):\n%s", diff)
+	}
+}
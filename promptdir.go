@@ -0,0 +1,291 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+// promptReloadInterval is how often the prompts directory is checked for changes.
+const promptReloadInterval = 2 * time.Second
+
+// promptFileExts lists the extensions of the prompt template files of a prompts directory.
+var promptFileExts = []string{".md", ".tmpl"}
+
+// validPromptName matches the names of prompts and prompt arguments.
+var validPromptName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// promptFrontMatter is the YAML front-matter of a prompt template file.
+type promptFrontMatter struct {
+	Name        string `yaml:"name"`
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	Arguments   []struct {
+		Name        string `yaml:"name"`
+		Title       string `yaml:"title"`
+		Description string `yaml:"description"`
+		Required    bool   `yaml:"required"`
+	} `yaml:"arguments"`
+}
+
+// parsePromptFile returns the prompt defined by the template file named name with contents data.
+//
+// The file starts with a YAML front-matter between two "---" lines, followed by the text/template body.
+// The name of the prompt defaults to the file name without its extension. The body must only refer
+// to the declared arguments.
+func parsePromptFile(name string, data []byte) (*promptTemplate, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	rest, ok := strings.CutPrefix(text, "---\n")
+	if !ok {
+		return nil, fmt.Errorf("prompt file %q: must start with a --- front-matter line", name)
+	}
+	// the leading newline lets the front-matter be empty
+	header, body, ok := strings.Cut("\n"+rest, "\n---\n")
+	if !ok {
+		header, ok = strings.CutSuffix("\n"+rest, "\n---")
+		if !ok {
+			return nil, fmt.Errorf("prompt file %q: front-matter is not closed by a --- line", name)
+		}
+	}
+
+	var fm promptFrontMatter
+	dec := yaml.NewDecoder(strings.NewReader(header))
+	dec.KnownFields(true)
+	if err := dec.Decode(&fm); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("prompt file %q: front-matter: %w", name, err)
+	}
+	if fm.Name == "" {
+		fm.Name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	if !validPromptName.MatchString(fm.Name) {
+		return nil, fmt.Errorf("prompt file %q: invalid name %q", name, fm.Name)
+	}
+
+	p := &mcp.Prompt{
+		Name:        fm.Name,
+		Title:       fm.Title,
+		Description: fm.Description,
+	}
+	args := make(map[string]string, len(fm.Arguments))
+	for _, arg := range fm.Arguments {
+		if !validPromptName.MatchString(arg.Name) {
+			return nil, fmt.Errorf("prompt file %q: invalid argument name %q", name, arg.Name)
+		}
+		if _, ok := args[arg.Name]; ok {
+			return nil, fmt.Errorf("prompt file %q: duplicate argument %q", name, arg.Name)
+		}
+		args[arg.Name] = arg.Name
+		p.Arguments = append(p.Arguments, &mcp.PromptArgument{
+			Name:        arg.Name,
+			Title:       arg.Title,
+			Description: arg.Description,
+			Required:    arg.Required,
+		})
+	}
+
+	if strings.TrimSpace(body) == "" {
+		return nil, fmt.Errorf("prompt file %q: body is empty", name)
+	}
+	pt, err := newPromptTemplate(p, body)
+	if err != nil {
+		return nil, fmt.Errorf("prompt file %q: %w", name, err)
+	}
+	pt.source = text
+
+	// render once with every argument set, failing on references to undeclared arguments
+	check, err := pt.tmpl.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("prompt file %q: %w", name, err)
+	}
+	if err := check.Option("missingkey=error").Execute(&bytes.Buffer{}, promptData{Tool: "tool", Args: args}); err != nil {
+		return nil, fmt.Errorf("prompt file %q: %w", name, err)
+	}
+	return pt, nil
+}
+
+// promptFile is a prompt template file read from a prompts directory.
+type promptFile struct {
+	name string
+	data []byte
+}
+
+// readPromptDir returns the prompt template files of dir, sorted by name, and their fingerprint.
+func readPromptDir(dir string) ([]promptFile, [sha256.Size]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, [sha256.Size]byte{}, fmt.Errorf("read prompts directory: %w", err)
+	}
+
+	var files []promptFile
+	h := sha256.New()
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || !slices.Contains(promptFileExts, filepath.Ext(name)) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, [sha256.Size]byte{}, fmt.Errorf("read prompts directory: %w", err)
+		}
+		files = append(files, promptFile{name: name, data: data})
+		fmt.Fprintf(h, "%s\x00%d\x00", name, len(data))
+		h.Write(data)
+	}
+	return files, [sha256.Size]byte(h.Sum(nil)), nil
+}
+
+// loadPromptFiles returns the prompts defined by files by name.
+func loadPromptFiles(files []promptFile) (map[string]*promptTemplate, error) {
+	prompts := make(map[string]*promptTemplate, len(files))
+	defined := make(map[string]string, len(files))
+	for _, f := range files {
+		pt, err := parsePromptFile(f.name, f.data)
+		if err != nil {
+			return nil, err
+		}
+		name := pt.prompt.Name
+		if other, ok := defined[name]; ok {
+			return nil, fmt.Errorf("prompt file %q: prompt %q is already defined by %q", f.name, name, other)
+		}
+		defined[name] = f.name
+		prompts[name] = pt
+	}
+	return prompts, nil
+}
+
+// promptLibrary holds the prompt templates of a directory over the built-in prompts, and reloads them
+// when the files of the directory change, updating the MCP server they are registered on.
+//
+// A prompt template named like a built-in prompt replaces it.
+type promptLibrary struct {
+	dir      string
+	builtins map[string]*promptTemplate
+
+	mu sync.Mutex
+	// fingerprint identifies the files last loaded, whether they were valid or not.
+	fingerprint [sha256.Size]byte
+	// prompts holds the built-in and loaded prompts by name.
+	prompts map[string]*promptTemplate
+	// srv is the server the prompts are registered on, driving the sequential thinking tool named tool.
+	srv  *mcp.Server
+	tool string
+}
+
+// loadPromptLibrary loads the prompt templates of dir. It fails if a template is invalid.
+func loadPromptLibrary(dir string) (*promptLibrary, error) {
+	builtins, err := builtinPromptTemplates()
+	if err != nil {
+		return nil, err
+	}
+	l := &promptLibrary{
+		dir:      dir,
+		builtins: builtins,
+	}
+	if _, err := l.reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// register adds the prompts to srv, driving the sequential thinking tool named tool, and keeps them
+// up to date on reload.
+func (l *promptLibrary) register(srv *mcp.Server, tool string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.srv, l.tool = srv, tool
+	applyPrompts(srv, tool, nil, l.prompts)
+}
+
+// reload loads the prompt templates again if the files of the directory changed since the last load,
+// and updates the prompts of the server. It reports whether the prompts changed.
+//
+// If a template is invalid, the prompts are left as they are until the files change again.
+func (l *promptLibrary) reload() (bool, error) {
+	files, fingerprint, err := readPromptDir(l.dir)
+	if err != nil {
+		return false, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.prompts != nil && fingerprint == l.fingerprint {
+		return false, nil
+	}
+	l.fingerprint = fingerprint
+	loaded, err := loadPromptFiles(files)
+	if err != nil {
+		return false, err
+	}
+
+	next := maps.Clone(l.builtins)
+	maps.Copy(next, loaded)
+	if l.srv != nil {
+		applyPrompts(l.srv, l.tool, l.prompts, next)
+	}
+	l.prompts = next
+	return true, nil
+}
+
+// watch reloads the prompt templates every interval until ctx is done.
+func (l *promptLibrary) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// an error is logged once, not on every tick until it is fixed
+	var lastErr string
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := l.reload()
+			if err != nil {
+				if err.Error() != lastErr {
+					slog.Error("reload prompts", slog.String("dir", l.dir), slog.Any("error", err))
+				}
+				lastErr = err.Error()
+				continue
+			}
+			lastErr = ""
+			if reloaded {
+				l.mu.Lock()
+				n := len(l.prompts)
+				l.mu.Unlock()
+				slog.Info("reloaded prompts", slog.String("dir", l.dir), slog.Int("prompts", n))
+			}
+		}
+	}
+}
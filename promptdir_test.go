@@ -0,0 +1,319 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const testPromptFile = `---
+name: triage-alert
+title: Triage an alert
+description: Decide whether an alert needs action
+arguments:
+  - name: alert
+    description: The alert and its labels
+    required: true
+  - name: runbook
+---
+Triage this alert with the {{.Tool}} tool.
+
+{{.Args.alert}}
+{{with .Args.runbook}}
+Runbook: {{.}}
+{{end}}`
+
+func TestParsePromptFile(t *testing.T) {
+	tests := map[string]struct {
+		name     string
+		data     string
+		args     map[string]string
+		want     *mcp.Prompt
+		wantText string
+		wantErr  string
+	}{
+		"success: front-matter": {
+			name: "triage.md",
+			data: testPromptFile,
+			args: map[string]string{"alert": "DiskFull on db-1", "runbook": "https://runbooks.example/disk"},
+			want: &mcp.Prompt{
+				Name:        "triage-alert",
+				Title:       "Triage an alert",
+				Description: "Decide whether an alert needs action",
+				Arguments: []*mcp.PromptArgument{
+					{Name: "alert", Description: "The alert and its labels", Required: true},
+					{Name: "runbook"},
+				},
+			},
+			wantText: "Triage this alert with the sequentialthinking tool.\n\nDiskFull on db-1\n\nRunbook: https://runbooks.example/disk",
+		},
+		"success: name from file name and CRLF": {
+			name:     "plan.tmpl",
+			data:     "---\r\ndescription: Plan a change\r\n---\r\nPlan it with {{.Tool}}.\r\n",
+			want:     &mcp.Prompt{Name: "plan", Description: "Plan a change"},
+			wantText: "Plan it with sequentialthinking.",
+		},
+		"error: missing front-matter": {
+			name:    "plan.md",
+			data:    "Plan it.\n",
+			wantErr: "must start with a --- front-matter line",
+		},
+		"error: unclosed front-matter": {
+			name:    "plan.md",
+			data:    "---\nname: plan\nPlan it.\n",
+			wantErr: "front-matter is not closed",
+		},
+		"error: unknown front-matter key": {
+			name:    "plan.md",
+			data:    "---\nname: plan\nargs: []\n---\nPlan it.\n",
+			wantErr: "field args not found",
+		},
+		"error: invalid name": {
+			name:    "plan.md",
+			data:    "---\nname: plan it\n---\nPlan it.\n",
+			wantErr: `invalid name "plan it"`,
+		},
+		"error: duplicate argument": {
+			name:    "plan.md",
+			data:    "---\narguments:\n  - name: goal\n  - name: goal\n---\nPlan {{.Args.goal}}.\n",
+			wantErr: `duplicate argument "goal"`,
+		},
+		"error: undeclared argument": {
+			name:    "plan.md",
+			data:    "---\narguments:\n  - name: goal\n---\nPlan {{.Args.gaol}}.\n",
+			wantErr: `map has no entry for key "gaol"`,
+		},
+		"error: invalid template": {
+			name:    "plan.md",
+			data:    "---\nname: plan\n---\nPlan {{.Args.goal.\n",
+			wantErr: `parse prompt "plan"`,
+		},
+		"error: empty body": {
+			name:    "plan.md",
+			data:    "---\nname: plan\n---\n",
+			wantErr: "body is empty",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			pt, err := parsePromptFile(tt.name, []byte(tt.data))
+			if diff := cmp.Diff(tt.wantErr != "", err != nil); diff != "" {
+				t.Fatalf("error presence mismatch %v (-want +got):\n%s", err, diff)
+			}
+			if err != nil {
+				if diff := cmp.Diff(true, strings.Contains(err.Error(), tt.wantErr)); diff != "" {
+					t.Fatalf("error text mismatch %q (-want +got):\n%s", err, diff)
+				}
+				return
+			}
+			if diff := cmp.Diff(tt.want, pt.prompt); diff != "" {
+				t.Fatalf("prompt mismatch (-want +got):\n%s", diff)
+			}
+			got, err := pt.render("sequentialthinking", tt.args)
+			if err != nil {
+				t.Fatalf("render prompt: %v", err)
+			}
+			if diff := cmp.Diff(tt.wantText, got); diff != "" {
+				t.Fatalf("text mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadPromptFilesDuplicateName(t *testing.T) {
+	_, err := loadPromptFiles([]promptFile{
+		{name: "a.md", data: []byte("---\nname: plan\n---\nPlan it.\n")},
+		{name: "plan.md", data: []byte("---\n---\nPlan it again.\n")},
+	})
+	want := `prompt file "plan.md": prompt "plan" is already defined by "a.md"`
+	if diff := cmp.Diff(true, err != nil && err.Error() == want); diff != "" {
+		t.Fatalf("unexpected error %v (-want +got):\n%s", err, diff)
+	}
+}
+
+// writePromptFile writes data to the prompt template file name in dir, or removes it if data is empty.
+func writePromptFile(t *testing.T, dir, name, data string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if data == "" {
+		if err := os.Remove(path); err != nil {
+			t.Fatalf("remove prompt file: %v", err)
+		}
+		return
+	}
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("write prompt file: %v", err)
+	}
+}
+
+// listPrompts returns the descriptions of the prompts listed by cs by name.
+func listPrompts(t *testing.T, cs *mcp.ClientSession) map[string]string {
+	t.Helper()
+
+	result, err := cs.ListPrompts(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("list prompts: %v", err)
+	}
+	prompts := make(map[string]string, len(result.Prompts))
+	for _, p := range result.Prompts {
+		prompts[p.Name] = p.Description
+	}
+	return prompts
+}
+
+func TestPromptLibraryReload(t *testing.T) {
+	dir := t.TempDir()
+	writePromptFile(t, dir, "triage.md", testPromptFile)
+	writePromptFile(t, dir, "notes.txt", "not a prompt")
+
+	lib, err := loadPromptLibrary(dir)
+	if err != nil {
+		t.Fatalf("load prompt library: %v", err)
+	}
+	srv, err := newServer(slog.New(slog.DiscardHandler), NewSequentialThinkingServer(), serverOptions{prompts: lib})
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	ss, err := srv.Connect(t.Context(), serverTransport, nil)
+	if err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+	t.Cleanup(func() { ss.Close() })
+	listChanged := make(chan struct{}, 16)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "v0.0.0"}, &mcp.ClientOptions{
+		PromptListChangedHandler: func(context.Context, *mcp.PromptListChangedRequest) {
+			listChanged <- struct{}{}
+		},
+	})
+	cs, err := client.Connect(t.Context(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("connect client: %v", err)
+	}
+	t.Cleanup(func() { cs.Close() })
+
+	builtinDescription := func(name string) string {
+		for _, p := range builtinPrompts {
+			if p.prompt.Name == name {
+				return p.prompt.Description
+			}
+		}
+		return ""
+	}
+	want := map[string]string{
+		"compare-alternatives": builtinDescription("compare-alternatives"),
+		"debug-a-failure":      builtinDescription("debug-a-failure"),
+		"design-review":        builtinDescription("design-review"),
+		"root-cause-analysis":  builtinDescription("root-cause-analysis"),
+		"triage-alert":         "Decide whether an alert needs action",
+	}
+	if diff := cmp.Diff(want, listPrompts(t, cs)); diff != "" {
+		t.Fatalf("prompts mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, step := range []struct {
+		name       string
+		file       string
+		data       string
+		wantReload bool
+		wantErr    bool
+		update     func(want map[string]string)
+	}{
+		{
+			name:       "override built-in prompt",
+			file:       "review.md",
+			data:       "---\nname: design-review\ndescription: Our design review\n---\nReview it with {{.Tool}}.\n",
+			wantReload: true,
+			update:     func(want map[string]string) { want["design-review"] = "Our design review" },
+		},
+		{
+			name:    "invalid template keeps prompts",
+			file:    "triage.md",
+			data:    "---\nname: triage-alert\n---\nTriage {{.Args.alert}}.\n",
+			wantErr: true,
+		},
+		{
+			name:       "fixed template",
+			file:       "triage.md",
+			data:       strings.Replace(testPromptFile, "Decide whether", "Decide if", 1),
+			wantReload: true,
+			update:     func(want map[string]string) { want["triage-alert"] = "Decide if an alert needs action" },
+		},
+		{
+			name:       "removed override restores built-in prompt",
+			file:       "review.md",
+			wantReload: true,
+			update:     func(want map[string]string) { want["design-review"] = builtinDescription("design-review") },
+		},
+		{
+			name:       "removed prompt",
+			file:       "triage.md",
+			wantReload: true,
+			update:     func(want map[string]string) { delete(want, "triage-alert") },
+		},
+	} {
+		writePromptFile(t, dir, step.file, step.data)
+		reloaded, err := lib.reload()
+		if diff := cmp.Diff(step.wantErr, err != nil); diff != "" {
+			t.Fatalf("%s: error presence mismatch %v (-want +got):\n%s", step.name, err, diff)
+		}
+		if diff := cmp.Diff(step.wantReload, reloaded); diff != "" {
+			t.Fatalf("%s: reloaded mismatch (-want +got):\n%s", step.name, diff)
+		}
+		if step.update != nil {
+			step.update(want)
+			select {
+			case <-listChanged:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s: no prompts/list_changed notification", step.name)
+			}
+		}
+		if diff := cmp.Diff(want, listPrompts(t, cs)); diff != "" {
+			t.Fatalf("%s: prompts mismatch (-want +got):\n%s", step.name, diff)
+		}
+
+		reloaded, err = lib.reload()
+		if err != nil || reloaded {
+			t.Fatalf("%s: reload of unchanged files: reloaded %v, error %v", step.name, reloaded, err)
+		}
+	}
+}
+
+func TestRunInvalidPromptsDir(t *testing.T) {
+	t.Cleanup(restoreDefaultLogger(t))
+
+	dir := t.TempDir()
+	writePromptFile(t, dir, "plan.md", "Plan it.\n")
+	cfg := defaultConfig()
+	cfg.Prompts.Dir = dir
+
+	err := run(cfg)
+	if diff := cmp.Diff(true, err != nil && strings.Contains(err.Error(), `prompt file "plan.md"`)); diff != "" {
+		t.Fatalf("unexpected error %v (-want +got):\n%s", err, diff)
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultInstructions returns the server instructions used when none are configured, for the
+// sequential thinking tool named tool.
+func defaultInstructions(tool string) string {
+	return fmt.Sprintf(`This server records step-by-step reasoning. Call %[1]s once per thought to work through problems that need several steps, planning, or course correction: estimate totalThoughts up front and adjust it as you go, revise earlier thoughts that turn out wrong, and branch to explore alternatives side by side. Set nextThoughtNeeded to false only once you reached a conclusion you can verify.
+
+The query tools read back the thoughts of the session, so the reasoning survives context compaction. The prompts of this server start common workflows, such as debugging a failure or comparing alternatives, with %[1]s.`, tool)
+}
+
+// loadInstructions returns the server instructions configured by o, or "" for the default instructions.
+func loadInstructions(o PromptsOptions) (string, error) {
+	if o.InstructionsFile == "" {
+		return o.Instructions, nil
+	}
+	if o.Instructions != "" {
+		return "", errors.New("-instructions and -instructions-file are mutually exclusive")
+	}
+	data, err := os.ReadFile(o.InstructionsFile)
+	if err != nil {
+		return "", fmt.Errorf("-instructions-file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// promptData is the data the prompt templates are executed with.
+type promptData struct {
+	// Tool is the name of the sequential thinking tool.
+	Tool string
+	// Args holds the prompt arguments by name.
+	Args map[string]string
+}
+
+// promptTemplate is a prompt whose user message is rendered by a text/template.
+type promptTemplate struct {
+	prompt *mcp.Prompt
+	tmpl   *template.Template
+	// source is the text the prompt was defined by, compared to tell whether a reloaded prompt changed.
+	source string
+}
+
+// newPromptTemplate returns the prompt p rendered by the template text.
+// Missing optional arguments are empty strings in the template.
+func newPromptTemplate(p *mcp.Prompt, text string) (*promptTemplate, error) {
+	tmpl, err := template.New(p.Name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse prompt %q: %w", p.Name, err)
+	}
+	return &promptTemplate{prompt: p, tmpl: tmpl, source: text}, nil
+}
+
+// render returns the message of the prompt for args and the sequential thinking tool named tool.
+func (pt *promptTemplate) render(tool string, args map[string]string) (string, error) {
+	for _, arg := range pt.prompt.Arguments {
+		if arg.Required && strings.TrimSpace(args[arg.Name]) == "" {
+			return "", &jsonrpc.Error{
+				Code:    jsonrpc.CodeInvalidParams,
+				Message: fmt.Sprintf("prompt %q: missing required argument %q", pt.prompt.Name, arg.Name),
+			}
+		}
+	}
+
+	var b strings.Builder
+	if err := pt.tmpl.Execute(&b, promptData{Tool: tool, Args: args}); err != nil {
+		return "", fmt.Errorf("render prompt %q: %w", pt.prompt.Name, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// handler returns the prompts/get handler of the prompt for the sequential thinking tool named tool.
+func (pt *promptTemplate) handler(tool string) mcp.PromptHandler {
+	return func(_ context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		text, err := pt.render(tool, req.Params.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.GetPromptResult{
+			Description: pt.prompt.Description,
+			Messages: []*mcp.PromptMessage{
+				{
+					Role:    "user",
+					Content: &mcp.TextContent{Text: text},
+				},
+			},
+		}, nil
+	}
+}
+
+// builtinPrompts lists the prompts of the structured reasoning workflows, with their templates.
+var builtinPrompts = []struct {
+	prompt *mcp.Prompt
+	text   string
+}{
+	{
+		prompt: &mcp.Prompt{
+			Name:        "debug-a-failure",
+			Title:       "Debug a failure",
+			Description: "Find the cause of a failing test, crash or wrong behavior by testing one hypothesis per branch",
+			Arguments: []*mcp.PromptArgument{
+				{Name: "failure", Title: "Failure", Description: "The failure: error message, failing test or unexpected behavior", Required: true},
+				{Name: "context", Title: "Context", Description: "Relevant code, logs, environment or recent changes"},
+			},
+		},
+		text: `Debug the following failure with the {{.Tool}} tool, recording one thought per call.
+
+Failure:
+{{.Args.failure}}
+{{with .Args.context}}
+Context:
+{{.}}
+{{end}}
+Work through it as follows:
+1. In the first thought, state the observed and the expected behavior, and estimate totalThoughts.
+2. List the plausible causes. Explore each one in its own branch: set branchFromThought to the thought that listed them, and a short branchId naming the cause.
+3. For each cause, name the evidence that would confirm or rule it out, and how to get it.
+4. When evidence contradicts an earlier thought, record a revision with isRevision and revisesThought instead of carrying on.
+5. If the failure runs deeper than estimated, raise totalThoughts and set needsMoreThoughts.
+6. Set nextThoughtNeeded to false only once a single cause explains every symptom.
+
+Then answer with the cause, the evidence for it, the fix, and a test that would have caught the failure.`,
+	},
+	{
+		prompt: &mcp.Prompt{
+			Name:        "design-review",
+			Title:       "Design review",
+			Description: "Review a design against its requirements, one concern at a time, and conclude with ranked findings",
+			Arguments: []*mcp.PromptArgument{
+				{Name: "design", Title: "Design", Description: "The design, proposal or code to review", Required: true},
+				{Name: "requirements", Title: "Requirements", Description: "Requirements and constraints the design must meet"},
+				{Name: "focus", Title: "Focus", Description: "Concerns to focus on, such as security, performance or operability"},
+			},
+		},
+		text: `Review the following design with the {{.Tool}} tool, recording one thought per call.
+
+Design:
+{{.Args.design}}
+{{with .Args.requirements}}
+Requirements:
+{{.}}
+{{end}}{{with .Args.focus}}
+Focus on: {{.}}
+{{end}}
+Work through it as follows:
+1. In the first thought, summarize the design in your own words and list the concerns to review, then estimate totalThoughts from them.
+2. Give each concern its own thought: correctness, failure modes, security, performance, operability and simplicity, as far as they apply.
+3. Check every requirement against the design, and record which ones it does not meet.
+4. When a later concern changes your view of an earlier one, record a revision with isRevision and revisesThought.
+5. For a finding with more than one fix, branch with branchFromThought and a branchId per fix, and weigh them.
+6. Set nextThoughtNeeded to false once every concern and requirement is covered.
+
+Then answer with the findings ranked by severity, each with its impact and a recommended change, and what the design does well.`,
+	},
+	{
+		prompt: &mcp.Prompt{
+			Name:        "root-cause-analysis",
+			Title:       "Root cause analysis",
+			Description: "Trace an incident from its symptoms to its root cause and the contributing factors",
+			Arguments: []*mcp.PromptArgument{
+				{Name: "incident", Title: "Incident", Description: "What happened and how it was noticed", Required: true},
+				{Name: "timeline", Title: "Timeline", Description: "Timeline of the events, changes and actions taken"},
+				{Name: "impact", Title: "Impact", Description: "Who and what was affected, and for how long"},
+			},
+		},
+		text: `Analyze the root cause of the following incident with the {{.Tool}} tool, recording one thought per call.
+
+Incident:
+{{.Args.incident}}
+{{with .Args.timeline}}
+Timeline:
+{{.}}
+{{end}}{{with .Args.impact}}
+Impact:
+{{.}}
+{{end}}
+Work through it as follows:
+1. In the first thought, state the symptoms and their timing, and estimate totalThoughts.
+2. Ask why each symptom happened, one level per thought, until you reach causes that are conditions of the system or the process rather than events.
+3. When more than one cause could explain a level, branch with branchFromThought and a branchId per cause, and keep the branches that the evidence supports.
+4. Separate the root cause from the contributing factors and from what only made detection or recovery slower.
+5. When the timeline contradicts an earlier thought, record a revision with isRevision and revisesThought.
+6. Set nextThoughtNeeded to false once removing the root cause would have prevented the incident.
+
+Then answer with the root cause, the contributing factors, the chain of events linking them to the symptoms, and actions to prevent, detect and mitigate a recurrence.`,
+	},
+	{
+		prompt: &mcp.Prompt{
+			Name:        "compare-alternatives",
+			Title:       "Compare alternatives",
+			Description: "Evaluate alternatives against weighted criteria in parallel branches and recommend one",
+			Arguments: []*mcp.PromptArgument{
+				{Name: "decision", Title: "Decision", Description: "The decision to make", Required: true},
+				{Name: "alternatives", Title: "Alternatives", Description: "The alternatives to compare, one per line or comma separated", Required: true},
+				{Name: "criteria", Title: "Criteria", Description: "Criteria to weigh, most important first"},
+			},
+		},
+		text: `Compare the alternatives for the following decision with the {{.Tool}} tool, recording one thought per call.
+
+Decision:
+{{.Args.decision}}
+
+Alternatives:
+{{.Args.alternatives}}
+{{with .Args.criteria}}
+Criteria:
+{{.}}
+{{end}}
+Work through it as follows:
+1. In the first thought, restate the decision and fix the criteria and their weights, adding any the decision obviously needs, then estimate totalThoughts.
+2. Evaluate each alternative in its own branch: set branchFromThought to the first thought and branchId to a short name of the alternative.
+3. Within a branch, score the alternative against every criterion with the reasons, and note its risks and what would make it fail.
+4. When a later branch shows that an earlier score was unfair, record a revision with isRevision and revisesThought.
+5. Compare the branches on the main line, including combinations of alternatives if one stands out.
+6. Set nextThoughtNeeded to false once one alternative is the best on the weighted criteria, or the decision depends on a fact you name.
+
+Then answer with a table of the alternatives and their scores, the recommendation, and the conditions under which another alternative would be better.`,
+	},
+}
+
+// builtinPromptTemplates returns the built-in prompts by name.
+func builtinPromptTemplates() (map[string]*promptTemplate, error) {
+	prompts := make(map[string]*promptTemplate, len(builtinPrompts))
+	for _, p := range builtinPrompts {
+		pt, err := newPromptTemplate(p.prompt, p.text)
+		if err != nil {
+			return nil, err
+		}
+		prompts[p.prompt.Name] = pt
+	}
+	return prompts, nil
+}
+
+// applyPrompts updates the prompts of srv from prev to next, driving the sequential thinking tool named tool.
+// It adds the prompts of next that are new or changed since prev, and removes the prompts of prev missing
+// from next. The server notifies its clients that the prompt list changed.
+func applyPrompts(srv *mcp.Server, tool string, prev, next map[string]*promptTemplate) {
+	var removed []string
+	for name := range prev {
+		if _, ok := next[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	if len(removed) > 0 {
+		srv.RemovePrompts(removed...)
+	}
+	for name, pt := range next {
+		if old, ok := prev[name]; ok && old.source == pt.source {
+			continue
+		}
+		srv.AddPrompt(pt.prompt, pt.handler(tool))
+	}
+}
+
+// registerPrompts adds the built-in prompts to srv, driving the sequential thinking tool named tool.
+func registerPrompts(srv *mcp.Server, tool string) error {
+	prompts, err := builtinPromptTemplates()
+	if err != nil {
+		return err
+	}
+	applyPrompts(srv, tool, nil, prompts)
+	return nil
+}
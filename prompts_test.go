@@ -0,0 +1,230 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// builtinPrompt returns the template of the built-in prompt name.
+func builtinPrompt(t *testing.T, name string) *promptTemplate {
+	t.Helper()
+
+	for _, p := range builtinPrompts {
+		if p.prompt.Name != name {
+			continue
+		}
+		pt, err := newPromptTemplate(p.prompt, p.text)
+		if err != nil {
+			t.Fatalf("new prompt template: %v", err)
+		}
+		return pt
+	}
+	t.Fatalf("prompt %q not found", name)
+	return nil
+}
+
+func TestPromptTemplateRender(t *testing.T) {
+	tests := map[string]struct {
+		prompt   string
+		args     map[string]string
+		golden   string
+		wantCode int64
+	}{
+		"success: debug a failure": {
+			prompt: "debug-a-failure",
+			args: map[string]string{
+				"failure": "TestReplay fails with \"unexpected EOF\" since the journal format change",
+				"context": "The journal now writes a header line before the thoughts.",
+			},
+			golden: "prompt_debug_a_failure",
+		},
+		"success: design review without optional arguments": {
+			prompt: "design-review",
+			args:   map[string]string{"design": "Store sessions in a single SQLite database instead of per-session journals."},
+			golden: "prompt_design_review",
+		},
+		"success: root cause analysis": {
+			prompt: "root-cause-analysis",
+			args: map[string]string{
+				"incident": "The API returned 503 for 40 minutes.",
+				"timeline": "09:10 deploy\n09:12 error rate rises\n09:50 rollback",
+				"impact":   "All clients in eu-west.",
+			},
+			golden: "prompt_root_cause_analysis",
+		},
+		"success: compare alternatives": {
+			prompt: "compare-alternatives",
+			args: map[string]string{
+				"decision":     "Which queue to use for thought events",
+				"alternatives": "NATS, Kafka, Redis streams",
+				"criteria":     "operational cost, ordering, throughput",
+			},
+			golden: "prompt_compare_alternatives",
+		},
+		"error: missing required argument": {
+			prompt:   "compare-alternatives",
+			args:     map[string]string{"decision": "Which queue to use", "alternatives": "  "},
+			wantCode: jsonrpc.CodeInvalidParams,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := builtinPrompt(t, tt.prompt).render("sequentialthinking", tt.args)
+			var rpcErr *jsonrpc.Error
+			if errors.As(err, &rpcErr) {
+				if diff := cmp.Diff(tt.wantCode, rpcErr.Code); diff != "" {
+					t.Fatalf("error code mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("render prompt: %v", err)
+			}
+			if tt.wantCode != 0 {
+				t.Fatalf("render prompt: want error code %d, got none", tt.wantCode)
+			}
+			checkGolden(t, tt.golden, got)
+		})
+	}
+}
+
+func TestLoadInstructions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instructions.md")
+	if err := os.WriteFile(path, []byte("\nThink before you answer.\n"), 0o600); err != nil {
+		t.Fatalf("write instructions: %v", err)
+	}
+
+	tests := map[string]struct {
+		opts    PromptsOptions
+		want    string
+		wantErr bool
+	}{
+		"success: default": {},
+		"success: inline": {
+			opts: PromptsOptions{Instructions: "Think step by step."},
+			want: "Think step by step.",
+		},
+		"success: file": {
+			opts: PromptsOptions{InstructionsFile: path},
+			want: "Think before you answer.",
+		},
+		"error: inline and file": {
+			opts:    PromptsOptions{Instructions: "Think step by step.", InstructionsFile: path},
+			wantErr: true,
+		},
+		"error: missing file": {
+			opts:    PromptsOptions{InstructionsFile: filepath.Join(t.TempDir(), "missing.md")},
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := loadInstructions(tt.opts)
+			if diff := cmp.Diff(tt.wantErr, err != nil); diff != "" {
+				t.Fatalf("error presence mismatch %v (-want +got):\n%s", err, diff)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("instructions mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestServerPrompts(t *testing.T) {
+	tests := map[string]struct {
+		opts             serverOptions
+		wantInstructions string
+	}{
+		"success: default instructions": {
+			wantInstructions: defaultInstructions("sequentialthinking"),
+		},
+		"success: configured instructions": {
+			opts:             serverOptions{instructions: "Think step by step."},
+			wantInstructions: "Think step by step.",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			srv, err := newServer(slog.New(slog.DiscardHandler), NewSequentialThinkingServer(), tt.opts)
+			if err != nil {
+				t.Fatalf("new server: %v", err)
+			}
+			serverTransport, clientTransport := mcp.NewInMemoryTransports()
+			ss, err := srv.Connect(t.Context(), serverTransport, nil)
+			if err != nil {
+				t.Fatalf("connect server: %v", err)
+			}
+			t.Cleanup(func() { ss.Close() })
+			client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "v0.0.0"}, nil)
+			cs, err := client.Connect(t.Context(), clientTransport, nil)
+			if err != nil {
+				t.Fatalf("connect client: %v", err)
+			}
+			t.Cleanup(func() { cs.Close() })
+
+			if diff := cmp.Diff(tt.wantInstructions, cs.InitializeResult().Instructions); diff != "" {
+				t.Fatalf("instructions mismatch (-want +got):\n%s", diff)
+			}
+
+			prompts, err := cs.ListPrompts(t.Context(), nil)
+			if err != nil {
+				t.Fatalf("list prompts: %v", err)
+			}
+			var names []string
+			for _, p := range prompts.Prompts {
+				names = append(names, p.Name)
+			}
+			if diff := cmp.Diff([]string{"compare-alternatives", "debug-a-failure", "design-review", "root-cause-analysis"}, names); diff != "" {
+				t.Fatalf("prompts mismatch (-want +got):\n%s", diff)
+			}
+
+			result, err := cs.GetPrompt(t.Context(), &mcp.GetPromptParams{
+				Name:      "debug-a-failure",
+				Arguments: map[string]string{"failure": "TestReplay fails"},
+			})
+			if err != nil {
+				t.Fatalf("get prompt: %v", err)
+			}
+			if diff := cmp.Diff(1, len(result.Messages)); diff != "" {
+				t.Fatalf("messages mismatch (-want +got):\n%s", diff)
+			}
+			want, err := builtinPrompt(t, "debug-a-failure").render("sequentialthinking", map[string]string{"failure": "TestReplay fails"})
+			if err != nil {
+				t.Fatalf("render prompt: %v", err)
+			}
+			if diff := cmp.Diff(&mcp.PromptMessage{Role: "user", Content: &mcp.TextContent{Text: want}}, result.Messages[0]); diff != "" {
+				t.Fatalf("message mismatch (-want +got):\n%s", diff)
+			}
+
+			if _, err := cs.GetPrompt(t.Context(), &mcp.GetPromptParams{Name: "debug-a-failure"}); err == nil {
+				t.Fatal("get prompt without required argument: want error, got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// GetThoughtInput is the input of the get_thought tool.
+type GetThoughtInput struct {
+	ThoughtNumber int    `json:"thoughtNumber" jsonschema:"Number of the thought to read"`
+	BranchID      string `json:"branchId,omitzero" jsonschema:"If set, read the thought from this branch instead of the main line"`
+}
+
+// GetThoughtOutput is the output of the get_thought tool.
+type GetThoughtOutput struct {
+	Thought   ThoughtRecord `json:"thought" jsonschema:"The latest thought recorded with the number"`
+	RevisedBy []int         `json:"revisedBy,omitzero" jsonschema:"Numbers of the thoughts of the same line that revise this thought"`
+}
+
+// ListThoughtsInput is the input of the list_thoughts tool.
+type ListThoughtsInput struct {
+	FromThought int    `json:"fromThought,omitzero" jsonschema:"If set, only list thoughts numbered at least this"`
+	ToThought   int    `json:"toThought,omitzero" jsonschema:"If set, only list thoughts numbered at most this"`
+	BranchID    string `json:"branchId,omitzero" jsonschema:"If set, only list the thoughts of this branch"`
+}
+
+// ListThoughtsOutput is the output of the list_thoughts tool.
+type ListThoughtsOutput struct {
+	Thoughts []ThoughtRecord `json:"thoughts" jsonschema:"Matching thoughts in the order they were recorded"`
+}
+
+// ListBranchesInput is the input of the list_branches tool.
+type ListBranchesInput struct{}
+
+// ListBranchesOutput is the output of the list_branches tool.
+type ListBranchesOutput struct {
+	Branches []BranchInfo `json:"branches" jsonschema:"Branches of the session sorted by ID"`
+}
+
+// GetSessionSummaryInput is the input of the get_session_summary tool.
+type GetSessionSummaryInput struct{}
+
+// GetSessionSummaryOutput is the output of the get_session_summary tool.
+type GetSessionSummaryOutput struct {
+	SessionID         string       `json:"sessionId" jsonschema:"Identifier of the thinking session"`
+	CreatedAt         time.Time    `json:"createdAt" jsonschema:"When the session was created"`
+	ThoughtCount      int          `json:"thoughtCount" jsonschema:"Number of recorded thoughts"`
+	RevisionCount     int          `json:"revisionCount" jsonschema:"Number of recorded thoughts that are revisions"`
+	LastThoughtNumber int          `json:"lastThoughtNumber" jsonschema:"Number of the most recent thought"`
+	TotalThoughts     int          `json:"totalThoughts" jsonschema:"Most recent estimate of the total thoughts"`
+	NextThoughtNeeded bool         `json:"nextThoughtNeeded" jsonschema:"Whether the most recent thought asked for another one"`
+	Branches          []BranchInfo `json:"branches" jsonschema:"Branches of the session sorted by ID"`
+}
+
+// readOnlyAnnotations are the annotations of the tools that only read the thinking history.
+func readOnlyAnnotations() *mcp.ToolAnnotations {
+	return &mcp.ToolAnnotations{
+		ReadOnlyHint:   true,
+		IdempotentHint: true,
+		OpenWorldHint:  new(false),
+	}
+}
+
+// registerQueryTools adds the tools that read back the thinking history of the caller's session to srv.
+// toolName is the name of the sequential thinking tool their descriptions refer to.
+func registerQueryTools(srv *mcp.Server, thinking *SequentialThinkingServer, toolName string) error {
+	getThoughtTool, err := newQueryTool[GetThoughtInput, GetThoughtOutput]("get_thought",
+		fmt.Sprintf("Read back a thought recorded with %s in this session, with the numbers of the thoughts that revise it. Use it to recover earlier steps of a long reasoning chain.", toolName),
+		"thoughtNumber")
+	if err != nil {
+		return err
+	}
+	mcp.AddTool(srv, getThoughtTool, thinking.GetThought)
+
+	listThoughtsTool, err := newQueryTool[ListThoughtsInput, ListThoughtsOutput]("list_thoughts",
+		fmt.Sprintf("List the thoughts recorded with %s in this session, optionally restricted to a range of thought numbers or to a branch.", toolName),
+		"fromThought", "toThought")
+	if err != nil {
+		return err
+	}
+	mcp.AddTool(srv, listThoughtsTool, thinking.ListThoughts)
+
+	listBranchesTool, err := newQueryTool[ListBranchesInput, ListBranchesOutput]("list_branches",
+		fmt.Sprintf("List the branches opened with %s in this session, with the thought each was forked from and its length.", toolName))
+	if err != nil {
+		return err
+	}
+	mcp.AddTool(srv, listBranchesTool, thinking.ListBranches)
+
+	getSessionSummaryTool, err := newQueryTool[GetSessionSummaryInput, GetSessionSummaryOutput]("get_session_summary",
+		"Summarize the sequential thinking of this session: thought and revision counts, the most recent thought number and estimate, and the branches.")
+	if err != nil {
+		return err
+	}
+	mcp.AddTool(srv, getSessionSummaryTool, thinking.GetSessionSummary)
+
+	return nil
+}
+
+// newQueryTool returns a read-only tool with the schemas generated from In and Out.
+// The thought number properties of In listed in positive must be >= 1.
+func newQueryTool[In, Out any](name, description string, positive ...string) (*mcp.Tool, error) {
+	inputSchema, err := jsonschema.For[In](&jsonschema.ForOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("parse %s input: %w", name, err)
+	}
+	for _, prop := range positive {
+		inputSchema.Properties[prop].Minimum = new(float64(1))
+	}
+	outputSchema, err := jsonschema.For[Out](&jsonschema.ForOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("parse %s output: %w", name, err)
+	}
+
+	return &mcp.Tool{
+		Name:         name,
+		Annotations:  readOnlyAnnotations(),
+		Description:  description,
+		InputSchema:  inputSchema,
+		OutputSchema: outputSchema,
+	}, nil
+}
+
+// GetThought returns the latest thought recorded with the requested number on the main line, or on the
+// requested branch, of the caller's session, with the revisions of it recorded on the same line.
+func (s *SequentialThinkingServer) GetThought(ctx context.Context, request *mcp.CallToolRequest, input GetThoughtInput) (*mcp.CallToolResult, any, error) {
+	snap, ok, terr := s.callerSnapshot(request)
+	if terr != nil {
+		result, err := toolErrorResult(terr)
+		return result, nil, err
+	}
+	if !ok {
+		result, err := toolErrorResult(newToolError(reasonThoughtNotFound, "thought %d has not been recorded", input.ThoughtNumber))
+		return result, nil, err
+	}
+
+	thoughts, line := snap.MainLine(), "the main line"
+	if input.BranchID != "" {
+		_, branchThoughts, ok := snap.Branch(input.BranchID)
+		if !ok {
+			result, err := toolErrorResult(newToolError(reasonBranchNotFound, "invalid branchId: branch %q does not exist", input.BranchID))
+			return result, nil, err
+		}
+		thoughts, line = branchThoughts, fmt.Sprintf("branch %q", input.BranchID)
+	}
+
+	output := GetThoughtOutput{}
+	found := false
+	for _, record := range thoughts {
+		if record.ThoughtNumber == input.ThoughtNumber {
+			output.Thought = record
+			found = true
+		}
+	}
+	if !found {
+		result, err := toolErrorResult(newToolError(reasonThoughtNotFound, "thought %d has not been recorded on %s", input.ThoughtNumber, line))
+		return result, nil, err
+	}
+	for _, record := range thoughts {
+		if record.IsRevision && record.RevisesThought == input.ThoughtNumber {
+			output.RevisedBy = append(output.RevisedBy, record.ThoughtNumber)
+		}
+	}
+
+	return toolResult(output)
+}
+
+// ListThoughts returns the thoughts of the caller's session matching the requested range and branch.
+func (s *SequentialThinkingServer) ListThoughts(ctx context.Context, request *mcp.CallToolRequest, input ListThoughtsInput) (*mcp.CallToolResult, any, error) {
+	if input.FromThought > 0 && input.ToThought > 0 && input.FromThought > input.ToThought {
+		result, err := toolErrorResult(newToolError(reasonInvalidRange, "invalid range: fromThought %d is greater than toThought %d", input.FromThought, input.ToThought))
+		return result, nil, err
+	}
+
+	snap, _, terr := s.callerSnapshot(request)
+	if terr != nil {
+		result, err := toolErrorResult(terr)
+		return result, nil, err
+	}
+	thoughts := snap.Thoughts
+	if input.BranchID != "" {
+		_, branchThoughts, ok := snap.Branch(input.BranchID)
+		if !ok {
+			result, err := toolErrorResult(newToolError(reasonBranchNotFound, "invalid branchId: branch %q does not exist", input.BranchID))
+			return result, nil, err
+		}
+		thoughts = branchThoughts
+	}
+
+	output := ListThoughtsOutput{
+		Thoughts: make([]ThoughtRecord, 0, len(thoughts)),
+	}
+	for _, record := range thoughts {
+		if input.FromThought > 0 && record.ThoughtNumber < input.FromThought {
+			continue
+		}
+		if input.ToThought > 0 && record.ThoughtNumber > input.ToThought {
+			continue
+		}
+		output.Thoughts = append(output.Thoughts, record)
+	}
+
+	return toolResult(output)
+}
+
+// ListBranches returns the branches of the caller's session.
+func (s *SequentialThinkingServer) ListBranches(ctx context.Context, request *mcp.CallToolRequest, _ ListBranchesInput) (*mcp.CallToolResult, any, error) {
+	snap, _, terr := s.callerSnapshot(request)
+	if terr != nil {
+		result, err := toolErrorResult(terr)
+		return result, nil, err
+	}
+	branches := snap.Branches
+	if branches == nil {
+		branches = make([]BranchInfo, 0)
+	}
+
+	return toolResult(ListBranchesOutput{Branches: branches})
+}
+
+// GetSessionSummary summarizes the caller's session.
+func (s *SequentialThinkingServer) GetSessionSummary(ctx context.Context, request *mcp.CallToolRequest, _ GetSessionSummaryInput) (*mcp.CallToolResult, any, error) {
+	snap, _, terr := s.callerSnapshot(request)
+	if terr != nil {
+		result, err := toolErrorResult(terr)
+		return result, nil, err
+	}
+
+	output := GetSessionSummaryOutput{
+		SessionID:    snap.ID,
+		CreatedAt:    snap.CreatedAt,
+		ThoughtCount: len(snap.Thoughts),
+		Branches:     snap.Branches,
+	}
+	if output.Branches == nil {
+		output.Branches = make([]BranchInfo, 0)
+	}
+	for _, record := range snap.Thoughts {
+		if record.IsRevision {
+			output.RevisionCount++
+		}
+	}
+	if n := len(snap.Thoughts); n > 0 {
+		last := snap.Thoughts[n-1]
+		output.LastThoughtNumber = last.ThoughtNumber
+		output.TotalThoughts = last.TotalThoughts
+		output.NextThoughtNeeded = last.NextThoughtNeeded
+	}
+
+	return toolResult(output)
+}
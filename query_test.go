@@ -0,0 +1,298 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+
+	"github.com/go-json-experiment/json"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// thoughtTexts returns the text of each record.
+func thoughtTexts(records []ThoughtRecord) []string {
+	texts := make([]string, 0, len(records))
+	for _, record := range records {
+		texts = append(texts, record.Thought)
+	}
+	return texts
+}
+
+// callQueryTool calls the named tool and decodes its result into out, or returns the reason of its tool error.
+func callQueryTool(t *testing.T, cs *mcp.ClientSession, name string, args, out any) (reason string) {
+	t.Helper()
+
+	result, err := cs.CallTool(t.Context(), &mcp.CallToolParams{
+		Name:      name,
+		Arguments: args,
+	})
+	if err != nil {
+		t.Fatalf("call tool %s: %v", name, err)
+	}
+	if result.IsError {
+		var terr ToolError
+		if err := json.Unmarshal([]byte(resultText(t, result)), &terr); err != nil {
+			t.Fatalf("decode tool error: %v", err)
+		}
+		return terr.Reason
+	}
+	if diff := cmp.Diff(true, result.StructuredContent != nil); diff != "" {
+		t.Fatalf("structured content presence mismatch (-want +got):\n%s", diff)
+	}
+	if err := json.Unmarshal([]byte(resultText(t, result)), out); err != nil {
+		t.Fatalf("decode %s output: %v", name, err)
+	}
+	return ""
+}
+
+func TestQueryToolsAnnotations(t *testing.T) {
+	cs := connectInMemoryClient(t, NewSequentialThinkingServer())
+
+	tools, err := cs.ListTools(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("list tools: %v", err)
+	}
+	got := make(map[string]bool)
+	for _, tool := range tools.Tools {
+		if tool.Name == "sequentialthinking" {
+			continue
+		}
+		got[tool.Name] = tool.Annotations != nil && tool.Annotations.ReadOnlyHint && tool.OutputSchema != nil
+	}
+	want := map[string]bool{
+		"get_thought":         true,
+		"list_thoughts":       true,
+		"list_branches":       true,
+		"get_session_summary": true,
+		"export_session":      true,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("read-only tools mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSequentialThinkingServerGetThought(t *testing.T) {
+	cs := connectInMemoryClient(t, NewSequentialThinkingServer())
+	recordThoughts(t, cs, resourceThoughts...)
+
+	tests := map[string]struct {
+		input         GetThoughtInput
+		wantThought   string
+		wantRevisedBy []int
+		wantReason    string
+	}{
+		"success: revised thought": {
+			input:         GetThoughtInput{ThoughtNumber: 1},
+			wantThought:   "frame the problem",
+			wantRevisedBy: []int{3},
+		},
+		"success: thought in branch": {
+			input:       GetThoughtInput{ThoughtNumber: 2, BranchID: "alt/1"},
+			wantThought: "try another angle",
+		},
+		"error: thought not recorded": {
+			input:      GetThoughtInput{ThoughtNumber: 9},
+			wantReason: reasonThoughtNotFound,
+		},
+		"error: branch thought without branch": {
+			input:      GetThoughtInput{ThoughtNumber: 2},
+			wantReason: reasonThoughtNotFound,
+		},
+		"error: thought not in branch": {
+			input:      GetThoughtInput{ThoughtNumber: 1, BranchID: "alt/1"},
+			wantReason: reasonThoughtNotFound,
+		},
+		"error: unknown branch": {
+			input:      GetThoughtInput{ThoughtNumber: 1, BranchID: "missing"},
+			wantReason: reasonBranchNotFound,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got GetThoughtOutput
+			reason := callQueryTool(t, cs, "get_thought", tt.input, &got)
+			if diff := cmp.Diff(tt.wantReason, reason); diff != "" {
+				t.Fatalf("reason mismatch (-want +got):\n%s", diff)
+			}
+			if reason != "" {
+				return
+			}
+			if diff := cmp.Diff(tt.wantThought, got.Thought.Thought); diff != "" {
+				t.Fatalf("thought mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantRevisedBy, got.RevisedBy); diff != "" {
+				t.Fatalf("revisedBy mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSequentialThinkingServerGetThoughtLines(t *testing.T) {
+	cs := connectInMemoryClient(t, NewSequentialThinkingServer())
+	recordThoughts(t, cs,
+		ThoughtData{Thought: "frame the problem", ThoughtNumber: 1, TotalThoughts: 3, NextThoughtNeeded: true},
+		ThoughtData{Thought: "measure", ThoughtNumber: 2, TotalThoughts: 3, NextThoughtNeeded: true},
+		ThoughtData{Thought: "try another angle", ThoughtNumber: 2, TotalThoughts: 3, NextThoughtNeeded: true, BranchFromThought: 1, BranchID: "alt"},
+		ThoughtData{Thought: "refine the angle", ThoughtNumber: 3, TotalThoughts: 3, IsRevision: true, RevisesThought: 2, BranchID: "alt"},
+	)
+
+	tests := map[string]struct {
+		input         GetThoughtInput
+		wantThought   string
+		wantRevisedBy []int
+	}{
+		"success: main line": {
+			input:       GetThoughtInput{ThoughtNumber: 2},
+			wantThought: "measure",
+		},
+		"success: branch": {
+			input:         GetThoughtInput{ThoughtNumber: 2, BranchID: "alt"},
+			wantThought:   "try another angle",
+			wantRevisedBy: []int{3},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got GetThoughtOutput
+			if reason := callQueryTool(t, cs, "get_thought", tt.input, &got); reason != "" {
+				t.Fatalf("tool error: %s", reason)
+			}
+			if diff := cmp.Diff(tt.wantThought, got.Thought.Thought); diff != "" {
+				t.Fatalf("thought mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantRevisedBy, got.RevisedBy); diff != "" {
+				t.Fatalf("revisedBy mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSequentialThinkingServerListThoughts(t *testing.T) {
+	cs := connectInMemoryClient(t, NewSequentialThinkingServer())
+	recordThoughts(t, cs, resourceThoughts...)
+
+	tests := map[string]struct {
+		input      ListThoughtsInput
+		want       []string
+		wantReason string
+	}{
+		"success: all": {
+			input: ListThoughtsInput{},
+			want:  []string{"frame the problem", "try another angle", "reframe the problem"},
+		},
+		"success: range": {
+			input: ListThoughtsInput{FromThought: 2, ToThought: 3},
+			want:  []string{"try another angle", "reframe the problem"},
+		},
+		"success: open range": {
+			input: ListThoughtsInput{ToThought: 1},
+			want:  []string{"frame the problem"},
+		},
+		"success: branch": {
+			input: ListThoughtsInput{BranchID: "alt/1"},
+			want:  []string{"try another angle"},
+		},
+		"success: empty match": {
+			input: ListThoughtsInput{FromThought: 7},
+			want:  []string{},
+		},
+		"error: inverted range": {
+			input:      ListThoughtsInput{FromThought: 3, ToThought: 1},
+			wantReason: reasonInvalidRange,
+		},
+		"error: unknown branch": {
+			input:      ListThoughtsInput{BranchID: "missing"},
+			wantReason: reasonBranchNotFound,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got ListThoughtsOutput
+			reason := callQueryTool(t, cs, "list_thoughts", tt.input, &got)
+			if diff := cmp.Diff(tt.wantReason, reason); diff != "" {
+				t.Fatalf("reason mismatch (-want +got):\n%s", diff)
+			}
+			if reason != "" {
+				return
+			}
+			if diff := cmp.Diff(tt.want, thoughtTexts(got.Thoughts)); diff != "" {
+				t.Fatalf("thoughts mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSequentialThinkingServerListBranchesAndSummary(t *testing.T) {
+	tests := map[string]struct {
+		inputs          []ThoughtData
+		wantBranches    []BranchInfo
+		wantSummary     GetSessionSummaryOutput
+		wantCreatedZero bool
+	}{
+		"success: empty session": {
+			wantBranches: []BranchInfo{},
+			wantSummary: GetSessionSummaryOutput{
+				SessionID: defaultSessionID,
+				Branches:  []BranchInfo{},
+			},
+			wantCreatedZero: true,
+		},
+		"success: recorded session": {
+			inputs:       resourceThoughts,
+			wantBranches: []BranchInfo{{ID: "alt/1", FromThought: 1, Length: 1}},
+			wantSummary: GetSessionSummaryOutput{
+				SessionID:         defaultSessionID,
+				ThoughtCount:      3,
+				RevisionCount:     1,
+				LastThoughtNumber: 3,
+				TotalThoughts:     3,
+				Branches:          []BranchInfo{{ID: "alt/1", FromThought: 1, Length: 1}},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cs := connectInMemoryClient(t, NewSequentialThinkingServer())
+			recordThoughts(t, cs, tt.inputs...)
+
+			var branches ListBranchesOutput
+			if reason := callQueryTool(t, cs, "list_branches", map[string]any{}, &branches); reason != "" {
+				t.Fatalf("list_branches tool error: %s", reason)
+			}
+			if diff := cmp.Diff(tt.wantBranches, branches.Branches); diff != "" {
+				t.Fatalf("branches mismatch (-want +got):\n%s", diff)
+			}
+
+			var summary GetSessionSummaryOutput
+			if reason := callQueryTool(t, cs, "get_session_summary", map[string]any{}, &summary); reason != "" {
+				t.Fatalf("get_session_summary tool error: %s", reason)
+			}
+			if diff := cmp.Diff(tt.wantSummary, summary, cmpopts.IgnoreFields(GetSessionSummaryOutput{}, "CreatedAt")); diff != "" {
+				t.Fatalf("summary mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantCreatedZero, summary.CreatedAt.IsZero()); diff != "" {
+				t.Fatalf("created zero mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
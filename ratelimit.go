@@ -0,0 +1,258 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+)
+
+// Scopes of the rate limits of the sequentialthinking tool, reported in the metrics.
+const (
+	rateScopeGlobal    = "global"
+	rateScopeSession   = "session"
+	rateScopePrincipal = "principal"
+)
+
+// minRatePrune is the number of session or principal buckets below which full buckets are not pruned.
+const minRatePrune = 1024
+
+// tokenBucket allows rate calls per second on average, in bursts of up to burst calls.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns a full bucket.
+func newTokenBucket(rate rateLimit, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate.perSecond,
+		burst:  float64(rate.burst),
+		tokens: float64(rate.burst),
+		last:   now,
+	}
+}
+
+// refill adds the tokens accrued since the last refill, up to the burst.
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// wait returns how long until the refilled bucket holds a token, or zero if it holds one now.
+func (b *tokenBucket) wait() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration(math.Ceil((1 - b.tokens) / b.rate * float64(time.Second)))
+}
+
+// full reports whether the refilled bucket holds its whole burst, like a new bucket.
+func (b *tokenBucket) full() bool {
+	return b.tokens >= b.burst
+}
+
+// rateLimit is a token bucket rate. A zero rate is unlimited.
+type rateLimit struct {
+	perSecond float64
+	burst     int
+}
+
+// newRateLimit returns the rate of perSecond calls in bursts of burst, validated for the flags named by flag.
+// A zero burst allows a burst of one second of calls, and at least one call.
+func newRateLimit(flag string, perSecond float64, burst int) (rateLimit, error) {
+	if perSecond < 0 || math.IsNaN(perSecond) || math.IsInf(perSecond, 0) {
+		return rateLimit{}, fmt.Errorf("-%s: must be >= 0, got %g", flag, perSecond)
+	}
+	if burst < 0 {
+		return rateLimit{}, fmt.Errorf("-%s-burst: must be >= 0, got %d", flag, burst)
+	}
+	if burst == 0 {
+		burst = max(1, int(math.Ceil(perSecond)))
+	}
+	return rateLimit{perSecond: perSecond, burst: burst}, nil
+}
+
+// rateLimiter applies the global, per-session and per-principal rate limits of the sequentialthinking tool.
+//
+// The session and principal buckets are created on first use. Full buckets are pruned once their
+// number doubles, as a full bucket is no different from a new one.
+type rateLimiter struct {
+	global    rateLimit
+	session   rateLimit
+	principal rateLimit
+
+	mu           sync.Mutex
+	globalBucket *tokenBucket
+	sessions     map[string]*tokenBucket
+	principals   map[string]*tokenBucket
+	pruneAt      int
+}
+
+// newRateLimiter returns the rate limiter configured by o, or nil if no rate limit is set.
+func newRateLimiter(o RateLimitOptions) (*rateLimiter, error) {
+	global, err := newRateLimit("rate-limit", o.Global, o.GlobalBurst)
+	if err != nil {
+		return nil, err
+	}
+	session, err := newRateLimit("session-rate-limit", o.Session, o.SessionBurst)
+	if err != nil {
+		return nil, err
+	}
+	principal, err := newRateLimit("principal-rate-limit", o.Principal, o.PrincipalBurst)
+	if err != nil {
+		return nil, err
+	}
+	if global.perSecond == 0 && session.perSecond == 0 && principal.perSecond == 0 {
+		return nil, nil
+	}
+
+	return &rateLimiter{
+		global:     global,
+		session:    session,
+		principal:  principal,
+		sessions:   make(map[string]*tokenBucket),
+		principals: make(map[string]*tokenBucket),
+		pruneAt:    minRatePrune,
+	}, nil
+}
+
+// rateBucket is a bucket a call is subject to.
+type rateBucket struct {
+	scope  string
+	key    string
+	limit  rateLimit
+	bucket *tokenBucket
+}
+
+// allow takes a token from every bucket a call of principal in the session id at now is subject to.
+// If one of them is empty, nothing is taken and allow returns the scope of the bucket that refills
+// last, and how long until it does. Unauthenticated calls, with an empty principal, are only subject
+// to the global and session limits.
+func (l *rateLimiter) allow(id, principal string, now time.Time) (scope string, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	buckets := make([]rateBucket, 0, 3)
+	if l.global.perSecond > 0 {
+		if l.globalBucket == nil {
+			l.globalBucket = newTokenBucket(l.global, now)
+		}
+		buckets = append(buckets, rateBucket{rateScopeGlobal, "", l.global, l.globalBucket})
+	}
+	if l.session.perSecond > 0 {
+		buckets = append(buckets, rateBucket{rateScopeSession, id, l.session, l.bucket(l.sessions, id, l.session, now)})
+	}
+	if l.principal.perSecond > 0 && principal != "" {
+		buckets = append(buckets, rateBucket{rateScopePrincipal, principal, l.principal, l.bucket(l.principals, principal, l.principal, now)})
+	}
+
+	for _, b := range buckets {
+		b.bucket.refill(now)
+		if wait := b.bucket.wait(); wait > retryAfter {
+			scope, retryAfter = b.scope, wait
+		}
+	}
+	if retryAfter > 0 {
+		return scope, retryAfter
+	}
+	for _, b := range buckets {
+		b.bucket.tokens--
+	}
+	return "", 0
+}
+
+// bucket returns the bucket of key in buckets, creating it with limit on first use.
+//
+// The caller must hold l.mu.
+func (l *rateLimiter) bucket(buckets map[string]*tokenBucket, key string, limit rateLimit, now time.Time) *tokenBucket {
+	if b, ok := buckets[key]; ok {
+		return b
+	}
+	if len(l.sessions)+len(l.principals) >= l.pruneAt {
+		l.prune(now)
+	}
+	b := newTokenBucket(limit, now)
+	buckets[key] = b
+	return b
+}
+
+// prune removes the session and principal buckets that are full at now.
+//
+// The caller must hold l.mu.
+func (l *rateLimiter) prune(now time.Time) {
+	for _, buckets := range []map[string]*tokenBucket{l.sessions, l.principals} {
+		for key, b := range buckets {
+			b.refill(now)
+			if b.full() {
+				delete(buckets, key)
+			}
+		}
+	}
+	l.pruneAt = max(minRatePrune, 2*(len(l.sessions)+len(l.principals)))
+}
+
+// limit returns the rate limit of scope and its configuration key.
+func (l *rateLimiter) limit(scope string) (rateLimit, string) {
+	switch scope {
+	case rateScopeSession:
+		return l.session, "rateLimit.session"
+	case rateScopePrincipal:
+		return l.principal, "rateLimit.principal"
+	default:
+		return l.global, "rateLimit.global"
+	}
+}
+
+// rateLimitObserver is a [sessionObserver] also notified of the calls rejected by the rate limits.
+type rateLimitObserver interface {
+	// thoughtRateLimited is called when a call in the session id is rejected by the rate limit of scope.
+	thoughtRateLimited(id, scope string)
+}
+
+// checkRate returns the tool error of a call of principal in the session id over a rate limit, or nil.
+func (s *SequentialThinkingServer) checkRate(id, principal string) *ToolError {
+	if s.rateLimits == nil {
+		return nil
+	}
+	scope, retryAfter := s.rateLimits.allow(id, principal, time.Now())
+	if retryAfter == 0 {
+		return nil
+	}
+
+	slog.Debug("rate limited thought", slog.String(logKeySessionID, id), slog.String(logKeyPrincipal, principal),
+		slog.String("scope", scope), slog.Duration("retry_after", retryAfter))
+	for _, o := range s.sessionObservers() {
+		if ro, ok := o.(rateLimitObserver); ok {
+			ro.thoughtRateLimited(id, scope)
+		}
+	}
+
+	// round up, so the call is allowed once the client waited as told
+	retryAfter = (retryAfter + time.Millisecond - 1).Truncate(time.Millisecond)
+	limit, key := s.rateLimits.limit(scope)
+	terr := newToolError(reasonRateLimited, "over the %s rate limit of %g thoughts per second in bursts of %d (%s): retry in %s", scope, limit.perSecond, limit.burst, key, retryAfter)
+	terr.RetryAfterMs = retryAfter.Milliseconds()
+	return terr
+}
@@ -0,0 +1,239 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-json-experiment/json"
+	"github.com/go-json-experiment/json/jsontext"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	tests := map[string]struct {
+		opts    RateLimitOptions
+		want    *rateLimiter
+		wantErr string
+	}{
+		"success: disabled": {},
+		"success: default bursts": {
+			opts: RateLimitOptions{Global: 100, Session: 2.5, Principal: 0.1},
+			want: &rateLimiter{
+				global:    rateLimit{perSecond: 100, burst: 100},
+				session:   rateLimit{perSecond: 2.5, burst: 3},
+				principal: rateLimit{perSecond: 0.1, burst: 1},
+			},
+		},
+		"success: explicit burst": {
+			opts: RateLimitOptions{Session: 5, SessionBurst: 20},
+			want: &rateLimiter{
+				global:    rateLimit{burst: 1},
+				session:   rateLimit{perSecond: 5, burst: 20},
+				principal: rateLimit{burst: 1},
+			},
+		},
+		"error: negative rate": {
+			opts:    RateLimitOptions{Principal: -1},
+			wantErr: "-principal-rate-limit: must be >= 0, got -1",
+		},
+		"error: negative burst": {
+			opts:    RateLimitOptions{Global: 1, GlobalBurst: -2},
+			wantErr: "-rate-limit-burst: must be >= 0, got -2",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := newRateLimiter(tt.opts)
+			if diff := cmp.Diff(tt.wantErr != "", err != nil); diff != "" {
+				t.Fatalf("error presence mismatch %v (-want +got):\n%s", err, diff)
+			}
+			if err != nil {
+				if diff := cmp.Diff(tt.wantErr, err.Error()); diff != "" {
+					t.Fatalf("error text mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if diff := cmp.Diff(tt.want == nil, got == nil); diff != "" {
+				t.Fatalf("limiter nil mismatch (-want +got):\n%s", diff)
+			}
+			if got == nil {
+				return
+			}
+			for _, scope := range []string{rateScopeGlobal, rateScopeSession, rateScopePrincipal} {
+				want, _ := tt.want.limit(scope)
+				limit, _ := got.limit(scope)
+				if diff := cmp.Diff(want, limit, cmp.AllowUnexported(rateLimit{})); diff != "" {
+					t.Fatalf("%s limit mismatch (-want +got):\n%s", scope, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	type call struct {
+		id        string
+		principal string
+		at        time.Duration
+		wantScope string
+		wantRetry time.Duration
+	}
+
+	tests := map[string]struct {
+		opts  RateLimitOptions
+		calls []call
+	}{
+		"success: session burst then refill": {
+			opts: RateLimitOptions{Session: 2, SessionBurst: 2},
+			calls: []call{
+				{id: "a"},
+				{id: "a"},
+				{id: "a", wantScope: rateScopeSession, wantRetry: 500 * time.Millisecond},
+				{id: "b"},
+				{id: "a", at: 250 * time.Millisecond, wantScope: rateScopeSession, wantRetry: 250 * time.Millisecond},
+				{id: "a", at: 500 * time.Millisecond},
+				{id: "a", at: 500 * time.Millisecond, wantScope: rateScopeSession, wantRetry: 500 * time.Millisecond},
+			},
+		},
+		"success: global across sessions": {
+			opts: RateLimitOptions{Global: 1, Session: 10},
+			calls: []call{
+				{id: "a"},
+				{id: "b", wantScope: rateScopeGlobal, wantRetry: time.Second},
+				{id: "b", at: time.Second},
+			},
+		},
+		"success: principal across sessions": {
+			opts: RateLimitOptions{Principal: 1, PrincipalBurst: 2},
+			calls: []call{
+				{id: "a", principal: "alice"},
+				{id: "b", principal: "alice"},
+				{id: "c", principal: "alice", wantScope: rateScopePrincipal, wantRetry: time.Second},
+				{id: "c", principal: "bob"},
+				{id: "d"},
+				{id: "d"},
+			},
+		},
+		"success: rejected call takes no token": {
+			opts: RateLimitOptions{Session: 1, Principal: 1, PrincipalBurst: 2},
+			calls: []call{
+				{id: "a", principal: "alice"},
+				{id: "a", principal: "alice", wantScope: rateScopeSession, wantRetry: time.Second},
+				{id: "b", principal: "alice"},
+				{id: "c", principal: "alice", wantScope: rateScopePrincipal, wantRetry: time.Second},
+			},
+		},
+		"success: longest wait reported": {
+			opts: RateLimitOptions{Global: 1, Session: 0.5},
+			calls: []call{
+				{id: "a"},
+				{id: "a", wantScope: rateScopeSession, wantRetry: 2 * time.Second},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			l, err := newRateLimiter(tt.opts)
+			if err != nil {
+				t.Fatalf("new rate limiter: %v", err)
+			}
+			start := time.Now()
+			for i, c := range tt.calls {
+				scope, retry := l.allow(c.id, c.principal, start.Add(c.at))
+				if diff := cmp.Diff(c.wantScope, scope); diff != "" {
+					t.Fatalf("call %d: scope mismatch (-want +got):\n%s", i, diff)
+				}
+				if diff := cmp.Diff(c.wantRetry, retry); diff != "" {
+					t.Fatalf("call %d: retry after mismatch (-want +got):\n%s", i, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterPrune(t *testing.T) {
+	l, err := newRateLimiter(RateLimitOptions{Session: 1})
+	if err != nil {
+		t.Fatalf("new rate limiter: %v", err)
+	}
+	start := time.Now()
+	for i := range minRatePrune {
+		l.allow(strings.Repeat("s", i+1), "", start)
+	}
+	l.allow("busy", "", start.Add(time.Second))
+	l.allow("busy", "", start.Add(time.Second))
+
+	// the buckets used at start are full again a second later
+	l.allow("new", "", start.Add(time.Second))
+	if diff := cmp.Diff(2, len(l.sessions)); diff != "" {
+		t.Fatalf("session buckets mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSequentialThinkingServerProcessThoughtRateLimit(t *testing.T) {
+	server := NewSequentialThinkingServer()
+	m := newMetrics(server)
+	rateLimits, err := newRateLimiter(RateLimitOptions{Session: 0.001, SessionBurst: 2})
+	if err != nil {
+		t.Fatalf("new rate limiter: %v", err)
+	}
+	server.rateLimits = rateLimits
+
+	input := ThoughtData{Thought: "think", ThoughtNumber: 1, TotalThoughts: 3, NextThoughtNeeded: true}
+	for range 2 {
+		result, _, err := server.ProcessThought(t.Context(), nil, input)
+		if err != nil {
+			t.Fatalf("process thought: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("tool error: %s", resultText(t, result))
+		}
+	}
+
+	result, _, err := server.ProcessThought(t.Context(), nil, input)
+	if err != nil {
+		t.Fatalf("process thought: %v", err)
+	}
+	if diff := cmp.Diff(true, result.IsError); diff != "" {
+		t.Fatalf("result IsError mismatch (-want +got):\n%s", diff)
+	}
+	dec := jsontext.NewDecoder(strings.NewReader(resultText(t, result)))
+	var got ToolError
+	if err := json.UnmarshalDecode(dec, &got); err != nil {
+		t.Fatalf("decode tool error: %v", err)
+	}
+	if diff := cmp.Diff(reasonRateLimited, got.Reason); diff != "" {
+		t.Fatalf("reason mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(true, strings.HasPrefix(got.Message, "over the session rate limit of 0.001 thoughts per second in bursts of 2 (rateLimit.session): retry in 16m")); diff != "" {
+		t.Fatalf("unexpected message %q (-want +got):\n%s", got.Message, diff)
+	}
+	if diff := cmp.Diff(true, got.RetryAfterMs > 999_000 && got.RetryAfterMs <= 1_000_000); diff != "" {
+		t.Fatalf("unexpected retry after %dms (-want +got):\n%s", got.RetryAfterMs, diff)
+	}
+	if diff := cmp.Diff(2, len(server.History(defaultSessionID))); diff != "" {
+		t.Fatalf("history length mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]uint64{rateScopeSession: 1}, m.rateLimited); diff != "" {
+		t.Fatalf("rate limited metrics mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,441 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// URIs of the thinking session resources.
+const (
+	sessionsURI        = "thinking://sessions"
+	sessionURITemplate = sessionsURI + "/{sessionId}"
+	thoughtURITemplate = sessionURITemplate + "/thoughts/{thoughtNumber}"
+	branchURITemplate  = sessionURITemplate + "/branches/{branchId}"
+)
+
+// MIME types of the resource renderings.
+const (
+	mimeTypeJSON     = "application/json"
+	mimeTypeMarkdown = "text/markdown"
+)
+
+// SessionSummary describes a thinking session in the sessions resource.
+type SessionSummary struct {
+	ID           string    `json:"id"`
+	URI          string    `json:"uri"`
+	CreatedAt    time.Time `json:"createdAt"`
+	ThoughtCount int       `json:"thoughtCount"`
+	BranchCount  int       `json:"branchCount"`
+}
+
+// BranchSnapshot is a branch of a thinking session with its thoughts.
+type BranchSnapshot struct {
+	BranchInfo
+	SessionID string          `json:"sessionId"`
+	Thoughts  []ThoughtRecord `json:"thoughts"`
+}
+
+// registerResources adds the read-only thinking session resources backed by thinking to srv.
+//
+// Every live session is also listed as a concrete resource, and changes to the sessions are
+// reported to the subscribers of the affected resources. A client only lists, reads and subscribes
+// to the sessions of its own principal.
+func registerResources(srv *mcp.Server, thinking *SequentialThinkingServer) {
+	srv.AddResource(&mcp.Resource{
+		URI:         sessionsURI,
+		Name:        "sessions",
+		Title:       "Thinking sessions",
+		Description: "Live sequential thinking sessions",
+		MIMEType:    mimeTypeJSON,
+	}, thinking.readResource)
+	srv.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: sessionURITemplate,
+		Name:        "session",
+		Title:       "Thinking session",
+		Description: "Thoughts and branches of a sequential thinking session",
+		MIMEType:    mimeTypeJSON,
+	}, thinking.readResource)
+	srv.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: thoughtURITemplate,
+		Name:        "thought",
+		Title:       "Thought",
+		Description: "The latest thought recorded with the thought number in a sequential thinking session",
+		MIMEType:    mimeTypeJSON,
+	}, thinking.readResource)
+	srv.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: branchURITemplate,
+		Name:        "branch",
+		Title:       "Thinking branch",
+		Description: "Thoughts of a branch of a sequential thinking session",
+		MIMEType:    mimeTypeJSON,
+	}, thinking.readResource)
+	srv.AddReceivingMiddleware(thinking.filterResources)
+
+	n := &resourceNotifier{
+		srv:      srv,
+		thinking: thinking,
+	}
+	thinking.observe(n)
+	for _, id := range thinking.SessionIDs() {
+		n.addSessionResource(id)
+	}
+}
+
+// subscribeResource accepts subscriptions to the thinking session resources,
+// including sessions that do not exist yet, but not to the sessions of another principal.
+func (s *SequentialThinkingServer) subscribeResource(_ context.Context, req *mcp.SubscribeRequest) error {
+	segments, ok := parseSessionsURI(req.Params.URI)
+	if !ok {
+		return mcp.ResourceNotFoundError(req.Params.URI)
+	}
+	if len(segments) > 0 {
+		if _, _, terr := s.principalSession(segments[0], requestPrincipal(req)); terr != nil {
+			return mcp.ResourceNotFoundError(req.Params.URI)
+		}
+	}
+	return nil
+}
+
+// filterResources is a receiving middleware that removes the sessions of other principals
+// from the concrete resources listed to a client.
+func (s *SequentialThinkingServer) filterResources(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		result, err := next(ctx, method, req)
+		listReq, ok := req.(*mcp.ListResourcesRequest)
+		if err != nil || !ok {
+			return result, err
+		}
+		res, ok := result.(*mcp.ListResourcesResult)
+		if !ok {
+			return result, nil
+		}
+
+		principal := requestPrincipal(listReq)
+		visible := res.Resources[:0:0]
+		for _, r := range res.Resources {
+			segments, ok := parseSessionsURI(r.URI)
+			if ok && len(segments) == 1 {
+				if _, ok, _ := s.principalSession(segments[0], principal); !ok {
+					continue
+				}
+			}
+			visible = append(visible, r)
+		}
+		res.Resources = visible
+		return res, nil
+	}
+}
+
+// unsubscribeResource accepts every unsubscription; the subscriptions themselves are tracked by the SDK.
+func unsubscribeResource(context.Context, *mcp.UnsubscribeRequest) error {
+	return nil
+}
+
+// resourceNotifier is a [sessionObserver] that keeps the session resources of srv in sync
+// with thinking and notifies their subscribers.
+type resourceNotifier struct {
+	srv      *mcp.Server
+	thinking *SequentialThinkingServer
+}
+
+var _ sessionObserver = (*resourceNotifier)(nil)
+
+// addSessionResource lists the session id as a concrete resource, which notifies list_changed.
+func (n *resourceNotifier) addSessionResource(id string) {
+	n.srv.AddResource(&mcp.Resource{
+		URI:         sessionURI(id),
+		Name:        id,
+		Title:       "Thinking session " + id,
+		Description: "Thoughts and branches of a sequential thinking session",
+		MIMEType:    mimeTypeJSON,
+	}, n.thinking.readResource)
+}
+
+// sessionOpened implements [sessionObserver].
+func (n *resourceNotifier) sessionOpened(id string) {
+	n.addSessionResource(id)
+	n.updated(sessionsURI)
+}
+
+// sessionClosed implements [sessionObserver].
+func (n *resourceNotifier) sessionClosed(id string) {
+	n.srv.RemoveResources(sessionURI(id))
+	n.updated(sessionsURI, sessionURI(id))
+}
+
+// thoughtAppended implements [sessionObserver].
+func (n *resourceNotifier) thoughtAppended(record ThoughtRecord, _ bool) {
+	uris := []string{
+		sessionsURI,
+		sessionURI(record.SessionID),
+		thoughtURI(record.SessionID, record.ThoughtNumber),
+	}
+	if record.IsRevision {
+		uris = append(uris, thoughtURI(record.SessionID, record.RevisesThought))
+	}
+	if record.BranchID != "" {
+		uris = append(uris, branchURI(record.SessionID, record.BranchID))
+	}
+	n.updated(uris...)
+}
+
+// updated notifies the subscribers of each of uris that the resource changed.
+func (n *resourceNotifier) updated(uris ...string) {
+	for _, uri := range uris {
+		// ResourceUpdated logs delivery failures to individual sessions itself
+		_ = n.srv.ResourceUpdated(context.Background(), &mcp.ResourceUpdatedNotificationParams{URI: uri})
+	}
+}
+
+// sessionURI returns the resource URI of the session id.
+func sessionURI(id string) string {
+	return sessionsURI + "/" + escapeURISegment(id)
+}
+
+// thoughtURI returns the resource URI of thoughtNumber in the session id.
+func thoughtURI(id string, thoughtNumber int) string {
+	return sessionURI(id) + "/thoughts/" + strconv.Itoa(thoughtNumber)
+}
+
+// branchURI returns the resource URI of the branch branchID in the session id.
+func branchURI(id, branchID string) string {
+	return sessionURI(id) + "/branches/" + escapeURISegment(branchID)
+}
+
+// escapeURISegment percent-encodes every byte of s outside the URI unreserved set,
+// so the segment matches a simple string expansion of the resource templates.
+func escapeURISegment(s string) string {
+	const hex = "0123456789ABCDEF"
+
+	var sb strings.Builder
+	for i := range len(s) {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(hex[c>>4])
+			sb.WriteByte(hex[c&0xf])
+		}
+	}
+	return sb.String()
+}
+
+// readResource reads a thinking session resource in JSON and Markdown.
+func (s *SequentialThinkingServer) readResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	segments, ok := parseSessionsURI(uri)
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+
+	if len(segments) == 0 {
+		summaries := s.sessionSummaries(requestPrincipal(req))
+		return renderResource(uri, summaries, renderSessionsMarkdown(summaries))
+	}
+
+	// the sessions of other principals are not found, rather than forbidden, not to reveal their IDs
+	ts, ok, _ := s.principalSession(segments[0], requestPrincipal(req))
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	snap := ts.snapshot()
+
+	switch {
+	case len(segments) == 1:
+		return renderResource(uri, snap, renderSessionMarkdown(snap))
+
+	case len(segments) == 3 && segments[1] == "thoughts":
+		n, err := strconv.Atoi(segments[2])
+		if err != nil {
+			return nil, mcp.ResourceNotFoundError(uri)
+		}
+		record, ok := snap.Thought(n)
+		if !ok {
+			return nil, mcp.ResourceNotFoundError(uri)
+		}
+		return renderResource(uri, record, renderThoughtMarkdown(record))
+
+	case len(segments) == 3 && segments[1] == "branches":
+		info, thoughts, ok := snap.Branch(segments[2])
+		if !ok {
+			return nil, mcp.ResourceNotFoundError(uri)
+		}
+		branch := BranchSnapshot{
+			BranchInfo: info,
+			SessionID:  snap.ID,
+			Thoughts:   thoughts,
+		}
+		return renderResource(uri, branch, renderBranchMarkdown(branch))
+	}
+
+	return nil, mcp.ResourceNotFoundError(uri)
+}
+
+// parseSessionsURI returns the unescaped path segments of uri below [sessionsURI].
+func parseSessionsURI(uri string) ([]string, bool) {
+	rest, ok := strings.CutPrefix(uri, sessionsURI)
+	if !ok {
+		return nil, false
+	}
+	if rest == "" {
+		return nil, true
+	}
+	rest, ok = strings.CutPrefix(rest, "/")
+	if !ok {
+		return nil, false
+	}
+
+	segments := strings.Split(rest, "/")
+	for i, seg := range segments {
+		unescaped, err := url.PathUnescape(seg)
+		if err != nil || unescaped == "" {
+			return nil, false
+		}
+		segments[i] = unescaped
+	}
+	return segments, true
+}
+
+// sessionSummaries returns the summaries of the live sessions of principal sorted by ID.
+func (s *SequentialThinkingServer) sessionSummaries(principal string) []SessionSummary {
+	ids := s.SessionIDs()
+	summaries := make([]SessionSummary, 0, len(ids))
+	for _, id := range ids {
+		ts, ok, _ := s.principalSession(id, principal)
+		if !ok {
+			continue
+		}
+		ts.mu.Lock()
+		summaries = append(summaries, SessionSummary{
+			ID:           ts.id,
+			URI:          sessionURI(ts.id),
+			CreatedAt:    ts.created,
+			ThoughtCount: len(ts.history),
+			BranchCount:  len(ts.branches),
+		})
+		ts.mu.Unlock()
+	}
+	return summaries
+}
+
+// renderResource returns the resource uri rendered as JSON from v and as Markdown.
+func renderResource(uri string, v any, markdown string) (*mcp.ReadResourceResult, error) {
+	data, err := sonic.ConfigStd.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal resource %s: %w", uri, err)
+	}
+
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{
+			{
+				URI:      uri,
+				MIMEType: mimeTypeJSON,
+				Text:     string(data),
+			},
+			{
+				URI:      uri,
+				MIMEType: mimeTypeMarkdown,
+				Text:     markdown,
+			},
+		},
+	}, nil
+}
+
+// renderSessionsMarkdown renders the session summaries as a Markdown table.
+func renderSessionsMarkdown(summaries []SessionSummary) string {
+	var sb strings.Builder
+	sb.WriteString("# Thinking sessions\n\n")
+	if len(summaries) == 0 {
+		sb.WriteString("No sessions.\n")
+		return sb.String()
+	}
+
+	sb.WriteString("| Session | Created | Thoughts | Branches |\n")
+	sb.WriteString("| --- | --- | ---: | ---: |\n")
+	for _, sum := range summaries {
+		fmt.Fprintf(&sb, "| [%s](%s) | %s | %d | %d |\n", sum.ID, sum.URI, sum.CreatedAt.Format(time.RFC3339), sum.ThoughtCount, sum.BranchCount)
+	}
+	return sb.String()
+}
+
+// renderSessionMarkdown renders the thoughts and branches of the session.
+func renderSessionMarkdown(snap SessionSnapshot) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Session %s\n\n", snap.ID)
+	fmt.Fprintf(&sb, "Created %s, %d thoughts, %d branches.\n\n", snap.CreatedAt.Format(time.RFC3339), len(snap.Thoughts), len(snap.Branches))
+	if snap.Principal != "" {
+		fmt.Fprintf(&sb, "Principal: %s\n\n", snap.Principal)
+	}
+
+	sb.WriteString("## Thoughts\n\n")
+	for _, record := range snap.Thoughts {
+		writeThoughtMarkdown(&sb, record)
+	}
+
+	if len(snap.Branches) > 0 {
+		sb.WriteString("## Branches\n\n")
+		for _, br := range snap.Branches {
+			fmt.Fprintf(&sb, "- [%s](%s): from thought %d, %d thoughts\n", br.ID, branchURI(snap.ID, br.ID), br.FromThought, br.Length)
+		}
+	}
+	return sb.String()
+}
+
+// renderThoughtMarkdown renders a single thought.
+func renderThoughtMarkdown(record ThoughtRecord) string {
+	var sb strings.Builder
+	writeThoughtMarkdown(&sb, record)
+	return sb.String()
+}
+
+// renderBranchMarkdown renders the thoughts of the branch.
+func renderBranchMarkdown(branch BranchSnapshot) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Branch %s\n\n", branch.ID)
+	fmt.Fprintf(&sb, "Forked from thought %d of session [%s](%s), %d thoughts.\n\n", branch.FromThought, branch.SessionID, sessionURI(branch.SessionID), branch.Length)
+	for _, record := range branch.Thoughts {
+		writeThoughtMarkdown(&sb, record)
+	}
+	return sb.String()
+}
+
+// writeThoughtMarkdown writes record as a Markdown section with its revision and branch annotations.
+func writeThoughtMarkdown(sb *strings.Builder, record ThoughtRecord) {
+	fmt.Fprintf(sb, "### Thought %d/%d", record.ThoughtNumber, record.TotalThoughts)
+	switch {
+	case record.IsRevision:
+		fmt.Fprintf(sb, " (revises thought %d)", record.RevisesThought)
+	case record.BranchFromThought > 0:
+		fmt.Fprintf(sb, " (branch %s from thought %d)", record.BranchID, record.BranchFromThought)
+	case record.BranchID != "":
+		fmt.Fprintf(sb, " (branch %s)", record.BranchID)
+	}
+	sb.WriteString("\n\n")
+	sb.WriteString(record.Thought)
+	sb.WriteString("\n\n")
+}
@@ -0,0 +1,455 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-json-experiment/json"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// recordThoughts processes inputs in the session of cs, failing the test on any error.
+func recordThoughts(t *testing.T, cs *mcp.ClientSession, inputs ...ThoughtData) {
+	t.Helper()
+
+	for _, input := range inputs {
+		callThought(t, cs, input)
+	}
+}
+
+// readResourceContents reads uri and returns its JSON and Markdown renderings.
+func readResourceContents(t *testing.T, cs *mcp.ClientSession, uri string) (jsonText, markdown string) {
+	t.Helper()
+
+	result, err := cs.ReadResource(t.Context(), &mcp.ReadResourceParams{URI: uri})
+	if err != nil {
+		t.Fatalf("read resource %s: %v", uri, err)
+	}
+	for _, c := range result.Contents {
+		switch c.MIMEType {
+		case mimeTypeJSON:
+			jsonText = c.Text
+		case mimeTypeMarkdown:
+			markdown = c.Text
+		}
+	}
+	if jsonText == "" || markdown == "" {
+		t.Fatalf("resource %s is missing a rendering: %+v", uri, result.Contents)
+	}
+	return jsonText, markdown
+}
+
+var resourceThoughts = []ThoughtData{
+	{Thought: "frame the problem", ThoughtNumber: 1, TotalThoughts: 3, NextThoughtNeeded: true},
+	{Thought: "try another angle", ThoughtNumber: 2, TotalThoughts: 3, NextThoughtNeeded: true, BranchFromThought: 1, BranchID: "alt/1"},
+	{Thought: "reframe the problem", ThoughtNumber: 3, TotalThoughts: 3, IsRevision: true, RevisesThought: 1},
+}
+
+func TestEscapeURISegment(t *testing.T) {
+	tests := map[string]struct {
+		in   string
+		want string
+	}{
+		"success: unreserved": {
+			in:   "0193f0e2-aB_.~",
+			want: "0193f0e2-aB_.~",
+		},
+		"success: reserved and unicode": {
+			in:   "a/b c:é",
+			want: "a%2Fb%20c%3A%C3%A9",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := escapeURISegment(tt.in)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("escaped mismatch (-want +got):\n%s", diff)
+			}
+			segments, ok := parseSessionsURI(sessionURI(tt.in))
+			if !ok {
+				t.Fatalf("parse %q failed", sessionURI(tt.in))
+			}
+			if diff := cmp.Diff([]string{tt.in}, segments); diff != "" {
+				t.Fatalf("round trip mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseSessionsURI(t *testing.T) {
+	tests := map[string]struct {
+		uri    string
+		want   []string
+		wantOK bool
+	}{
+		"success: sessions": {
+			uri:    "thinking://sessions",
+			want:   nil,
+			wantOK: true,
+		},
+		"success: thought": {
+			uri:    "thinking://sessions/s1/thoughts/2",
+			want:   []string{"s1", "thoughts", "2"},
+			wantOK: true,
+		},
+		"error: other scheme": {
+			uri:    "file:///sessions",
+			wantOK: false,
+		},
+		"error: prefix without separator": {
+			uri:    "thinking://sessionsx",
+			wantOK: false,
+		},
+		"error: empty segment": {
+			uri:    "thinking://sessions//thoughts/1",
+			wantOK: false,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := parseSessionsURI(tt.uri)
+			if diff := cmp.Diff(tt.wantOK, ok); diff != "" {
+				t.Fatalf("ok mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("segments mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSequentialThinkingServerResources(t *testing.T) {
+	cs := connectInMemoryClient(t, NewSequentialThinkingServer())
+	recordThoughts(t, cs, resourceThoughts...)
+
+	templates, err := cs.ListResourceTemplates(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("list resource templates: %v", err)
+	}
+	var gotTemplates []string
+	for _, tmpl := range templates.ResourceTemplates {
+		gotTemplates = append(gotTemplates, tmpl.URITemplate)
+	}
+	wantTemplates := []string{sessionURITemplate, branchURITemplate, thoughtURITemplate}
+	if diff := cmp.Diff(wantTemplates, gotTemplates); diff != "" {
+		t.Fatalf("templates mismatch (-want +got):\n%s", diff)
+	}
+
+	tests := map[string]struct {
+		uri          string
+		check        func(t *testing.T, jsonText string)
+		wantMarkdown []string
+	}{
+		"success: sessions": {
+			uri: sessionsURI,
+			check: func(t *testing.T, jsonText string) {
+				var got []SessionSummary
+				if err := json.Unmarshal([]byte(jsonText), &got); err != nil {
+					t.Fatalf("decode sessions: %v", err)
+				}
+				if diff := cmp.Diff(1, len(got)); diff != "" {
+					t.Fatalf("sessions length mismatch (-want +got):\n%s", diff)
+				}
+				if diff := cmp.Diff(SessionSummary{ID: defaultSessionID, URI: sessionURI(defaultSessionID), ThoughtCount: 3, BranchCount: 1}, got[0], cmpopts.IgnoreFields(SessionSummary{}, "CreatedAt")); diff != "" {
+					t.Fatalf("session summary mismatch (-want +got):\n%s", diff)
+				}
+			},
+			wantMarkdown: []string{"# Thinking sessions", "| [default](thinking://sessions/default) |"},
+		},
+		"success: session": {
+			uri: sessionURI(defaultSessionID),
+			check: func(t *testing.T, jsonText string) {
+				var got SessionSnapshot
+				if err := json.Unmarshal([]byte(jsonText), &got); err != nil {
+					t.Fatalf("decode session: %v", err)
+				}
+				if diff := cmp.Diff(3, len(got.Thoughts)); diff != "" {
+					t.Fatalf("thoughts length mismatch (-want +got):\n%s", diff)
+				}
+				if diff := cmp.Diff([]BranchInfo{{ID: "alt/1", FromThought: 1, Length: 1}}, got.Branches); diff != "" {
+					t.Fatalf("branches mismatch (-want +got):\n%s", diff)
+				}
+			},
+			wantMarkdown: []string{"# Session default", "### Thought 3/3 (revises thought 1)", "reframe the problem", "[alt/1](thinking://sessions/default/branches/alt%2F1)"},
+		},
+		"success: thought": {
+			uri: thoughtURI(defaultSessionID, 1),
+			check: func(t *testing.T, jsonText string) {
+				var got ThoughtRecord
+				if err := json.Unmarshal([]byte(jsonText), &got); err != nil {
+					t.Fatalf("decode thought: %v", err)
+				}
+				if diff := cmp.Diff("frame the problem", got.Thought); diff != "" {
+					t.Fatalf("thought mismatch (-want +got):\n%s", diff)
+				}
+			},
+			wantMarkdown: []string{"### Thought 1/3", "frame the problem"},
+		},
+		"success: branch": {
+			uri: branchURI(defaultSessionID, "alt/1"),
+			check: func(t *testing.T, jsonText string) {
+				var got BranchSnapshot
+				if err := json.Unmarshal([]byte(jsonText), &got); err != nil {
+					t.Fatalf("decode branch: %v", err)
+				}
+				if diff := cmp.Diff(BranchInfo{ID: "alt/1", FromThought: 1, Length: 1}, got.BranchInfo); diff != "" {
+					t.Fatalf("branch info mismatch (-want +got):\n%s", diff)
+				}
+				if diff := cmp.Diff(1, len(got.Thoughts)); diff != "" {
+					t.Fatalf("branch thoughts mismatch (-want +got):\n%s", diff)
+				}
+			},
+			wantMarkdown: []string{"# Branch alt/1", "Forked from thought 1", "try another angle"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			jsonText, markdown := readResourceContents(t, cs, tt.uri)
+			tt.check(t, jsonText)
+			for _, want := range tt.wantMarkdown {
+				if diff := cmp.Diff(true, strings.Contains(markdown, want)); diff != "" {
+					t.Fatalf("markdown missing %q (-want +got):\n%s", want, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestSequentialThinkingServerResourcesNotFound(t *testing.T) {
+	cs := connectInMemoryClient(t, NewSequentialThinkingServer())
+	recordThoughts(t, cs, resourceThoughts...)
+
+	tests := map[string]struct {
+		uri string
+	}{
+		"error: unknown session": {
+			uri: sessionURI("missing"),
+		},
+		"error: unknown thought": {
+			uri: thoughtURI(defaultSessionID, 42),
+		},
+		"error: non-numeric thought": {
+			uri: sessionURI(defaultSessionID) + "/thoughts/first",
+		},
+		"error: unknown branch": {
+			uri: branchURI(defaultSessionID, "missing"),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := cs.ReadResource(t.Context(), &mcp.ReadResourceParams{URI: tt.uri})
+			if diff := cmp.Diff(true, err != nil); diff != "" {
+				t.Fatalf("error presence mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// connectObserver connects a second in-memory client to srv that reports resource notifications on the returned channels.
+func connectObserver(t *testing.T, srv *mcp.Server) (cs *mcp.ClientSession, updated <-chan string, listChanged <-chan struct{}) {
+	t.Helper()
+
+	updatedc := make(chan string, 64)
+	listChangedc := make(chan struct{}, 64)
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	ss, err := srv.Connect(t.Context(), serverTransport, nil)
+	if err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+	t.Cleanup(func() { ss.Close() })
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "observer", Version: "v0.0.0"}, &mcp.ClientOptions{
+		ResourceUpdatedHandler: func(_ context.Context, req *mcp.ResourceUpdatedNotificationRequest) {
+			updatedc <- req.Params.URI
+		},
+		ResourceListChangedHandler: func(context.Context, *mcp.ResourceListChangedRequest) {
+			listChangedc <- struct{}{}
+		},
+	})
+	cs, err = client.Connect(t.Context(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("connect client: %v", err)
+	}
+	t.Cleanup(func() { cs.Close() })
+	return cs, updatedc, listChangedc
+}
+
+// receive returns the next value of c, failing the test if none arrives in time.
+func receive[T any](t *testing.T, c <-chan T) T {
+	t.Helper()
+
+	select {
+	case v := <-c:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for notification")
+		panic("unreachable")
+	}
+}
+
+func TestSequentialThinkingServerResourceSubscriptions(t *testing.T) {
+	thinking := NewSequentialThinkingServer()
+	srv, err := newServer(slog.New(slog.DiscardHandler), thinking, serverOptions{})
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	agent, _, _ := connectObserver(t, srv)
+	observer, updated, listChanged := connectObserver(t, srv)
+
+	for _, uri := range []string{
+		sessionsURI,
+		sessionURI(defaultSessionID),
+		thoughtURI(defaultSessionID, 1),
+		branchURI(defaultSessionID, "alt/1"),
+	} {
+		if err := observer.Subscribe(t.Context(), &mcp.SubscribeParams{URI: uri}); err != nil {
+			t.Fatalf("subscribe %s: %v", uri, err)
+		}
+	}
+	if err := observer.Subscribe(t.Context(), &mcp.SubscribeParams{URI: "file:///etc/passwd"}); err == nil {
+		t.Fatal("subscribe to a foreign URI succeeded")
+	}
+
+	steps := []struct {
+		input       ThoughtData
+		wantUpdated []string
+	}{
+		{
+			// creates the session, then appends thought 1
+			input:       resourceThoughts[0],
+			wantUpdated: []string{sessionsURI, sessionsURI, sessionURI(defaultSessionID), thoughtURI(defaultSessionID, 1)},
+		},
+		{
+			input:       resourceThoughts[1],
+			wantUpdated: []string{sessionsURI, sessionURI(defaultSessionID), branchURI(defaultSessionID, "alt/1")},
+		},
+		{
+			input:       resourceThoughts[2],
+			wantUpdated: []string{sessionsURI, sessionURI(defaultSessionID), thoughtURI(defaultSessionID, 1)},
+		},
+	}
+	for i, step := range steps {
+		recordThoughts(t, agent, step.input)
+		var got []string
+		for range step.wantUpdated {
+			got = append(got, receive(t, updated))
+		}
+		if diff := cmp.Diff(step.wantUpdated, got, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+			t.Fatalf("step %d: updated URIs mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+
+	// the new session was listed as a concrete resource
+	receive(t, listChanged)
+	resources, err := observer.ListResources(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("list resources: %v", err)
+	}
+	var gotURIs []string
+	for _, r := range resources.Resources {
+		gotURIs = append(gotURIs, r.URI)
+	}
+	if diff := cmp.Diff([]string{sessionsURI, sessionURI(defaultSessionID)}, gotURIs, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+		t.Fatalf("resources mismatch (-want +got):\n%s", diff)
+	}
+
+	// closing the agent evicts its session
+	agent.Close()
+	receive(t, listChanged)
+	got := []string{receive(t, updated), receive(t, updated)}
+	if diff := cmp.Diff([]string{sessionsURI, sessionURI(defaultSessionID)}, got, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+		t.Fatalf("eviction updated URIs mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSequentialThinkingServerResourcesPrincipals(t *testing.T) {
+	tokensPath := filepath.Join(t.TempDir(), "tokens")
+	if err := os.WriteFile(tokensPath, []byte("alice a-token\nbob b-token\n"), 0o600); err != nil {
+		t.Fatalf("write tokens: %v", err)
+	}
+	ac, err := loadAuthConfig(tokensPath, "", "", "")
+	if err != nil {
+		t.Fatalf("load auth config: %v", err)
+	}
+	thinking := NewSequentialThinkingServer()
+	ts := httptest.NewServer(newAuthHandler(t, thinking, ac))
+	t.Cleanup(ts.Close)
+	connect := func(token string) *mcp.ClientSession {
+		cs, err := connectAuthClient(t, ts.URL, &http.Client{Transport: &headerTransport{
+			base:   http.DefaultTransport,
+			header: http.Header{"Authorization": {"Bearer " + token}},
+		}})
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		return cs
+	}
+	listURIs := func(cs *mcp.ClientSession) []string {
+		resources, err := cs.ListResources(t.Context(), nil)
+		if err != nil {
+			t.Fatalf("list resources: %v", err)
+		}
+		var uris []string
+		for _, r := range resources.Resources {
+			uris = append(uris, r.URI)
+		}
+		return uris
+	}
+
+	alice, bob := connect("a-token"), connect("b-token")
+	recordThoughts(t, alice, resourceThoughts...)
+	aliceSession := sessionURI(alice.ID())
+
+	if diff := cmp.Diff([]string{sessionsURI, aliceSession}, listURIs(alice), cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+		t.Fatalf("alice resources mismatch (-want +got):\n%s", diff)
+	}
+	readResourceContents(t, alice, aliceSession)
+
+	if diff := cmp.Diff([]string{sessionsURI}, listURIs(bob)); diff != "" {
+		t.Fatalf("bob resources mismatch (-want +got):\n%s", diff)
+	}
+	jsonText, markdown := readResourceContents(t, bob, sessionsURI)
+	if diff := cmp.Diff("[]", jsonText); diff != "" {
+		t.Fatalf("bob sessions mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(false, strings.Contains(markdown, alice.ID())); diff != "" {
+		t.Fatalf("alice's session listed to bob in %q (-want +got):\n%s", markdown, diff)
+	}
+	for _, uri := range []string{aliceSession, thoughtURI(alice.ID(), 1), branchURI(alice.ID(), "alt/1")} {
+		if _, err := bob.ReadResource(t.Context(), &mcp.ReadResourceParams{URI: uri}); err == nil {
+			t.Fatalf("bob read %s", uri)
+		}
+		if err := bob.Subscribe(t.Context(), &mcp.SubscribeParams{URI: uri}); err == nil {
+			t.Fatalf("bob subscribed to %s", uri)
+		}
+	}
+}
@@ -18,14 +18,9 @@ package main
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"math"
-	"os"
-	"sort"
-	"strconv"
-	"strings"
 	"sync"
+	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -46,173 +41,180 @@ type ThoughtData struct {
 
 // Output represents the output data for a thought.
 type Output struct {
-	ThoughtNumber        int      `json:"thoughtNumber"`
-	TotalThoughts        int      `json:"totalThoughts"`
-	NextThoughtNeeded    bool     `json:"nextThoughtNeeded"`
-	Branches             []string `json:"branches"`
-	ThoughtHistoryLength int      `json:"thoughtHistoryLength"`
+	ThoughtNumber        int          `json:"thoughtNumber"`
+	TotalThoughts        int          `json:"totalThoughts"`
+	NextThoughtNeeded    bool         `json:"nextThoughtNeeded"`
+	Branches             []BranchInfo `json:"branches"`
+	ThoughtHistoryLength int          `json:"thoughtHistoryLength"`
+	// Numbering is nil when the thought numbers of the client are trusted.
+	Numbering *Numbering `json:"numbering,omitzero"`
+}
+
+// BranchInfo describes a branch of the session.
+type BranchInfo struct {
+	ID          string `json:"id" jsonschema:"Branch identifier"`
+	FromThought int    `json:"fromThought" jsonschema:"Thought number the branch was forked from"`
+	Length      int    `json:"length" jsonschema:"Number of thoughts recorded in the branch"`
+}
+
+// ThoughtRecord is a thought accepted by [SequentialThinkingServer.ProcessThought] and retained in the history.
+type ThoughtRecord struct {
+	Thought           string    `json:"thought"`
+	ThoughtNumber     int       `json:"thoughtNumber"`
+	TotalThoughts     int       `json:"totalThoughts"`
+	NextThoughtNeeded bool      `json:"nextThoughtNeeded"`
+	IsRevision        bool      `json:"isRevision,omitzero"`
+	RevisesThought    int       `json:"revisesThought,omitzero"`
+	BranchFromThought int       `json:"branchFromThought,omitzero"`
+	BranchID          string    `json:"branchId,omitzero"`
+	NeedsMoreThoughts bool      `json:"needsMoreThoughts,omitzero"`
+	SessionID         string    `json:"sessionId,omitzero"`
+	Principal         string    `json:"principal,omitzero"`
+	Timestamp         time.Time `json:"timestamp"`
+}
+
+// newThoughtRecord returns the record of the validated input received in sessionID at now.
+func newThoughtRecord(input ThoughtData, sessionID string, now time.Time) ThoughtRecord {
+	return ThoughtRecord{
+		Thought:           input.Thought,
+		ThoughtNumber:     input.ThoughtNumber,
+		TotalThoughts:     input.TotalThoughts,
+		NextThoughtNeeded: input.NextThoughtNeeded,
+		IsRevision:        input.IsRevision,
+		RevisesThought:    input.RevisesThought,
+		BranchFromThought: input.BranchFromThought,
+		BranchID:          input.BranchID,
+		NeedsMoreThoughts: input.NeedsMoreThoughts,
+		SessionID:         sessionID,
+		Timestamp:         now,
+	}
 }
 
 // SequentialThinkingServer implements the sequential thinking logic.
 type SequentialThinkingServer struct {
-	thoughtHistory       []struct{}
-	branches             map[string]struct{}
-	branchKeys           []string
-	enableThoughtLogging bool
-	mu                   sync.Mutex
+	sessions  map[string]*thinkingSession
+	journal   *journalStore
+	observers []sessionObserver
+	limits    sessionLimits
+	// rateLimits is nil without rate limits.
+	rateLimits *rateLimiter
+	// numbering is the numbering mode of the thoughts, [numberingClient] if empty.
+	numbering string
+	mu        sync.Mutex
 }
 
 // NewSequentialThinkingServer creates a new instance of the server.
 func NewSequentialThinkingServer() *SequentialThinkingServer {
-	enableLogging := false
-	val := os.Getenv("ENABLE_SEQUENTIA_LTHINKING_LOG")
-	if ok, err := strconv.ParseBool(val); err == nil && ok {
-		enableLogging = true
-	}
-
 	return &SequentialThinkingServer{
-		thoughtHistory:       make([]struct{}, 0),
-		branches:             make(map[string]struct{}),
-		enableThoughtLogging: enableLogging,
+		sessions: make(map[string]*thinkingSession),
 	}
 }
 
 // validateThoughtData validates the input thought data.
-func (s *SequentialThinkingServer) validateThoughtData(input ThoughtData) error {
+//
+// References to other thoughts are validated against the session history by [thinkingSession.validateReferences].
+func (s *SequentialThinkingServer) validateThoughtData(input ThoughtData) *ToolError {
 	if input.Thought == "" {
-		return errors.New("invalid thought: must be a string")
+		return newToolError(reasonInvalidThought, "invalid thought: must be a string")
 	}
 	if input.ThoughtNumber <= 0 {
-		return errors.New("invalid thoughtNumber: must be a number > 0")
+		return newToolError(reasonInvalidThoughtNumber, "invalid thoughtNumber: must be a number > 0")
 	}
 	if input.TotalThoughts <= 0 {
-		return errors.New("invalid totalThoughts: must be a number > 0")
+		return newToolError(reasonInvalidTotalThoughts, "invalid totalThoughts: must be a number > 0")
+	}
+	if input.IsRevision && input.RevisesThought <= 0 {
+		return newToolError(reasonRevisionMissingTarget, "invalid revisesThought: must be set when isRevision is true")
+	}
+	if !input.IsRevision && input.RevisesThought > 0 {
+		return newToolError(reasonRevisionWithoutFlag, "invalid revisesThought: isRevision must be true when revisesThought is set")
+	}
+	if input.BranchFromThought > 0 && input.BranchID == "" {
+		return newToolError(reasonBranchMissingID, "invalid branchFromThought: branchId must be set when branching")
 	}
 	return nil
 }
 
-// formatThought formats the thought for logging.
-func (s *SequentialThinkingServer) formatThought(thoughtData ThoughtData) string {
-	// Plain text components
-	prefixText := ""
-	context := ""
-
-	switch {
-	case thoughtData.IsRevision:
-		prefixText = "🔄 Revision"
-		if thoughtData.RevisesThought < 0 {
-			context = fmt.Sprintf(" (revising thought %d)", thoughtData.RevisesThought)
-		}
-
-	case thoughtData.BranchFromThought < 0:
-		prefixText = "🌿 Branch"
-		branchID := ""
-		if thoughtData.BranchID != "" {
-			branchID = thoughtData.BranchID
-		}
-		context = fmt.Sprintf(" (from thought %d, ID: %s)", thoughtData.BranchFromThought, branchID)
-
-	default:
-		prefixText = "💭 Thought"
-		context = ""
-	}
-
-	headerContent := fmt.Sprintf("%s %d/%d%s", prefixText, thoughtData.ThoughtNumber, thoughtData.TotalThoughts, context)
-
-	// Colors
-	const (
-		yellow = `\033[33m`
-		green  = `\033[32m`
-		blue   = `\033[34m`
-		reset  = `\033[0m`
-	)
-
-	coloredPrefix := ""
-	switch {
-	case thoughtData.IsRevision:
-		coloredPrefix = yellow + prefixText + reset
-	case thoughtData.BranchFromThought < 0:
-		coloredPrefix = green + prefixText + reset
-	default:
-		coloredPrefix = blue + prefixText + reset
-	}
-
-	// Reconstruct header with colors, but use headerContent length for layout
-	coloredHeader := strings.Replace(headerContent, prefixText, coloredPrefix, 1)
-
-	borderLen := int(math.Max(float64(len(headerContent)), float64(len(thoughtData.Thought)))) + 4
-	border := strings.Repeat("─", borderLen)
-
-	return fmt.Sprintf(`
-┌%s┐
-│ %s%s │
-├%s┤
-│ %s%s │
-└%s┘`,
-		border,
-		coloredHeader,
-		strings.Repeat(" ", borderLen-len(headerContent)-2),
-		border,
-		thoughtData.Thought,
-		strings.Repeat(" ", borderLen-len(thoughtData.Thought)-2),
-		border,
-	)
-}
-
 // ProcessThought processes a thought request.
+//
+// The returned [Output] becomes the structured content of the tool result, validated by the SDK against
+// the tool's output schema.
 func (s *SequentialThinkingServer) ProcessThought(ctx context.Context, request *mcp.CallToolRequest, input ThoughtData) (*mcp.CallToolResult, any, error) {
-	if err := s.validateThoughtData(input); err != nil {
-		return nil, nil, err
-	}
-
-	if input.ThoughtNumber > input.TotalThoughts {
-		input.TotalThoughts = input.ThoughtNumber
-	}
-
-	var (
-		branchesSnapshot []string
-		historyLen       int
-	)
-
-	s.mu.Lock()
-	s.thoughtHistory = append(s.thoughtHistory, struct{}{})
-
-	if input.BranchFromThought < 0 && input.BranchID != "" {
-		branchID := input.BranchID
-		if _, exists := s.branches[branchID]; !exists {
-			s.branches[branchID] = struct{}{}
-			insertAt := sort.SearchStrings(s.branchKeys, branchID)
-			if insertAt == len(s.branchKeys) {
-				s.branchKeys = append(s.branchKeys, branchID)
-			} else if s.branchKeys[insertAt] != branchID {
-				s.branchKeys = append(s.branchKeys, "")
-				copy(s.branchKeys[insertAt+1:], s.branchKeys[insertAt:])
-				s.branchKeys[insertAt] = branchID
-			}
-		}
+	if terr := s.checkRate(sessionID(request), requestPrincipal(request)); terr != nil {
+		result, err := toolErrorResult(terr)
+		return result, nil, err
+	}
+	if terr := s.validateThoughtData(input); terr != nil {
+		result, err := toolErrorResult(terr)
+		return result, nil, err
 	}
 
-	historyLen = len(s.thoughtHistory)
-	if len(s.branchKeys) > 0 {
-		branchesSnapshot = append([]string(nil), s.branchKeys...)
+	if terr := s.limits.checkThoughtBytes(len(input.Thought)); terr != nil {
+		result, err := toolErrorResult(terr)
+		return result, nil, err
 	}
 
-	s.mu.Unlock()
+	ts, terr := s.session(request)
+	if terr != nil {
+		result, err := toolErrorResult(terr)
+		return result, nil, err
+	}
+	principal := requestPrincipal(request)
+	if terr := ts.checkPrincipal(principal); terr != nil {
+		result, err := toolErrorResult(terr)
+		return result, nil, err
+	}
+	record := newThoughtRecord(input, ts.id, time.Now())
+	record.Principal = principal
+
+	ts.mu.Lock()
+	if terr := ts.validateReferences(input); terr != nil {
+		ts.mu.Unlock()
+		result, err := toolErrorResult(terr)
+		return result, nil, err
+	}
+	var numbering *Numbering
+	if s.numbering != "" && s.numbering != numberingClient {
+		numbering = ts.number(&record, s.numbering)
+	} else if record.ThoughtNumber > record.TotalThoughts {
+		record.TotalThoughts = record.ThoughtNumber
+	}
+	if terr := s.limits.checkSession(ts, len(input.Thought)); terr != nil {
+		ts.mu.Unlock()
+		result, err := toolErrorResult(terr)
+		return result, nil, err
+	}
+	if s.journal != nil {
+		if err := s.journal.Append(record, ts.created); err != nil {
+			ts.mu.Unlock()
+			return nil, nil, fmt.Errorf("persist thought: %w", err)
+		}
+	}
+	branchOpened := ts.appendThought(record)
+	historyLen := len(ts.history)
+	branchesSnapshot := ts.branchInfos()
+	ts.mu.Unlock()
 
-	if s.enableThoughtLogging {
-		formatted := s.formatThought(input)
-		fmt.Fprintln(os.Stderr, formatted)
+	for _, o := range s.sessionObservers() {
+		o.thoughtAppended(record, branchOpened)
 	}
 
 	// Prepare response
 	output := Output{
-		ThoughtNumber:        input.ThoughtNumber,
-		TotalThoughts:        input.TotalThoughts,
-		NextThoughtNeeded:    input.NextThoughtNeeded,
+		ThoughtNumber:        record.ThoughtNumber,
+		TotalThoughts:        record.TotalThoughts,
+		NextThoughtNeeded:    record.NextThoughtNeeded,
 		Branches:             branchesSnapshot,
 		ThoughtHistoryLength: historyLen,
+		Numbering:            numbering,
 	}
 
+	return toolResult(output)
+}
+
+// toolResult returns the tool result carrying output, which the SDK also sets as the structured content.
+// The same JSON is returned as a text content for clients that do not support structured content.
+func toolResult[Out any](output Out) (*mcp.CallToolResult, any, error) {
 	data, err := sonic.ConfigFastest.MarshalToString(&output)
 	if err != nil {
 		return nil, nil, fmt.Errorf("marshal response: %w", err)
@@ -224,5 +226,5 @@ func (s *SequentialThinkingServer) ProcessThought(ctx context.Context, request *
 				Text: data,
 			},
 		},
-	}, nil, nil
+	}, output, nil
 }
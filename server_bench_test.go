@@ -43,6 +43,14 @@ func BenchmarkProcessThought_NoBranch(b *testing.B) {
 
 func BenchmarkProcessThought_BranchInsert(b *testing.B) {
 	server := NewSequentialThinkingServer()
+	origin := ThoughtData{
+		Thought:       "origin",
+		ThoughtNumber: 1,
+		TotalThoughts: 1,
+	}
+	if _, _, err := server.ProcessThought(b.Context(), nil, origin); err != nil {
+		b.Fatalf("process thought: %v", err)
+	}
 	branchIDs := make([]string, b.N)
 	for i := range branchIDs {
 		branchIDs[i] = strconv.Itoa(i)
@@ -51,7 +59,7 @@ func BenchmarkProcessThought_BranchInsert(b *testing.B) {
 		Thought:           "bench",
 		ThoughtNumber:     1,
 		TotalThoughts:     1,
-		BranchFromThought: -1,
+		BranchFromThought: 1,
 	}
 
 	b.ReportAllocs()
@@ -17,19 +17,18 @@
 package main
 
 import (
-	"io"
-	"os"
+	"log/slog"
 	"strings"
 	"testing"
 
 	"github.com/go-json-experiment/json"
 	"github.com/go-json-experiment/json/jsontext"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
-const loggingEnvKey = "ENABLE_SEQUENTIA_LTHINKING_LOG"
-
 func decodeOutput(t *testing.T, text string) Output {
 	t.Helper()
 
@@ -57,88 +56,26 @@ func resultText(t *testing.T, result *mcp.CallToolResult) string {
 	return content.Text
 }
 
-func captureStderr(t *testing.T, fn func()) string {
-	t.Helper()
-
-	original := os.Stderr
-	r, w, err := os.Pipe()
-	if err != nil {
-		t.Fatalf("pipe stderr: %v", err)
-	}
-	os.Stderr = w
-	defer func() {
-		os.Stderr = original
-	}()
-
-	fn()
-
-	if err := w.Close(); err != nil {
-		t.Fatalf("close writer: %v", err)
-	}
-	data, err := io.ReadAll(r)
-	if err != nil {
-		t.Fatalf("read stderr: %v", err)
-	}
-	if err := r.Close(); err != nil {
-		t.Fatalf("close reader: %v", err)
-	}
-	return string(data)
-}
-
 func TestNewSequentialThinkingServer(t *testing.T) {
 	tests := map[string]struct {
-		envValue        string
-		wantLogging     bool
-		wantHistorySize int
-		wantBranchSize  int
-		wantNilHistory  bool
-		wantNilBranches bool
+		wantSessionSize int
+		wantNilSessions bool
 	}{
-		"default: logging disabled": {
-			envValue:        "",
-			wantLogging:     false,
-			wantHistorySize: 0,
-			wantBranchSize:  0,
-			wantNilHistory:  false,
-			wantNilBranches: false,
-		},
-		"enabled: logging enabled": {
-			envValue:        "true",
-			wantLogging:     true,
-			wantHistorySize: 0,
-			wantBranchSize:  0,
-			wantNilHistory:  false,
-			wantNilBranches: false,
-		},
-		"invalid: logging disabled": {
-			envValue:        "not-bool",
-			wantLogging:     false,
-			wantHistorySize: 0,
-			wantBranchSize:  0,
-			wantNilHistory:  false,
-			wantNilBranches: false,
+		"default: no sessions": {
+			wantSessionSize: 0,
+			wantNilSessions: false,
 		},
 	}
 
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			t.Setenv(loggingEnvKey, tt.envValue)
 			server := NewSequentialThinkingServer()
 
-			if diff := cmp.Diff(tt.wantLogging, server.enableThoughtLogging); diff != "" {
-				t.Fatalf("logging flag mismatch (-want +got):\n%s", diff)
-			}
-			if diff := cmp.Diff(tt.wantHistorySize, len(server.thoughtHistory)); diff != "" {
-				t.Fatalf("history size mismatch (-want +got):\n%s", diff)
+			if diff := cmp.Diff(tt.wantSessionSize, len(server.sessions)); diff != "" {
+				t.Fatalf("session size mismatch (-want +got):\n%s", diff)
 			}
-			if diff := cmp.Diff(tt.wantBranchSize, len(server.branches)); diff != "" {
-				t.Fatalf("branch size mismatch (-want +got):\n%s", diff)
-			}
-			if diff := cmp.Diff(tt.wantNilHistory, server.thoughtHistory == nil); diff != "" {
-				t.Fatalf("history nil mismatch (-want +got):\n%s", diff)
-			}
-			if diff := cmp.Diff(tt.wantNilBranches, server.branches == nil); diff != "" {
-				t.Fatalf("branches nil mismatch (-want +got):\n%s", diff)
+			if diff := cmp.Diff(tt.wantNilSessions, server.sessions == nil); diff != "" {
+				t.Fatalf("sessions nil mismatch (-want +got):\n%s", diff)
 			}
 		})
 	}
@@ -148,9 +85,10 @@ func TestSequentialThinkingServerValidateThoughtData(t *testing.T) {
 	server := NewSequentialThinkingServer()
 
 	tests := map[string]struct {
-		input    ThoughtData
-		wantErr  bool
-		wantText string
+		input      ThoughtData
+		wantErr    bool
+		wantText   string
+		wantReason string
 	}{
 		"error: empty thought": {
 			input: ThoughtData{
@@ -158,8 +96,9 @@ func TestSequentialThinkingServerValidateThoughtData(t *testing.T) {
 				ThoughtNumber: 1,
 				TotalThoughts: 1,
 			},
-			wantErr:  true,
-			wantText: "invalid thought: must be a string",
+			wantErr:    true,
+			wantText:   "invalid thought: must be a string",
+			wantReason: reasonInvalidThought,
 		},
 		"error: invalid thoughtNumber": {
 			input: ThoughtData{
@@ -167,8 +106,9 @@ func TestSequentialThinkingServerValidateThoughtData(t *testing.T) {
 				ThoughtNumber: 0,
 				TotalThoughts: 1,
 			},
-			wantErr:  true,
-			wantText: "invalid thoughtNumber: must be a number > 0",
+			wantErr:    true,
+			wantText:   "invalid thoughtNumber: must be a number > 0",
+			wantReason: reasonInvalidThoughtNumber,
 		},
 		"error: invalid totalThoughts": {
 			input: ThoughtData{
@@ -176,8 +116,42 @@ func TestSequentialThinkingServerValidateThoughtData(t *testing.T) {
 				ThoughtNumber: 1,
 				TotalThoughts: 0,
 			},
-			wantErr:  true,
-			wantText: "invalid totalThoughts: must be a number > 0",
+			wantErr:    true,
+			wantText:   "invalid totalThoughts: must be a number > 0",
+			wantReason: reasonInvalidTotalThoughts,
+		},
+		"error: revision without revisesThought": {
+			input: ThoughtData{
+				Thought:       "ok",
+				ThoughtNumber: 2,
+				TotalThoughts: 2,
+				IsRevision:    true,
+			},
+			wantErr:    true,
+			wantText:   "invalid revisesThought: must be set when isRevision is true",
+			wantReason: reasonRevisionMissingTarget,
+		},
+		"error: revisesThought without isRevision": {
+			input: ThoughtData{
+				Thought:        "ok",
+				ThoughtNumber:  2,
+				TotalThoughts:  2,
+				RevisesThought: 1,
+			},
+			wantErr:    true,
+			wantText:   "invalid revisesThought: isRevision must be true when revisesThought is set",
+			wantReason: reasonRevisionWithoutFlag,
+		},
+		"error: branchFromThought without branchId": {
+			input: ThoughtData{
+				Thought:           "ok",
+				ThoughtNumber:     2,
+				TotalThoughts:     2,
+				BranchFromThought: 1,
+			},
+			wantErr:    true,
+			wantText:   "invalid branchFromThought: branchId must be set when branching",
+			wantReason: reasonBranchMissingID,
 		},
 		"success: valid input": {
 			input: ThoughtData{
@@ -185,8 +159,9 @@ func TestSequentialThinkingServerValidateThoughtData(t *testing.T) {
 				ThoughtNumber: 1,
 				TotalThoughts: 2,
 			},
-			wantErr:  false,
-			wantText: "",
+			wantErr:    false,
+			wantText:   "",
+			wantReason: "",
 		},
 	}
 
@@ -200,90 +175,125 @@ func TestSequentialThinkingServerValidateThoughtData(t *testing.T) {
 				if diff := cmp.Diff(tt.wantText, err.Error()); diff != "" {
 					t.Fatalf("error text mismatch (-want +got):\n%s", diff)
 				}
+				if diff := cmp.Diff(tt.wantReason, err.Reason); diff != "" {
+					t.Fatalf("error reason mismatch (-want +got):\n%s", diff)
+				}
 			}
 		})
 	}
 }
 
-func TestSequentialThinkingServerFormatThought(t *testing.T) {
-	server := NewSequentialThinkingServer()
+func TestSequentialThinkingServerProcessThoughtValidation(t *testing.T) {
+	recorded := []ThoughtData{
+		{
+			Thought:       "first",
+			ThoughtNumber: 1,
+			TotalThoughts: 3,
+		},
+		{
+			Thought:           "branch",
+			ThoughtNumber:     2,
+			TotalThoughts:     3,
+			BranchFromThought: 1,
+			BranchID:          "b",
+		},
+	}
 
 	tests := map[string]struct {
-		input        ThoughtData
-		wantContains []string
+		input ThoughtData
+		want  ToolError
 	}{
-		"format: revision": {
+		"error: invalid thought": {
+			input: ThoughtData{
+				Thought:       "",
+				ThoughtNumber: 1,
+				TotalThoughts: 1,
+			},
+			want: ToolError{
+				Message: "invalid thought: must be a string",
+				Reason:  reasonInvalidThought,
+			},
+		},
+		"error: revision of unrecorded thought": {
 			input: ThoughtData{
 				Thought:        "revise",
-				ThoughtNumber:  1,
-				TotalThoughts:  2,
+				ThoughtNumber:  3,
+				TotalThoughts:  3,
 				IsRevision:     true,
-				RevisesThought: -2,
+				RevisesThought: 99,
+			},
+			want: ToolError{
+				Message: "invalid revisesThought: thought 99 has not been recorded",
+				Reason:  reasonRevisionTargetNotFound,
 			},
-			wantContains: []string{"Revision", "revising thought -2", "revise"},
 		},
-		"format: branch": {
+		"error: branch from unrecorded thought": {
 			input: ThoughtData{
-				Thought:           "branch",
-				ThoughtNumber:     2,
+				Thought:           "fork",
+				ThoughtNumber:     3,
 				TotalThoughts:     3,
-				BranchFromThought: -1,
-				BranchID:          "b1",
+				BranchFromThought: 7,
+				BranchID:          "c",
+			},
+			want: ToolError{
+				Message: "invalid branchFromThought: thought 7 has not been recorded",
+				Reason:  reasonBranchOriginNotFound,
 			},
-			wantContains: []string{"Branch", "from thought -1, ID: b1", "branch"},
 		},
-		"format: default": {
+		"error: unknown branch without origin": {
 			input: ThoughtData{
-				Thought:       "think",
+				Thought:       "continue",
 				ThoughtNumber: 3,
 				TotalThoughts: 3,
+				BranchID:      "missing",
+			},
+			want: ToolError{
+				Message: `invalid branchId: branch "missing" does not exist, set branchFromThought to open it`,
+				Reason:  reasonBranchNotFound,
 			},
-			wantContains: []string{"Thought", "think"},
 		},
-	}
-
-	for name, tt := range tests {
-		t.Run(name, func(t *testing.T) {
-			got := server.formatThought(tt.input)
-			for _, want := range tt.wantContains {
-				if diff := cmp.Diff(true, strings.Contains(got, want)); diff != "" {
-					t.Fatalf("expected content missing (-want +got):\n%s", diff)
-				}
-			}
-		})
-	}
-}
-
-func TestSequentialThinkingServerProcessThoughtValidation(t *testing.T) {
-	server := NewSequentialThinkingServer()
-
-	tests := map[string]struct {
-		input   ThoughtData
-		wantErr string
-	}{
-		"error: invalid thought": {
+		"error: branch origin mismatch": {
 			input: ThoughtData{
-				Thought:       "",
-				ThoughtNumber: 1,
-				TotalThoughts: 1,
+				Thought:           "continue",
+				ThoughtNumber:     3,
+				TotalThoughts:     3,
+				BranchFromThought: 2,
+				BranchID:          "b",
+			},
+			want: ToolError{
+				Message: `invalid branchFromThought: branch "b" was opened from thought 1`,
+				Reason:  reasonBranchOriginMismatch,
 			},
-			wantErr: "invalid thought: must be a string",
 		},
 	}
 
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			result, _, err := server.ProcessThought(t.Context(), nil, tt.input)
-			if diff := cmp.Diff(true, err != nil); diff != "" {
-				t.Fatalf("error presence mismatch (-want +got):\n%s", diff)
+			server := NewSequentialThinkingServer()
+			for _, input := range recorded {
+				if _, _, err := server.ProcessThought(t.Context(), nil, input); err != nil {
+					t.Fatalf("process thought: %v", err)
+				}
 			}
+
+			result, _, err := server.ProcessThought(t.Context(), nil, tt.input)
 			if err != nil {
-				if diff := cmp.Diff(tt.wantErr, err.Error()); diff != "" {
-					t.Fatalf("error text mismatch (-want +got):\n%s", diff)
-				}
+				t.Fatalf("process thought: %v", err)
+			}
+			if diff := cmp.Diff(true, result.IsError); diff != "" {
+				t.Fatalf("result IsError mismatch (-want +got):\n%s", diff)
 			}
-			if diff := cmp.Diff(true, result == nil); diff != "" {
-				t.Fatalf("result nil mismatch (-want +got):\n%s", diff)
+
+			dec := jsontext.NewDecoder(strings.NewReader(resultText(t, result)))
+			var got ToolError
+			if err := json.UnmarshalDecode(dec, &got); err != nil {
+				t.Fatalf("decode tool error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("tool error mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(len(recorded), len(server.History(defaultSessionID))); diff != "" {
+				t.Fatalf("history length mismatch (-want +got):\n%s", diff)
 			}
 		})
 	}
@@ -299,56 +309,104 @@ func TestSequentialThinkingServerProcessThoughtSuccess(t *testing.T) {
 				{
 					Thought:           "first",
 					NextThoughtNeeded: true,
+					ThoughtNumber:     1,
+					TotalThoughts:     1,
+				},
+				{
+					Thought:           "second",
+					NextThoughtNeeded: true,
 					ThoughtNumber:     2,
 					TotalThoughts:     1,
-					BranchFromThought: -1,
+					BranchFromThought: 1,
 					BranchID:          "b",
 				},
 				{
-					Thought:           "second",
+					Thought:           "third",
+					NextThoughtNeeded: true,
+					ThoughtNumber:     2,
+					TotalThoughts:     3,
+					BranchFromThought: 1,
+					BranchID:          "a",
+				},
+				{
+					Thought:           "fourth",
 					NextThoughtNeeded: false,
 					ThoughtNumber:     3,
 					TotalThoughts:     3,
-					BranchFromThought: -2,
-					BranchID:          "a",
+					BranchID:          "b",
 				},
 			},
 			wantOutputs: []Output{
 				{
-					ThoughtNumber:        2,
-					TotalThoughts:        2,
+					ThoughtNumber:        1,
+					TotalThoughts:        1,
 					NextThoughtNeeded:    true,
-					Branches:             []string{"b"},
+					Branches:             nil,
 					ThoughtHistoryLength: 1,
 				},
 				{
-					ThoughtNumber:        3,
-					TotalThoughts:        3,
-					NextThoughtNeeded:    false,
-					Branches:             []string{"a", "b"},
+					ThoughtNumber:     2,
+					TotalThoughts:     2,
+					NextThoughtNeeded: true,
+					Branches: []BranchInfo{
+						{ID: "b", FromThought: 1, Length: 1},
+					},
 					ThoughtHistoryLength: 2,
 				},
+				{
+					ThoughtNumber:     2,
+					TotalThoughts:     3,
+					NextThoughtNeeded: true,
+					Branches: []BranchInfo{
+						{ID: "a", FromThought: 1, Length: 1},
+						{ID: "b", FromThought: 1, Length: 1},
+					},
+					ThoughtHistoryLength: 3,
+				},
+				{
+					ThoughtNumber:     3,
+					TotalThoughts:     3,
+					NextThoughtNeeded: false,
+					Branches: []BranchInfo{
+						{ID: "a", FromThought: 1, Length: 1},
+						{ID: "b", FromThought: 1, Length: 2},
+					},
+					ThoughtHistoryLength: 4,
+				},
 			},
 		},
-		"success: no branch recorded when branchFromThought non-negative": {
+		"success: revision of recorded thought": {
 			inputs: []ThoughtData{
 				{
-					Thought:           "third",
-					NextThoughtNeeded: false,
+					Thought:           "first",
+					NextThoughtNeeded: true,
 					ThoughtNumber:     1,
-					TotalThoughts:     1,
-					BranchFromThought: 1,
-					BranchID:          "ignored",
+					TotalThoughts:     2,
+				},
+				{
+					Thought:           "revised",
+					NextThoughtNeeded: false,
+					ThoughtNumber:     2,
+					TotalThoughts:     2,
+					IsRevision:        true,
+					RevisesThought:    1,
 				},
 			},
 			wantOutputs: []Output{
 				{
 					ThoughtNumber:        1,
-					TotalThoughts:        1,
-					NextThoughtNeeded:    false,
+					TotalThoughts:        2,
+					NextThoughtNeeded:    true,
 					Branches:             nil,
 					ThoughtHistoryLength: 1,
 				},
+				{
+					ThoughtNumber:        2,
+					TotalThoughts:        2,
+					NextThoughtNeeded:    false,
+					Branches:             nil,
+					ThoughtHistoryLength: 2,
+				},
 			},
 		},
 	}
@@ -371,43 +429,242 @@ func TestSequentialThinkingServerProcessThoughtSuccess(t *testing.T) {
 	}
 }
 
-func TestSequentialThinkingServerProcessThoughtLogging(t *testing.T) {
+func TestSequentialThinkingServerProcessThoughtHistory(t *testing.T) {
 	tests := map[string]struct {
-		input        ThoughtData
-		wantContains []string
+		inputs []ThoughtData
+		want   []ThoughtRecord
 	}{
-		"success: logging writes formatted thought": {
-			input: ThoughtData{
-				Thought:       "log this",
-				ThoughtNumber: 1,
-				TotalThoughts: 1,
+		"success: records retain thought content": {
+			inputs: []ThoughtData{
+				{
+					Thought:           "first",
+					NextThoughtNeeded: true,
+					ThoughtNumber:     1,
+					TotalThoughts:     2,
+				},
+				{
+					Thought:           "second",
+					NextThoughtNeeded: true,
+					ThoughtNumber:     3,
+					TotalThoughts:     2,
+					IsRevision:        true,
+					RevisesThought:    1,
+					NeedsMoreThoughts: true,
+				},
+			},
+			want: []ThoughtRecord{
+				{
+					Thought:           "first",
+					ThoughtNumber:     1,
+					TotalThoughts:     2,
+					NextThoughtNeeded: true,
+					SessionID:         defaultSessionID,
+				},
+				{
+					Thought:           "second",
+					ThoughtNumber:     3,
+					TotalThoughts:     3,
+					NextThoughtNeeded: true,
+					IsRevision:        true,
+					RevisesThought:    1,
+					NeedsMoreThoughts: true,
+					SessionID:         defaultSessionID,
+				},
 			},
-			wantContains: []string{"log this", "Thought"},
 		},
 	}
 
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			t.Setenv(loggingEnvKey, "true")
 			server := NewSequentialThinkingServer()
+
+			for _, input := range tt.inputs {
+				if _, _, err := server.ProcessThought(t.Context(), nil, input); err != nil {
+					t.Fatalf("process thought: %v", err)
+				}
+			}
+
+			got := server.History(defaultSessionID)
+			for i := range got {
+				if got[i].Timestamp.IsZero() {
+					t.Fatalf("record %d has zero timestamp", i)
+				}
+			}
+			if diff := cmp.Diff(tt.want, got, cmpopts.IgnoreFields(ThoughtRecord{}, "Timestamp")); diff != "" {
+				t.Fatalf("history mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// connectInMemoryClient connects a new MCP client to an in-memory server backed by thinking.
+func connectInMemoryClient(t *testing.T, thinking *SequentialThinkingServer) *mcp.ClientSession {
+	t.Helper()
+
+	srv, err := newServer(slog.New(slog.DiscardHandler), thinking, serverOptions{})
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	ss, err := srv.Connect(t.Context(), serverTransport, nil)
+	if err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+	t.Cleanup(func() { ss.Close() })
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "v0.0.0"}, nil)
+	cs, err := client.Connect(t.Context(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("connect client: %v", err)
+	}
+	t.Cleanup(func() { cs.Close() })
+	return cs
+}
+
+// toolOutputSchema returns the resolved output schema advertised for the named tool.
+func toolOutputSchema(t *testing.T, cs *mcp.ClientSession, name string) *jsonschema.Resolved {
+	t.Helper()
+
+	tools, err := cs.ListTools(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("list tools: %v", err)
+	}
+	for _, tool := range tools.Tools {
+		if tool.Name != name {
+			continue
+		}
+		data, err := json.Marshal(tool.OutputSchema)
+		if err != nil {
+			t.Fatalf("marshal output schema: %v", err)
+		}
+		var schema jsonschema.Schema
+		if err := json.Unmarshal(data, &schema); err != nil {
+			t.Fatalf("unmarshal output schema: %v", err)
+		}
+		resolved, err := schema.Resolve(nil)
+		if err != nil {
+			t.Fatalf("resolve output schema: %v", err)
+		}
+		return resolved
+	}
+	t.Fatalf("tool %q not found", name)
+	return nil
+}
+
+func TestSequentialThinkingServerProcessThoughtStructuredContent(t *testing.T) {
+	tests := map[string]struct {
+		inputs []ThoughtData
+		want   Output
+	}{
+		"success: main line thought": {
+			inputs: []ThoughtData{
+				{
+					Thought:           "first",
+					NextThoughtNeeded: true,
+					ThoughtNumber:     1,
+					TotalThoughts:     2,
+				},
+			},
+			want: Output{
+				ThoughtNumber:        1,
+				TotalThoughts:        2,
+				NextThoughtNeeded:    true,
+				ThoughtHistoryLength: 1,
+			},
+		},
+		"success: branch thought": {
+			inputs: []ThoughtData{
+				{
+					Thought:           "first",
+					NextThoughtNeeded: true,
+					ThoughtNumber:     1,
+					TotalThoughts:     2,
+				},
+				{
+					Thought:           "fork",
+					NextThoughtNeeded: false,
+					ThoughtNumber:     2,
+					TotalThoughts:     2,
+					BranchFromThought: 1,
+					BranchID:          "alt",
+				},
+			},
+			want: Output{
+				ThoughtNumber:     2,
+				TotalThoughts:     2,
+				NextThoughtNeeded: false,
+				Branches: []BranchInfo{
+					{ID: "alt", FromThought: 1, Length: 1},
+				},
+				ThoughtHistoryLength: 2,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cs := connectInMemoryClient(t, NewSequentialThinkingServer())
+			outputSchema := toolOutputSchema(t, cs, "sequentialthinking")
+
 			var result *mcp.CallToolResult
-			var err error
+			for _, input := range tt.inputs {
+				var err error
+				result, err = cs.CallTool(t.Context(), &mcp.CallToolParams{
+					Name:      "sequentialthinking",
+					Arguments: input,
+				})
+				if err != nil {
+					t.Fatalf("call tool: %v", err)
+				}
+				if result.IsError {
+					t.Fatalf("tool error: %s", resultText(t, result))
+				}
+			}
 
-			output := captureStderr(t, func() {
-				result, _, err = server.ProcessThought(t.Context(), nil, tt.input)
-			})
+			if diff := cmp.Diff(true, result.StructuredContent != nil); diff != "" {
+				t.Fatalf("structured content presence mismatch (-want +got):\n%s", diff)
+			}
+			if err := outputSchema.Validate(result.StructuredContent); err != nil {
+				t.Fatalf("structured content does not match output schema: %v", err)
+			}
 
+			data, err := json.Marshal(result.StructuredContent)
 			if err != nil {
-				t.Fatalf("process thought: %v", err)
+				t.Fatalf("marshal structured content: %v", err)
 			}
-			if diff := cmp.Diff(true, result != nil); diff != "" {
-				t.Fatalf("result nil mismatch (-want +got):\n%s", diff)
+			if diff := cmp.Diff(tt.want, decodeOutput(t, string(data))); diff != "" {
+				t.Fatalf("structured content mismatch (-want +got):\n%s", diff)
 			}
-			for _, want := range tt.wantContains {
-				if diff := cmp.Diff(true, strings.Contains(output, want)); diff != "" {
-					t.Fatalf("logging output missing content (-want +got):\n%s", diff)
-				}
+			if diff := cmp.Diff(tt.want, decodeOutput(t, resultText(t, result))); diff != "" {
+				t.Fatalf("text content mismatch (-want +got):\n%s", diff)
 			}
 		})
 	}
 }
+
+func TestSequentialThinkingServerProcessThoughtToolErrorRoundTrip(t *testing.T) {
+	cs := connectInMemoryClient(t, NewSequentialThinkingServer())
+
+	result, err := cs.CallTool(t.Context(), &mcp.CallToolParams{
+		Name: "sequentialthinking",
+		Arguments: ThoughtData{
+			Thought:        "revise",
+			ThoughtNumber:  1,
+			TotalThoughts:  1,
+			IsRevision:     true,
+			RevisesThought: 5,
+		},
+	})
+	if err != nil {
+		t.Fatalf("call tool: %v", err)
+	}
+	if diff := cmp.Diff(true, result.IsError); diff != "" {
+		t.Fatalf("result IsError mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(true, result.StructuredContent == nil); diff != "" {
+		t.Fatalf("structured content nil mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(true, strings.Contains(resultText(t, result), reasonRevisionTargetNotFound)); diff != "" {
+		t.Fatalf("reason missing from text (-want +got):\n%s", diff)
+	}
+}
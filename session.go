@@ -0,0 +1,476 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultSessionID is the session key used when the MCP session has no ID,
+// such as over the stdio transport or when ProcessThought is called without a request.
+const defaultSessionID = "default"
+
+// thoughtBranch is a line of thoughts forked from a recorded thought of the session.
+type thoughtBranch struct {
+	id          string
+	fromThought int
+	created     time.Time
+	// thoughts holds the indexes of the branch thoughts in the session history, in order.
+	thoughts []int
+	// line tracks the thought numbers recorded on the branch.
+	line thoughtLine
+}
+
+// thoughtLine tracks the thought numbers recorded on the main line or on a branch of a session.
+type thoughtLine struct {
+	// numbers holds the thought numbers recorded on the line.
+	numbers map[int]bool
+	// highest is the highest number recorded on the line, or the origin of a branch without thoughts.
+	highest int
+}
+
+// add records the thought number n on l.
+func (l *thoughtLine) add(n int) {
+	if l.numbers == nil {
+		l.numbers = make(map[int]bool)
+	}
+	l.numbers[n] = true
+	l.highest = max(l.highest, n)
+}
+
+// thinkingSession holds the thought history and branches of a single MCP session.
+type thinkingSession struct {
+	id      string
+	created time.Time
+	// principal is the authenticated client that opened the session, or "" without authentication.
+	principal string
+	// lastUsed is the time the session was created or last recorded a thought, in Unix nanoseconds.
+	lastUsed atomic.Int64
+
+	mu      sync.Mutex
+	history []ThoughtRecord
+	// bytes is the size of the thought texts in history.
+	bytes int
+	// numbers maps each recorded thought number to its latest index in history.
+	numbers map[int]int
+	// mainLine tracks the thought numbers recorded without a branch.
+	mainLine   thoughtLine
+	branches   map[string]*thoughtBranch
+	branchKeys []string
+}
+
+// newThinkingSession returns an empty session identified by id.
+func newThinkingSession(id string, now time.Time) *thinkingSession {
+	ts := &thinkingSession{
+		id:       id,
+		created:  now,
+		history:  make([]ThoughtRecord, 0),
+		numbers:  make(map[int]int),
+		branches: make(map[string]*thoughtBranch),
+	}
+	ts.lastUsed.Store(now.UnixNano())
+	return ts
+}
+
+// appendThought appends record to the history and to the branch it belongs to.
+//
+// A thought with both branchFromThought and branchId opens the branch if it does not exist yet.
+// A thought with only branchId continues an existing branch.
+// It reports whether a new branch was opened.
+//
+// The caller must hold ts.mu.
+func (ts *thinkingSession) appendThought(record ThoughtRecord) (branchOpened bool) {
+	idx := len(ts.history)
+	ts.history = append(ts.history, record)
+	ts.numbers[record.ThoughtNumber] = idx
+	ts.bytes += len(record.Thought)
+	ts.lastUsed.Store(record.Timestamp.UnixNano())
+
+	if record.BranchID == "" {
+		ts.mainLine.add(record.ThoughtNumber)
+		return false
+	}
+	br, ok := ts.branches[record.BranchID]
+	if !ok {
+		if record.BranchFromThought <= 0 {
+			return false
+		}
+		br = ts.openBranch(record.BranchID, record.BranchFromThought, record.Timestamp)
+		branchOpened = true
+	}
+	br.thoughts = append(br.thoughts, idx)
+	br.line.add(record.ThoughtNumber)
+
+	return branchOpened
+}
+
+// validateReferences reports whether the revision and branch references of input
+// point at thoughts and branches recorded in the session.
+//
+// The caller must hold ts.mu.
+func (ts *thinkingSession) validateReferences(input ThoughtData) *ToolError {
+	if input.IsRevision {
+		if _, ok := ts.numbers[input.RevisesThought]; !ok {
+			return newToolError(reasonRevisionTargetNotFound, "invalid revisesThought: thought %d has not been recorded", input.RevisesThought)
+		}
+	}
+
+	if input.BranchID == "" {
+		return nil
+	}
+	br, ok := ts.branches[input.BranchID]
+	switch {
+	case !ok && input.BranchFromThought <= 0:
+		return newToolError(reasonBranchNotFound, "invalid branchId: branch %q does not exist, set branchFromThought to open it", input.BranchID)
+	case !ok:
+		if _, ok := ts.numbers[input.BranchFromThought]; !ok {
+			return newToolError(reasonBranchOriginNotFound, "invalid branchFromThought: thought %d has not been recorded", input.BranchFromThought)
+		}
+	case input.BranchFromThought > 0 && input.BranchFromThought != br.fromThought:
+		return newToolError(reasonBranchOriginMismatch, "invalid branchFromThought: branch %q was opened from thought %d", input.BranchID, br.fromThought)
+	}
+
+	return nil
+}
+
+// openBranch records a new branch forked from thought fromThought, keeping branchKeys sorted.
+//
+// The caller must hold ts.mu.
+func (ts *thinkingSession) openBranch(branchID string, fromThought int, now time.Time) *thoughtBranch {
+	br := &thoughtBranch{
+		id:          branchID,
+		fromThought: fromThought,
+		created:     now,
+		line:        thoughtLine{highest: fromThought},
+	}
+	ts.branches[branchID] = br
+
+	insertAt := sort.SearchStrings(ts.branchKeys, branchID)
+	if insertAt == len(ts.branchKeys) {
+		ts.branchKeys = append(ts.branchKeys, branchID)
+		return br
+	}
+	ts.branchKeys = append(ts.branchKeys, "")
+	copy(ts.branchKeys[insertAt+1:], ts.branchKeys[insertAt:])
+	ts.branchKeys[insertAt] = branchID
+
+	return br
+}
+
+// branchInfos returns the branches of the session sorted by ID, or nil if there are none.
+//
+// The caller must hold ts.mu.
+func (ts *thinkingSession) branchInfos() []BranchInfo {
+	if len(ts.branchKeys) == 0 {
+		return nil
+	}
+
+	infos := make([]BranchInfo, 0, len(ts.branchKeys))
+	for _, id := range ts.branchKeys {
+		br := ts.branches[id]
+		infos = append(infos, BranchInfo{
+			ID:          br.id,
+			FromThought: br.fromThought,
+			Length:      len(br.thoughts),
+		})
+	}
+	return infos
+}
+
+// branchThoughts returns the thoughts of the branch in order.
+//
+// The caller must hold ts.mu.
+func (ts *thinkingSession) branchThoughts(branchID string) ([]ThoughtRecord, bool) {
+	br, ok := ts.branches[branchID]
+	if !ok {
+		return nil, false
+	}
+
+	thoughts := make([]ThoughtRecord, 0, len(br.thoughts))
+	for _, idx := range br.thoughts {
+		thoughts = append(thoughts, ts.history[idx])
+	}
+	return thoughts, true
+}
+
+// sessionObserver is notified of changes to the thinking sessions.
+//
+// Methods are called after the change is applied, without holding any session lock.
+type sessionObserver interface {
+	// sessionOpened is called when the session id is created.
+	sessionOpened(id string)
+	// sessionClosed is called when the session id is removed.
+	sessionClosed(id string)
+	// thoughtAppended is called when record is accepted into its session.
+	// branchOpened reports whether the thought opened a new branch.
+	thoughtAppended(record ThoughtRecord, branchOpened bool)
+}
+
+// observe registers o to be notified of changes to the thinking sessions.
+func (s *SequentialThinkingServer) observe(o sessionObserver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.observers = append(s.observers, o)
+}
+
+// sessionObservers returns the registered observers.
+func (s *SequentialThinkingServer) sessionObservers() []sessionObserver {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.observers
+}
+
+// sessionID returns the key of the thinking session the request belongs to.
+func sessionID(request *mcp.CallToolRequest) string {
+	if request == nil || request.Session == nil {
+		return defaultSessionID
+	}
+	if id := request.Session.ID(); id != "" {
+		return id
+	}
+	return defaultSessionID
+}
+
+// session returns the thinking session of the request, creating it on first use.
+//
+// A session created for a connected MCP session is torn down once that session closes.
+// Creating a session over the session limit evicts the least recently used session, or
+// fails with a [ToolError] if the limits reject new sessions instead.
+func (s *SequentialThinkingServer) session(request *mcp.CallToolRequest) (*thinkingSession, *ToolError) {
+	id := sessionID(request)
+
+	s.mu.Lock()
+	ts, ok := s.sessions[id]
+	var evicted []*thinkingSession
+	if !ok {
+		var terr *ToolError
+		evicted, terr = s.admitSession()
+		if terr != nil {
+			s.mu.Unlock()
+			return nil, terr
+		}
+		ts = newThinkingSession(id, time.Now())
+		ts.principal = requestPrincipal(request)
+		s.sessions[id] = ts
+		if s.journal != nil {
+			s.journal.Create(id)
+		}
+	}
+	observers := s.observers
+	s.mu.Unlock()
+
+	if ok {
+		return ts, nil
+	}
+	s.evicted(evicted, evictionLRU, observers)
+	for _, o := range observers {
+		o.sessionOpened(id)
+	}
+	if request != nil && request.Session != nil {
+		go func(ss *mcp.ServerSession) {
+			_ = ss.Wait()
+			s.closeSession(id, ts)
+		}(request.Session)
+	}
+
+	return ts, nil
+}
+
+// closeSession removes the session id if it is still ts, and closes its journal.
+func (s *SequentialThinkingServer) closeSession(id string, ts *thinkingSession) {
+	s.mu.Lock()
+	closed := s.sessions[id] == ts
+	if closed {
+		delete(s.sessions, id)
+	}
+	observers := s.observers
+	s.mu.Unlock()
+
+	if closed {
+		s.sessionRemoved(id, observers)
+	}
+}
+
+// sessionRemoved closes the journal of the session id, removed from s.sessions, and notifies observers.
+func (s *SequentialThinkingServer) sessionRemoved(id string, observers []sessionObserver) {
+	if s.journal != nil {
+		if err := s.journal.CloseSession(id); err != nil {
+			slog.Warn("close session journal", slog.String("session", id), slog.Any("error", err))
+		}
+	}
+	for _, o := range observers {
+		o.sessionClosed(id)
+	}
+}
+
+// lookupSession returns the session id, if it exists.
+func (s *SequentialThinkingServer) lookupSession(id string) (*thinkingSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ts, ok := s.sessions[id]
+	return ts, ok
+}
+
+// checkPrincipal returns the tool error of a call of principal in ts, a session of another principal, or nil.
+func (ts *thinkingSession) checkPrincipal(principal string) *ToolError {
+	if principal != ts.principal {
+		return newToolError(reasonPrincipalMismatch, "session %s belongs to another principal", ts.id)
+	}
+	return nil
+}
+
+// principalSession returns the session id, if it exists.
+// It fails with a [ToolError] if the session belongs to another principal than principal.
+func (s *SequentialThinkingServer) principalSession(id, principal string) (*thinkingSession, bool, *ToolError) {
+	ts, ok := s.lookupSession(id)
+	if !ok {
+		return nil, false, nil
+	}
+	if terr := ts.checkPrincipal(principal); terr != nil {
+		return nil, false, terr
+	}
+	return ts, true, nil
+}
+
+// callerSnapshot returns a snapshot of the session of request, and false if it does not exist yet.
+// It fails with a [ToolError] if the session belongs to another principal than the caller.
+func (s *SequentialThinkingServer) callerSnapshot(request *mcp.CallToolRequest) (SessionSnapshot, bool, *ToolError) {
+	id := sessionID(request)
+	ts, ok, terr := s.principalSession(id, requestPrincipal(request))
+	if !ok {
+		return SessionSnapshot{ID: id}, false, terr
+	}
+	return ts.snapshot(), true, nil
+}
+
+// SessionIDs returns the IDs of the live thinking sessions in sorted order.
+func (s *SequentialThinkingServer) SessionIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.sessions))
+	for id := range s.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// History returns a copy of the thoughts recorded in the session id in the order they were accepted.
+func (s *SequentialThinkingServer) History(id string) []ThoughtRecord {
+	ts, ok := s.lookupSession(id)
+	if !ok {
+		return nil
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return append([]ThoughtRecord(nil), ts.history...)
+}
+
+// Branches returns the branches of the session id sorted by branch ID.
+func (s *SequentialThinkingServer) Branches(id string) []BranchInfo {
+	ts, ok := s.lookupSession(id)
+	if !ok {
+		return nil
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.branchInfos()
+}
+
+// SessionSnapshot is a point-in-time copy of a thinking session.
+type SessionSnapshot struct {
+	ID        string          `json:"id"`
+	Principal string          `json:"principal,omitzero"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Thoughts  []ThoughtRecord `json:"thoughts"`
+	Branches  []BranchInfo    `json:"branches"`
+}
+
+// snapshot returns a copy of the session.
+func (ts *thinkingSession) snapshot() SessionSnapshot {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return SessionSnapshot{
+		ID:        ts.id,
+		Principal: ts.principal,
+		CreatedAt: ts.created,
+		Thoughts:  append([]ThoughtRecord(nil), ts.history...),
+		Branches:  ts.branchInfos(),
+	}
+}
+
+// Snapshot returns a copy of the session id, if it exists.
+func (s *SequentialThinkingServer) Snapshot(id string) (SessionSnapshot, bool) {
+	ts, ok := s.lookupSession(id)
+	if !ok {
+		return SessionSnapshot{}, false
+	}
+	return ts.snapshot(), true
+}
+
+// Thought returns the latest thought recorded with thoughtNumber in the snapshot.
+func (snap SessionSnapshot) Thought(thoughtNumber int) (ThoughtRecord, bool) {
+	for i := len(snap.Thoughts) - 1; i >= 0; i-- {
+		if snap.Thoughts[i].ThoughtNumber == thoughtNumber {
+			return snap.Thoughts[i], true
+		}
+	}
+	return ThoughtRecord{}, false
+}
+
+// MainLine returns the thoughts of the snapshot recorded without a branch, in order.
+func (snap SessionSnapshot) MainLine() []ThoughtRecord {
+	var thoughts []ThoughtRecord
+	for _, record := range snap.Thoughts {
+		if record.BranchID == "" {
+			thoughts = append(thoughts, record)
+		}
+	}
+	return thoughts
+}
+
+// Branch returns the branch branchID of the snapshot and its thoughts in order.
+func (snap SessionSnapshot) Branch(branchID string) (BranchInfo, []ThoughtRecord, bool) {
+	idx := slices.IndexFunc(snap.Branches, func(b BranchInfo) bool { return b.ID == branchID })
+	if idx < 0 {
+		return BranchInfo{}, nil, false
+	}
+
+	var thoughts []ThoughtRecord
+	for _, record := range snap.Thoughts {
+		if record.BranchID == branchID {
+			thoughts = append(thoughts, record)
+		}
+	}
+	return snap.Branches[idx], thoughts, true
+}
@@ -0,0 +1,216 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// connectHTTPClient connects a new MCP client to the streamable HTTP endpoint.
+func connectHTTPClient(t *testing.T, endpoint string) *mcp.ClientSession {
+	t.Helper()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "v0.0.0"}, nil)
+	cs, err := client.Connect(t.Context(), &mcp.StreamableClientTransport{
+		Endpoint:             endpoint,
+		DisableStandaloneSSE: true,
+	}, nil)
+	if err != nil {
+		t.Fatalf("connect client: %v", err)
+	}
+	return cs
+}
+
+// newHTTPTestServer serves thinking over streamable HTTP for the duration of the test.
+func newHTTPTestServer(t *testing.T, thinking *SequentialThinkingServer) *httptest.Server {
+	t.Helper()
+
+	srv, err := newServer(slog.New(slog.DiscardHandler), thinking, serverOptions{})
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server {
+		return srv
+	}, nil)
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func callThought(t *testing.T, cs *mcp.ClientSession, input ThoughtData) Output {
+	t.Helper()
+
+	result, err := cs.CallTool(t.Context(), &mcp.CallToolParams{
+		Name:      "sequentialthinking",
+		Arguments: input,
+	})
+	if err != nil {
+		t.Fatalf("call tool: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("tool error: %s", resultText(t, result))
+	}
+	return decodeOutput(t, resultText(t, result))
+}
+
+func TestSessionIDFromRequest(t *testing.T) {
+	tests := map[string]struct {
+		request *mcp.CallToolRequest
+		want    string
+	}{
+		"default: nil request": {
+			request: nil,
+			want:    defaultSessionID,
+		},
+		"default: nil session": {
+			request: &mcp.CallToolRequest{},
+			want:    defaultSessionID,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, sessionID(tt.request)); diff != "" {
+				t.Fatalf("session ID mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestThinkingSessionAppendThought(t *testing.T) {
+	tests := map[string]struct {
+		records           []ThoughtRecord
+		wantOpened        []bool
+		wantBranches      []BranchInfo
+		wantBranchThought map[string][]string
+	}{
+		"success: branches sorted with their own thoughts": {
+			records: []ThoughtRecord{
+				{Thought: "main", ThoughtNumber: 1},
+				{Thought: "c1", ThoughtNumber: 2, BranchFromThought: 1, BranchID: "c"},
+				{Thought: "a1", ThoughtNumber: 2, BranchFromThought: 1, BranchID: "a"},
+				{Thought: "c2", ThoughtNumber: 3, BranchID: "c"},
+				{Thought: "c3", ThoughtNumber: 4, BranchFromThought: 1, BranchID: "c"},
+			},
+			wantOpened: []bool{false, true, true, false, false},
+			wantBranches: []BranchInfo{
+				{ID: "a", FromThought: 1, Length: 1},
+				{ID: "c", FromThought: 1, Length: 3},
+			},
+			wantBranchThought: map[string][]string{
+				"a": {"a1"},
+				"c": {"c1", "c2", "c3"},
+			},
+		},
+		"success: unknown branch without origin stays on the main line": {
+			records: []ThoughtRecord{
+				{Thought: "main", ThoughtNumber: 1, BranchID: "x"},
+			},
+			wantOpened:        []bool{false},
+			wantBranches:      nil,
+			wantBranchThought: map[string][]string{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ts := newThinkingSession("s", time.Now())
+			var opened []bool
+			for _, record := range tt.records {
+				opened = append(opened, ts.appendThought(record))
+			}
+			if diff := cmp.Diff(tt.wantOpened, opened); diff != "" {
+				t.Fatalf("branch opened mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantBranches, ts.branchInfos()); diff != "" {
+				t.Fatalf("branches mismatch (-want +got):\n%s", diff)
+			}
+			for id, want := range tt.wantBranchThought {
+				thoughts, ok := ts.branchThoughts(id)
+				if !ok {
+					t.Fatalf("branch %q not found", id)
+				}
+				var got []string
+				for _, thought := range thoughts {
+					got = append(got, thought.Thought)
+				}
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Fatalf("branch %q thoughts mismatch (-want +got):\n%s", id, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestSequentialThinkingServerSessionsIsolated(t *testing.T) {
+	thinking := NewSequentialThinkingServer()
+	ts := newHTTPTestServer(t, thinking)
+
+	first := connectHTTPClient(t, ts.URL)
+	second := connectHTTPClient(t, ts.URL)
+	t.Cleanup(func() { second.Close() })
+
+	input := ThoughtData{
+		Thought:       "think",
+		ThoughtNumber: 1,
+		TotalThoughts: 2,
+	}
+	for _, cs := range []*mcp.ClientSession{first, first, second} {
+		callThought(t, cs, input)
+	}
+
+	if diff := cmp.Diff(2, len(thinking.History(first.ID()))); diff != "" {
+		t.Fatalf("first history length mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(1, len(thinking.History(second.ID()))); diff != "" {
+		t.Fatalf("second history length mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(2, len(thinking.SessionIDs())); diff != "" {
+		t.Fatalf("session count mismatch (-want +got):\n%s", diff)
+	}
+	for _, record := range thinking.History(first.ID()) {
+		if diff := cmp.Diff(first.ID(), record.SessionID); diff != "" {
+			t.Fatalf("record session mismatch (-want +got):\n%s", diff)
+		}
+	}
+
+	firstID := first.ID()
+	if err := first.Close(); err != nil {
+		t.Fatalf("close first session: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := thinking.lookupSession(firstID); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("session was not torn down after close")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := thinking.lookupSession(second.ID()); !ok {
+		t.Fatal("second session was torn down")
+	}
+}
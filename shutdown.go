@@ -0,0 +1,153 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Transport modes selected with the -transport flag.
+const (
+	transportStdio = "stdio"
+	transportHTTP  = "http"
+	transportBoth  = "both"
+)
+
+// errShuttingDown rejects tool calls received while the server drains.
+var errShuttingDown = errors.New("server is shutting down")
+
+// transportMode returns the transport mode selected by the -transport, -http and -unix flag values.
+//
+// Streamable HTTP is served on the -http address, the -unix socket, or both.
+// Without an explicit mode, the server uses HTTP if either is set and stdio otherwise.
+func transportMode(mode, httpAddr, unixPath string) (string, error) {
+	httpListen := httpAddr != "" || unixPath != ""
+	switch mode {
+	case "":
+		if httpListen {
+			return transportHTTP, nil
+		}
+		return transportStdio, nil
+
+	case transportStdio:
+		if httpListen {
+			return "", fmt.Errorf("-http or -unix is set but -transport is %q; use -transport=%s to serve both", mode, transportBoth)
+		}
+		return mode, nil
+
+	case transportHTTP, transportBoth:
+		if !httpListen {
+			return "", fmt.Errorf("-transport %q requires an -http address or a -unix socket", mode)
+		}
+		return mode, nil
+	}
+
+	return "", fmt.Errorf("unknown -transport %q, must be one of %s, %s or %s", mode, transportStdio, transportHTTP, transportBoth)
+}
+
+// callTracker counts the in-flight tool calls of a server so that shutdown can wait for them.
+type callTracker struct {
+	mu       sync.Mutex
+	draining bool
+	inflight sync.WaitGroup
+}
+
+// middleware is an [mcp.Middleware] that tracks tool calls and rejects new ones once draining started.
+func (ct *callTracker) middleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if method != "tools/call" {
+			return next(ctx, method, req)
+		}
+
+		ct.mu.Lock()
+		if ct.draining {
+			ct.mu.Unlock()
+			return nil, errShuttingDown
+		}
+		ct.inflight.Add(1)
+		ct.mu.Unlock()
+		defer ct.inflight.Done()
+
+		return next(ctx, method, req)
+	}
+}
+
+// drain stops accepting tool calls and waits until the in-flight calls complete or ctx is done.
+func (ct *callTracker) drain(ctx context.Context) error {
+	ct.mu.Lock()
+	ct.draining = true
+	ct.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		ct.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("drain tool calls: %w", ctx.Err())
+	}
+}
+
+// shutdown gracefully stops srv within timeout: it drains the in-flight tool calls, flushes the journals
+// of js if it is set, closes the MCP sessions and their streams, and shuts httpSrv down if it is set.
+//
+// Tool calls still running when the timeout expires are abandoned: the journals are closed under them,
+// so the thoughts they record are not persisted. The HTTP connections are then closed.
+func shutdown(logger *slog.Logger, srv *mcp.Server, httpSrv *http.Server, calls *callTracker, js *journalStore, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := calls.drain(ctx); err != nil {
+		logger.Warn("shutdown timed out with tool calls in flight", slog.Any("error", err))
+	}
+
+	var journalErr error
+	if js != nil {
+		if err := js.Close(); err != nil {
+			journalErr = fmt.Errorf("flush journals: %w", err)
+		}
+	}
+
+	for ss := range srv.Sessions() {
+		ss.Close()
+	}
+
+	if httpSrv != nil {
+		if err := httpSrv.Shutdown(ctx); err != nil {
+			logger.Warn("shutdown timed out with open HTTP connections", slog.Any("error", err))
+			httpSrv.Close()
+		}
+	}
+
+	if journalErr != nil {
+		return journalErr
+	}
+	logger.Info("shut down")
+	return nil
+}
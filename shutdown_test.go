@@ -0,0 +1,241 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestTransportMode(t *testing.T) {
+	tests := map[string]struct {
+		mode     string
+		httpAddr string
+		unixPath string
+		want     string
+		wantErr  string
+	}{
+		"success: default stdio": {
+			want: transportStdio,
+		},
+		"success: default http": {
+			httpAddr: "127.0.0.1:8080",
+			want:     transportHTTP,
+		},
+		"success: default http on unix socket": {
+			unixPath: "/run/thinking.sock",
+			want:     transportHTTP,
+		},
+		"success: both": {
+			mode:     transportBoth,
+			httpAddr: "127.0.0.1:8080",
+			want:     transportBoth,
+		},
+		"error: stdio with address": {
+			mode:     transportStdio,
+			httpAddr: "127.0.0.1:8080",
+			wantErr:  "-transport=both",
+		},
+		"error: stdio with unix socket": {
+			mode:     transportStdio,
+			unixPath: "/run/thinking.sock",
+			wantErr:  "-transport=both",
+		},
+		"error: http without address": {
+			mode:    transportHTTP,
+			wantErr: "requires an -http address or a -unix socket",
+		},
+		"error: unknown mode": {
+			mode:    "grpc",
+			wantErr: "unknown -transport",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := transportMode(tt.mode, tt.httpAddr, tt.unixPath)
+			if diff := cmp.Diff(tt.wantErr != "", err != nil); diff != "" {
+				t.Fatalf("error presence mismatch (-want +got):\n%s", diff)
+			}
+			if err != nil {
+				if diff := cmp.Diff(true, strings.Contains(err.Error(), tt.wantErr)); diff != "" {
+					t.Fatalf("error text mismatch %q (-want +got):\n%s", err, diff)
+				}
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("mode mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCallTrackerDrain(t *testing.T) {
+	var ct callTracker
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := ct.middleware(func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+
+	callErr := make(chan error, 1)
+	go func() {
+		_, err := handler(t.Context(), "tools/call", nil)
+		callErr <- err
+	}()
+	<-started
+
+	// the in-flight call outlives a short deadline
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	if err := ct.drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("drain with call in flight: got %v, want deadline exceeded", err)
+	}
+
+	// new tool calls are rejected while draining, other methods still pass
+	if _, err := handler(t.Context(), "tools/call", nil); !errors.Is(err, errShuttingDown) {
+		t.Fatalf("call while draining: got %v, want %v", err, errShuttingDown)
+	}
+
+	close(release)
+	if err := <-callErr; err != nil {
+		t.Fatalf("in-flight call: %v", err)
+	}
+	if err := ct.drain(t.Context()); err != nil {
+		t.Fatalf("drain after calls completed: %v", err)
+	}
+}
+
+func TestShutdownClosesJournalsUnderAbandonedCalls(t *testing.T) {
+	js, err := openJournalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("open journal store: %v", err)
+	}
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	first := ThoughtRecord{Thought: "first", ThoughtNumber: 1, TotalThoughts: 2, SessionID: "a", Timestamp: created}
+	js.Create("a")
+	if err := js.Append(first, created); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	var ct callTracker
+	release := make(chan struct{})
+	started := make(chan struct{})
+	appendErr := make(chan error, 1)
+	handler := ct.middleware(func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		close(started)
+		<-release
+		appendErr <- js.Append(ThoughtRecord{Thought: "late", ThoughtNumber: 2, TotalThoughts: 2, SessionID: "a", Timestamp: created}, created)
+		return nil, nil
+	})
+	go handler(t.Context(), "tools/call", nil)
+	<-started
+
+	srv := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "v0.0.0"}, nil)
+	if err := shutdown(slog.New(slog.DiscardHandler), srv, nil, &ct, js, 10*time.Millisecond); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	// the call abandoned by the shutdown fails to persist instead of reopening the journal
+	close(release)
+	if err := <-appendErr; !errors.Is(err, errJournalClosed) {
+		t.Fatalf("append of abandoned call: got %v, want %v", err, errJournalClosed)
+	}
+	jn, err := loadJournal(js.journalPath("a"))
+	if err != nil {
+		t.Fatalf("load journal: %v", err)
+	}
+	if diff := cmp.Diff([]ThoughtRecord{first}, jn.records); diff != "" {
+		t.Fatalf("records mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunGracefulShutdown(t *testing.T) {
+	t.Cleanup(restoreDefaultLogger(t))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("reserve port: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	storeDir := t.TempDir()
+	cfg := defaultConfig()
+	cfg.Transport.HTTP = addr
+	cfg.Transport.Mode = transportHTTP
+	cfg.Transport.ShutdownTimeout = duration(5 * time.Second)
+	cfg.Storage.Path = storeDir
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- run(cfg) }()
+
+	// a connected client with an open standalone stream proves the signal handler is installed
+	var cs *mcp.ClientSession
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "v0.0.0"}, nil)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		cs, err = client.Connect(t.Context(), &mcp.StreamableClientTransport{Endpoint: "http://" + addr}, nil)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("connect client: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer cs.Close()
+	callThought(t, cs, ThoughtData{Thought: "persist me", ThoughtNumber: 1, TotalThoughts: 1})
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatalf("send SIGINT: %v", err)
+	}
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("run did not return after SIGINT")
+	}
+
+	js, err := openJournalStore(storeDir)
+	if err != nil {
+		t.Fatalf("open journal store: %v", err)
+	}
+	journals, err := js.Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if diff := cmp.Diff(1, len(journals)); diff != "" {
+		t.Fatalf("journals length mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"persist me"}, thoughtTexts(journals[0].records)); diff != "" {
+		t.Fatalf("journal thoughts mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultToolName is the name of the sequential thinking tool unless configured otherwise.
+const defaultToolName = "sequentialthinking"
+
+// Built-in descriptions of the sequential thinking tool, selected by -tool-description.
+const (
+	descriptionFull    = "full"
+	descriptionCompact = "compact"
+)
+
+// fullDescription is the detailed description of the sequential thinking tool.
+const fullDescription = `A detailed tool for dynamic and reflective problem-solving through thoughts.
+This tool helps analyze problems through a flexible thinking process that can adapt and evolve.
+Each thought can build on, question, or revise previous insights as understanding deepens.
+
+When to use this tool:
+- Breaking down complex problems into steps
+- Planning and design with room for revision
+- Analysis that might need course correction
+- Problems where the full scope might not be clear initially
+- Problems that require a multi-step solution
+- Tasks that need to maintain context over multiple steps
+- Situations where irrelevant information needs to be filtered out
+
+Key features:
+- You can adjust total_thoughts up or down as you progress
+- You can question or revise previous thoughts
+- You can add more thoughts even after reaching what seemed like the end
+- You can express uncertainty and explore alternative approaches
+- Not every thought needs to build linearly - you can branch or backtrack
+- Generates a solution hypothesis
+- Verifies the hypothesis based on the Chain of Thought steps
+- Repeats the process until satisfied
+- Provides a correct answer
+
+Parameters explained:
+- thought (string): Required. Your current thinking step, which can include:
+  * Regular analytical steps
+  * Revisions of previous thoughts
+  * Questions about previous decisions
+  * Realizations about needing more analysis
+  * Changes in approach
+  * Hypothesis generation
+  * Hypothesis verification
+- nextThoughtNeeded (boolean): Required. True if you need more thinking, even if at what seemed like the end
+- thoughtNumber (integer): Required. Current number in sequence (can go beyond initial total if needed)
+- totalThoughts (integer): Required. Current estimate of thoughts needed (can be adjusted up/down)
+- isRevision (boolean): Optional. A boolean indicating if this thought revises previous thinking
+- revisesThought (integer): Optional. If is_revision is true, which thought number is being reconsidered
+- branchFromThought (integer): Optional. If branching, which thought number is the branching point
+- branchId (string): Optional. Identifier for the current branch (if any)
+- needsMoreThoughts (boolean): Optional. If reaching end but realizing more thoughts needed
+
+You should:
+1. Start with an initial estimate of needed thoughts, but be ready to adjust
+2. Feel free to question or revise previous thoughts
+3. Don't hesitate to add more thoughts if needed, even at the "end"
+4. Express uncertainty when present
+5. Mark thoughts that revise previous thinking or branch into new paths
+6. Ignore information that is irrelevant to the current step
+7. Generate a solution hypothesis when appropriate
+8. Verify the hypothesis based on the Chain of Thought steps
+9. Repeat the process until satisfied with the solution
+10. Provide a single, ideally correct answer as the final output
+11. Only set nextThoughtNeeded to false when truly done and a satisfactory answer is reached`
+
+// compactDescription is a short description of the sequential thinking tool, for models that do better
+// with fewer instructions.
+const compactDescription = `Record one step of your reasoning per call, for problems that need several steps, planning or course correction.
+
+- thought: the current step: analysis, a hypothesis, its verification, or a revision.
+- thoughtNumber, totalThoughts: the number of this step and your estimate of the total. Adjust the estimate as you go; the number may exceed it.
+- nextThoughtNeeded: false only once you reached a satisfactory, verified answer.
+- isRevision with revisesThought: this step reconsiders an earlier thought.
+- branchFromThought with branchId: explore an alternative from an earlier thought. branchId alone continues the branch.
+- needsMoreThoughts: you reached the planned end but need more steps.`
+
+// validToolName matches the tool names allowed by the MCP specification.
+var validToolName = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,128}$`)
+
+// reservedToolNames lists the names of the other tools of the server.
+var reservedToolNames = []string{"get_thought", "list_thoughts", "list_branches", "get_session_summary", "export_session"}
+
+// toolSpec describes the sequential thinking tool advertised by the server.
+type toolSpec struct {
+	name        string
+	description string
+	annotations *mcp.ToolAnnotations
+	// fieldDescriptions overrides the descriptions of the input schema properties, by JSON field name.
+	fieldDescriptions map[string]string
+}
+
+// newToolSpec returns the tool described by o.
+func newToolSpec(o ToolOptions) (*toolSpec, error) {
+	if !validToolName.MatchString(o.Name) {
+		return nil, fmt.Errorf("-tool-name: must be 1 to 128 letters, digits, '_', '-' or '.', got %q", o.Name)
+	}
+	if slices.Contains(reservedToolNames, o.Name) {
+		return nil, fmt.Errorf("-tool-name: %q is the name of another tool", o.Name)
+	}
+
+	var description string
+	switch {
+	case o.DescriptionFile != "":
+		if o.Description != "" {
+			return nil, errors.New("-tool-description and -tool-description-file are mutually exclusive")
+		}
+		data, err := os.ReadFile(o.DescriptionFile)
+		if err != nil {
+			return nil, fmt.Errorf("-tool-description-file: %w", err)
+		}
+		description = strings.TrimSpace(string(data))
+		if description == "" {
+			return nil, errors.New("-tool-description-file: must not be empty")
+		}
+	case o.Description == "", o.Description == descriptionFull:
+		description = fullDescription
+	case o.Description == descriptionCompact:
+		description = compactDescription
+	default:
+		description = o.Description
+	}
+
+	a := o.Annotations
+	return &toolSpec{
+		name:        o.Name,
+		description: description,
+		annotations: &mcp.ToolAnnotations{
+			Title:           a.Title,
+			ReadOnlyHint:    a.ReadOnlyHint,
+			DestructiveHint: new(a.DestructiveHint),
+			IdempotentHint:  a.IdempotentHint,
+			OpenWorldHint:   new(a.OpenWorldHint),
+		},
+		fieldDescriptions: o.FieldDescriptions,
+	}, nil
+}
+
+// tool returns the MCP tool of spec, with its input and output schemas.
+func (spec *toolSpec) tool() (*mcp.Tool, error) {
+	inputSchema, err := jsonschema.For[ThoughtData](&jsonschema.ForOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("parse ThoughtData: %w", err)
+	}
+	inputSchema.Properties["thoughtNumber"].Minimum = new(float64(1))
+	inputSchema.Properties["totalThoughts"].Minimum = new(float64(1))
+	inputSchema.Properties["revisesThought"].Minimum = new(float64(1))
+	inputSchema.Properties["branchFromThought"].Minimum = new(float64(1))
+	for field, description := range spec.fieldDescriptions {
+		prop, ok := inputSchema.Properties[field]
+		if !ok {
+			return nil, fmt.Errorf("tool.fieldDescriptions: %q is not a field of the tool input", field)
+		}
+		prop.Description = description
+	}
+
+	outputSchema, err := jsonschema.For[Output](&jsonschema.ForOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("parse Output: %w", err)
+	}
+
+	return &mcp.Tool{
+		Name:         spec.name,
+		Annotations:  spec.annotations,
+		Description:  spec.description,
+		InputSchema:  inputSchema,
+		OutputSchema: outputSchema,
+	}, nil
+}
+
+// toolNames returns the names of the tools of the server.
+func (spec *toolSpec) toolNames() []string {
+	return append([]string{spec.name}, reservedToolNames...)
+}
@@ -0,0 +1,266 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestNewToolSpec(t *testing.T) {
+	descriptionFile := filepath.Join(t.TempDir(), "description.md")
+	if err := os.WriteFile(descriptionFile, []byte("\nThink in numbered steps.\n"), 0o600); err != nil {
+		t.Fatalf("write description: %v", err)
+	}
+	emptyFile := filepath.Join(t.TempDir(), "empty.md")
+	if err := os.WriteFile(emptyFile, []byte("\n"), 0o600); err != nil {
+		t.Fatalf("write description: %v", err)
+	}
+
+	tests := map[string]struct {
+		modify          func(o *ToolOptions)
+		wantName        string
+		wantDescription string
+		wantAnnotations *mcp.ToolAnnotations
+		wantErr         string
+	}{
+		"success: defaults": {
+			wantName:        "sequentialthinking",
+			wantDescription: fullDescription,
+			wantAnnotations: &mcp.ToolAnnotations{ReadOnlyHint: true, DestructiveHint: new(false), IdempotentHint: true, OpenWorldHint: new(true)},
+		},
+		"success: compact description and annotations": {
+			modify: func(o *ToolOptions) {
+				o.Name = "think"
+				o.Description = descriptionCompact
+				o.Annotations = ToolAnnotationsOptions{Title: "Think", DestructiveHint: true}
+			},
+			wantName:        "think",
+			wantDescription: compactDescription,
+			wantAnnotations: &mcp.ToolAnnotations{Title: "Think", DestructiveHint: new(true), OpenWorldHint: new(false)},
+		},
+		"success: custom description": {
+			modify:          func(o *ToolOptions) { o.Description = "Reason step by step." },
+			wantName:        "sequentialthinking",
+			wantDescription: "Reason step by step.",
+			wantAnnotations: &mcp.ToolAnnotations{ReadOnlyHint: true, DestructiveHint: new(false), IdempotentHint: true, OpenWorldHint: new(true)},
+		},
+		"success: description file": {
+			modify:          func(o *ToolOptions) { o.DescriptionFile = descriptionFile },
+			wantName:        "sequentialthinking",
+			wantDescription: "Think in numbered steps.",
+			wantAnnotations: &mcp.ToolAnnotations{ReadOnlyHint: true, DestructiveHint: new(false), IdempotentHint: true, OpenWorldHint: new(true)},
+		},
+		"error: invalid name": {
+			modify:  func(o *ToolOptions) { o.Name = "sequential thinking" },
+			wantErr: `-tool-name: must be 1 to 128 letters, digits, '_', '-' or '.', got "sequential thinking"`,
+		},
+		"error: reserved name": {
+			modify:  func(o *ToolOptions) { o.Name = "get_thought" },
+			wantErr: `-tool-name: "get_thought" is the name of another tool`,
+		},
+		"success: explicit full description": {
+			modify:          func(o *ToolOptions) { o.Description = descriptionFull },
+			wantName:        "sequentialthinking",
+			wantDescription: fullDescription,
+			wantAnnotations: &mcp.ToolAnnotations{ReadOnlyHint: true, DestructiveHint: new(false), IdempotentHint: true, OpenWorldHint: new(true)},
+		},
+		"error: description and file": {
+			modify: func(o *ToolOptions) {
+				o.Description = descriptionCompact
+				o.DescriptionFile = descriptionFile
+			},
+			wantErr: "-tool-description and -tool-description-file are mutually exclusive",
+		},
+		"error: full description and file": {
+			modify: func(o *ToolOptions) {
+				o.Description = descriptionFull
+				o.DescriptionFile = descriptionFile
+			},
+			wantErr: "-tool-description and -tool-description-file are mutually exclusive",
+		},
+		"error: empty description file": {
+			modify:  func(o *ToolOptions) { o.DescriptionFile = emptyFile },
+			wantErr: "-tool-description-file: must not be empty",
+		},
+		"error: missing description file": {
+			modify:  func(o *ToolOptions) { o.DescriptionFile = filepath.Join(t.TempDir(), "missing.md") },
+			wantErr: "-tool-description-file: open",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			o := defaultConfig().Tool
+			if tt.modify != nil {
+				tt.modify(&o)
+			}
+
+			spec, err := newToolSpec(o)
+			if diff := cmp.Diff(tt.wantErr != "", err != nil); diff != "" {
+				t.Fatalf("error presence mismatch %v (-want +got):\n%s", err, diff)
+			}
+			if err != nil {
+				if diff := cmp.Diff(true, strings.HasPrefix(err.Error(), tt.wantErr)); diff != "" {
+					t.Fatalf("error text mismatch %q (-want +got):\n%s", err, diff)
+				}
+				return
+			}
+			if diff := cmp.Diff(tt.wantName, spec.name); diff != "" {
+				t.Fatalf("name mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantDescription, spec.description); diff != "" {
+				t.Fatalf("description mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantAnnotations, spec.annotations); diff != "" {
+				t.Fatalf("annotations mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestToolSpecFieldDescriptions(t *testing.T) {
+	tests := map[string]struct {
+		fieldDescriptions map[string]string
+		want              map[string]string
+		wantErr           string
+	}{
+		"success: defaults": {
+			want: map[string]string{
+				"thought":  "Your current thinking step",
+				"branchId": "Branch identifier",
+			},
+		},
+		"success: overrides": {
+			fieldDescriptions: map[string]string{"thought": "One step of the analysis"},
+			want: map[string]string{
+				"thought":  "One step of the analysis",
+				"branchId": "Branch identifier",
+			},
+		},
+		"error: unknown field": {
+			fieldDescriptions: map[string]string{"confidence": "How sure you are"},
+			wantErr:           `tool.fieldDescriptions: "confidence" is not a field of the tool input`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			o := defaultConfig().Tool
+			o.FieldDescriptions = tt.fieldDescriptions
+			spec, err := newToolSpec(o)
+			if err != nil {
+				t.Fatalf("new tool spec: %v", err)
+			}
+
+			tool, err := spec.tool()
+			if diff := cmp.Diff(tt.wantErr != "", err != nil); diff != "" {
+				t.Fatalf("error presence mismatch %v (-want +got):\n%s", err, diff)
+			}
+			if err != nil {
+				if diff := cmp.Diff(tt.wantErr, err.Error()); diff != "" {
+					t.Fatalf("error text mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			schema := tool.InputSchema.(*jsonschema.Schema)
+			got := map[string]string{}
+			for field := range tt.want {
+				got[field] = schema.Properties[field].Description
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("field descriptions mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestServerCustomTool(t *testing.T) {
+	o := defaultConfig().Tool
+	o.Name = "think"
+	o.Description = descriptionCompact
+	o.Annotations.Title = "Think"
+	spec, err := newToolSpec(o)
+	if err != nil {
+		t.Fatalf("new tool spec: %v", err)
+	}
+	srv, err := newServer(slog.New(slog.DiscardHandler), NewSequentialThinkingServer(), serverOptions{tool: spec})
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	ss, err := srv.Connect(t.Context(), serverTransport, nil)
+	if err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+	t.Cleanup(func() { ss.Close() })
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "v0.0.0"}, nil)
+	cs, err := client.Connect(t.Context(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("connect client: %v", err)
+	}
+	t.Cleanup(func() { cs.Close() })
+
+	if diff := cmp.Diff(defaultInstructions("think"), cs.InitializeResult().Instructions); diff != "" {
+		t.Fatalf("instructions mismatch (-want +got):\n%s", diff)
+	}
+	tools, err := cs.ListTools(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("list tools: %v", err)
+	}
+	var got *mcp.Tool
+	for _, tool := range tools.Tools {
+		if tool.Name == defaultToolName {
+			t.Fatalf("default tool %q listed alongside %q", defaultToolName, "think")
+		}
+		if tool.Name == "think" {
+			got = tool
+		}
+	}
+	if got == nil {
+		t.Fatalf("tool %q not listed", "think")
+	}
+	if diff := cmp.Diff(compactDescription, got.Description); diff != "" {
+		t.Fatalf("description mismatch (-want +got):\n%s", diff)
+	}
+	for _, tool := range tools.Tools {
+		if strings.Contains(tool.Description, defaultToolName) {
+			t.Fatalf("tool %q description names %q instead of %q: %s", tool.Name, defaultToolName, "think", tool.Description)
+		}
+	}
+	if diff := cmp.Diff(spec.annotations, got.Annotations); diff != "" {
+		t.Fatalf("annotations mismatch (-want +got):\n%s", diff)
+	}
+
+	res, err := cs.CallTool(t.Context(), &mcp.CallToolParams{
+		Name:      "think",
+		Arguments: ThoughtData{Thought: "frame the problem", ThoughtNumber: 1, TotalThoughts: 2, NextThoughtNeeded: true},
+	})
+	if err != nil {
+		t.Fatalf("call tool: %v", err)
+	}
+	if res.IsError {
+		t.Fatalf("call tool: unexpected tool error %v", res.Content)
+	}
+}
@@ -0,0 +1,624 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// traceparentKey is the W3C Trace Context header, also read from the _meta of tool calls.
+const traceparentKey = "traceparent"
+
+const (
+	// traceServiceName is the service.name resource attribute of exported spans.
+	traceServiceName = "mcp-sequential-thinking"
+	// traceScopeName is the instrumentation scope of exported spans.
+	traceScopeName = "github.com/zchee/mcp-sequential-thinking"
+	// otlpTracesPath is appended to an OTLP endpoint given without a path.
+	otlpTracesPath = "/v1/traces"
+	// traceFlushInterval is how often ended spans are exported.
+	traceFlushInterval = 5 * time.Second
+	// traceBatchSize is the number of ended spans that triggers an export before the interval.
+	traceBatchSize = 512
+)
+
+// traceFlagSampled is the sampled flag of the W3C trace flags. Spans of traces not sampled by the
+// caller are recorded to propagate the trace, but not exported.
+const traceFlagSampled = 0x01
+
+// OTLP span kinds and status codes.
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+
+	spanStatusError = 2
+)
+
+type (
+	traceID [16]byte
+	spanID  [8]byte
+)
+
+// spanContext identifies a span within a trace.
+type spanContext struct {
+	traceID traceID
+	spanID  spanID
+	flags   byte
+}
+
+// sampled reports whether the span is sampled, and so exported.
+func (sc spanContext) sampled() bool {
+	return sc.flags&traceFlagSampled != 0
+}
+
+// parseTraceparent parses a version 00 W3C traceparent value.
+func parseTraceparent(s string) (spanContext, bool) {
+	var sc spanContext
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || (parts[0] == "00" && len(parts) != 4) {
+		return sc, false
+	}
+	if len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.traceID[:], []byte(parts[1])); err != nil || sc.traceID == (traceID{}) {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.spanID[:], []byte(parts[2])); err != nil || sc.spanID == (spanID{}) {
+		return sc, false
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return sc, false
+	}
+	sc.flags = byte(flags)
+	return sc, true
+}
+
+// traceparent formats sc as a W3C traceparent value.
+func (sc spanContext) traceparent() string {
+	return fmt.Sprintf("00-%x-%x-%02x", sc.traceID[:], sc.spanID[:], sc.flags)
+}
+
+// requestTraceparent returns the remote parent of request from the traceparent of its _meta,
+// or else from the traceparent header of its HTTP request.
+func requestTraceparent(request *mcp.CallToolRequest) (spanContext, bool) {
+	if request.Params != nil {
+		if s, ok := request.Params.Meta[traceparentKey].(string); ok {
+			if sc, ok := parseTraceparent(s); ok {
+				return sc, true
+			}
+		}
+	}
+	if request.Extra != nil && request.Extra.Header != nil {
+		return parseTraceparent(request.Extra.Header.Get(traceparentKey))
+	}
+	return spanContext{}, false
+}
+
+// spanAttr is a span attribute with a string, int or bool value.
+type spanAttr struct {
+	key   string
+	value any
+}
+
+// span is a timed operation of a trace.
+type span struct {
+	sc     spanContext
+	parent spanID
+	name   string
+	kind   int
+	start  time.Time
+	end    time.Time
+	attrs  []spanAttr
+	links  []spanContext
+
+	statusCode    int
+	statusMessage string
+}
+
+// tracer records a span for every tool call, parented to a span covering the MCP session of the call,
+// and exports the ended spans as OTLP JSON.
+//
+// The session span continues the trace of the traceparent of the first call of the session, and
+// inherits its sampled flag: the spans of a trace the caller did not sample are not exported.
+// A session without a traceparent starts a sampled trace.
+// A later call carrying a traceparent of another trace links to it.
+type tracer struct {
+	logger    *slog.Logger
+	exporters []spanExporter
+	// thinkingTool is the name of the sequential thinking tool, whose spans describe the thought.
+	thinkingTool string
+
+	mu       sync.Mutex
+	sessions map[string]*span
+	ended    []*span
+
+	flush chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+// newTracer returns a tracer exporting to the OTLP/HTTP endpoint and appending to the file at filePath,
+// or nil if neither is set.
+func newTracer(logger *slog.Logger, endpoint, filePath string) (*tracer, error) {
+	var exporters []spanExporter
+	if endpoint != "" {
+		e, err := newOTLPExporter(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, e)
+	}
+	if filePath != "" {
+		f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("open trace file: %w", err)
+		}
+		exporters = append(exporters, &fileExporter{f: f})
+	}
+	if len(exporters) == 0 {
+		return nil, nil
+	}
+
+	t := &tracer{
+		logger:       logger,
+		exporters:    exporters,
+		thinkingTool: defaultToolName,
+		sessions:     make(map[string]*span),
+		flush:        make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	go t.run()
+	return t, nil
+}
+
+// run exports the ended spans periodically and when a batch is full, until stop is closed.
+func (t *tracer) run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(traceFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-t.flush:
+		case <-t.stop:
+			return
+		}
+		t.export(context.Background())
+	}
+}
+
+// middleware is an [mcp.Middleware] that records a span for every tool call.
+func (t *tracer) middleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		request, ok := req.(*mcp.CallToolRequest)
+		if method != "tools/call" || !ok || request.Params == nil {
+			return next(ctx, method, req)
+		}
+
+		remote, hasRemote := requestTraceparent(request)
+		session := t.sessionSpan(request, remote, hasRemote)
+		sp := &span{
+			sc: spanContext{
+				traceID: session.sc.traceID,
+				spanID:  newSpanID(),
+				flags:   session.sc.flags,
+			},
+			parent: session.sc.spanID,
+			name:   method + " " + request.Params.Name,
+			kind:   spanKindServer,
+			start:  time.Now(),
+			attrs: []spanAttr{
+				{"mcp.method.name", method},
+				{"mcp.session.id", sessionID(request)},
+				{"gen_ai.tool.name", request.Params.Name},
+			},
+		}
+		if hasRemote && remote.traceID != sp.sc.traceID {
+			sp.links = append(sp.links, remote)
+		}
+		if request.Params.Name == t.thinkingTool {
+			sp.attrs = append(sp.attrs, thoughtAttrs(request.Params.Arguments)...)
+		}
+
+		res, err := next(ctx, method, req)
+
+		sp.end = time.Now()
+		if err != nil {
+			sp.statusCode = spanStatusError
+			sp.statusMessage = err.Error()
+		} else if result, ok := res.(*mcp.CallToolResult); ok && result.IsError {
+			sp.statusCode = spanStatusError
+			sp.attrs = append(sp.attrs, spanAttr{"error.type", toolErrorReason(result)})
+		}
+		t.record(sp)
+
+		return res, err
+	}
+}
+
+// thoughtAttrs returns the span attributes describing the sequentialthinking arguments.
+func thoughtAttrs(arguments []byte) []spanAttr {
+	var input ThoughtData
+	if err := sonic.ConfigFastest.Unmarshal(arguments, &input); err != nil {
+		return nil
+	}
+
+	attrs := []spanAttr{
+		{"sequential_thinking.thought_number", input.ThoughtNumber},
+		{"sequential_thinking.total_thoughts", input.TotalThoughts},
+		{"sequential_thinking.next_thought_needed", input.NextThoughtNeeded},
+		{"sequential_thinking.is_revision", input.IsRevision},
+	}
+	if input.IsRevision {
+		attrs = append(attrs, spanAttr{"sequential_thinking.revises_thought", input.RevisesThought})
+	}
+	if input.BranchID != "" {
+		attrs = append(attrs, spanAttr{"sequential_thinking.branch_id", input.BranchID})
+	}
+	if input.BranchFromThought > 0 {
+		attrs = append(attrs, spanAttr{"sequential_thinking.branch_from_thought", input.BranchFromThought})
+	}
+	return attrs
+}
+
+// sessionSpan returns the span of the MCP session of request, starting it on the first call of the session.
+// The span ends when the session closes.
+func (t *tracer) sessionSpan(request *mcp.CallToolRequest, remote spanContext, hasRemote bool) *span {
+	id := sessionID(request)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if sp, ok := t.sessions[id]; ok {
+		return sp
+	}
+	sp := &span{
+		sc: spanContext{
+			traceID: newTraceID(),
+			spanID:  newSpanID(),
+			flags:   traceFlagSampled,
+		},
+		name:  "mcp.session",
+		kind:  spanKindInternal,
+		start: time.Now(),
+		attrs: []spanAttr{
+			{"mcp.session.id", id},
+		},
+	}
+	if hasRemote {
+		sp.sc.traceID = remote.traceID
+		sp.sc.flags = remote.flags
+		sp.parent = remote.spanID
+	}
+	if principal := requestPrincipal(request); principal != "" {
+		sp.attrs = append(sp.attrs, spanAttr{"enduser.id", principal})
+	}
+	t.sessions[id] = sp
+
+	if request.Session != nil {
+		go func(ss *mcp.ServerSession) {
+			_ = ss.Wait()
+			t.endSession(id, sp)
+		}(request.Session)
+	}
+	return sp
+}
+
+// endSession ends the session span sp if it is still open.
+func (t *tracer) endSession(id string, sp *span) {
+	t.mu.Lock()
+	open := t.sessions[id] == sp
+	if open {
+		delete(t.sessions, id)
+	}
+	t.mu.Unlock()
+
+	if open {
+		sp.end = time.Now()
+		t.record(sp)
+	}
+}
+
+// record queues the ended span sp for export, unless it is not sampled.
+func (t *tracer) record(sp *span) {
+	if !sp.sc.sampled() {
+		return
+	}
+	t.mu.Lock()
+	t.ended = append(t.ended, sp)
+	full := len(t.ended) >= traceBatchSize
+	t.mu.Unlock()
+
+	if full {
+		select {
+		case t.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// export sends the queued spans to every exporter. Export errors are logged and the spans are dropped.
+func (t *tracer) export(ctx context.Context) {
+	t.mu.Lock()
+	spans := t.ended
+	t.ended = nil
+	t.mu.Unlock()
+
+	if len(spans) == 0 {
+		return
+	}
+	payload, err := encodeSpans(spans)
+	if err != nil {
+		t.logger.Warn("encode spans", slog.Any("error", err))
+		return
+	}
+	for _, e := range t.exporters {
+		if err := e.exportSpans(ctx, payload); err != nil {
+			t.logger.Warn("export spans", slog.Int("spans", len(spans)), slog.Any("error", err))
+		}
+	}
+}
+
+// shutdown ends the open session spans, exports every queued span and closes the exporters.
+// Calls after the first return the result of the first.
+func (t *tracer) shutdown(ctx context.Context) error {
+	t.shutdownOnce.Do(func() {
+		t.mu.Lock()
+		now := time.Now()
+		for id, sp := range t.sessions {
+			sp.end = now
+			if sp.sc.sampled() {
+				t.ended = append(t.ended, sp)
+			}
+			delete(t.sessions, id)
+		}
+		t.mu.Unlock()
+
+		close(t.stop)
+		<-t.done
+		t.export(ctx)
+
+		var errs []error
+		for _, e := range t.exporters {
+			errs = append(errs, e.close())
+		}
+		t.shutdownErr = errors.Join(errs...)
+	})
+	return t.shutdownErr
+}
+
+func newTraceID() traceID {
+	var id traceID
+	rand.Read(id[:])
+	return id
+}
+
+func newSpanID() spanID {
+	var id spanID
+	rand.Read(id[:])
+	return id
+}
+
+// spanExporter sends OTLP JSON encoded export requests.
+type spanExporter interface {
+	exportSpans(ctx context.Context, payload []byte) error
+	close() error
+}
+
+// otlpExporter posts spans to an OTLP/HTTP collector.
+type otlpExporter struct {
+	url    string
+	client *http.Client
+}
+
+// newOTLPExporter returns an exporter for the OTLP/HTTP endpoint, appending [otlpTracesPath] if it has no path.
+func newOTLPExporter(endpoint string) (*otlpExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: must be an http or https URL", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = otlpTracesPath
+	}
+	return &otlpExporter{
+		url:    u.String(),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (e *otlpExporter) exportSpans(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("post %s: %s", e.url, resp.Status)
+	}
+	return nil
+}
+
+func (e *otlpExporter) close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// fileExporter appends every export request to a file as a line of JSON.
+type fileExporter struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func (e *fileExporter) exportSpans(_ context.Context, payload []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, err := e.f.Write(append(payload, '\n'))
+	return err
+}
+
+func (e *fileExporter) close() error {
+	return e.f.Close()
+}
+
+// OTLP JSON encoding of an ExportTraceServiceRequest.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Flags             uint32         `json:"flags"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Links             []otlpLink     `json:"links,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpLink struct {
+		TraceID string `json:"traceId"`
+		SpanID  string `json:"spanId"`
+		Flags   uint32 `json:"flags"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+	otlpAnyValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    string  `json:"intValue,omitempty"`
+		BoolValue   *bool   `json:"boolValue,omitempty"`
+	}
+)
+
+// encodeSpans encodes spans as an OTLP JSON ExportTraceServiceRequest.
+func encodeSpans(spans []*span) ([]byte, error) {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, sp := range spans {
+		o := otlpSpan{
+			TraceID:           hex.EncodeToString(sp.sc.traceID[:]),
+			SpanID:            hex.EncodeToString(sp.sc.spanID[:]),
+			Flags:             uint32(sp.sc.flags),
+			Name:              sp.name,
+			Kind:              sp.kind,
+			StartTimeUnixNano: strconv.FormatInt(sp.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(sp.end.UnixNano(), 10),
+			Attributes:        otlpAttributes(sp.attrs),
+			Status: otlpStatus{
+				Code:    sp.statusCode,
+				Message: sp.statusMessage,
+			},
+		}
+		if sp.parent != (spanID{}) {
+			o.ParentSpanID = hex.EncodeToString(sp.parent[:])
+		}
+		for _, link := range sp.links {
+			o.Links = append(o.Links, otlpLink{
+				TraceID: hex.EncodeToString(link.traceID[:]),
+				SpanID:  hex.EncodeToString(link.spanID[:]),
+				Flags:   uint32(link.flags),
+			})
+		}
+		out = append(out, o)
+	}
+
+	return sonic.ConfigFastest.Marshal(&otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: otlpAttributes([]spanAttr{{"service.name", traceServiceName}}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: traceScopeName},
+				Spans: out,
+			}},
+		}},
+	})
+}
+
+func otlpAttributes(attrs []spanAttr) []otlpKeyValue {
+	kvs := make([]otlpKeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		kv := otlpKeyValue{Key: attr.key}
+		switch v := attr.value.(type) {
+		case string:
+			kv.Value.StringValue = &v
+		case int:
+			kv.Value.IntValue = strconv.Itoa(v)
+		case bool:
+			kv.Value.BoolValue = &v
+		default:
+			s := fmt.Sprint(v)
+			kv.Value.StringValue = &s
+		}
+		kvs = append(kvs, kv)
+	}
+	return kvs
+}
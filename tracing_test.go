@@ -0,0 +1,542 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-json-experiment/json"
+	"github.com/google/go-cmp/cmp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	testTraceparent      = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	testOtherTraceparent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := map[string]struct {
+		in     string
+		wantOK bool
+	}{
+		"success: sampled": {
+			in:     testTraceparent,
+			wantOK: true,
+		},
+		"success: not sampled": {
+			in:     "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			wantOK: true,
+		},
+		"success: future version with extra fields": {
+			in:     "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			wantOK: true,
+		},
+		"error: empty": {},
+		"error: version 00 with extra fields": {
+			in: testTraceparent + "-extra",
+		},
+		"error: invalid version": {
+			in: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+		"error: zero trace id": {
+			in: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		},
+		"error: zero span id": {
+			in: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		},
+		"error: short trace id": {
+			in: "00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+		},
+		"error: not hex": {
+			in: "00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			sc, ok := parseTraceparent(tt.in)
+			if diff := cmp.Diff(tt.wantOK, ok); diff != "" {
+				t.Fatalf("ok mismatch (-want +got):\n%s", diff)
+			}
+			if ok && tt.in[:2] == "00" {
+				if diff := cmp.Diff(tt.in, sc.traceparent()); diff != "" {
+					t.Fatalf("round trip mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+// readTraceFile returns the spans of every export request in the trace file at path.
+func readTraceFile(t *testing.T, path string) []otlpSpan {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open trace file: %v", err)
+	}
+	defer f.Close()
+
+	var spans []otlpSpan
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var req otlpRequest
+		if err := json.Unmarshal(sc.Bytes(), &req); err != nil {
+			t.Fatalf("decode export request: %v", err)
+		}
+		for _, rs := range req.ResourceSpans {
+			if diff := cmp.Diff(traceServiceName, *rs.Resource.Attributes[0].Value.StringValue); diff != "" {
+				t.Fatalf("service name mismatch (-want +got):\n%s", diff)
+			}
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("read trace file: %v", err)
+	}
+	return spans
+}
+
+// spanAttrValues returns the attributes of sp as strings keyed by attribute name.
+func spanAttrValues(sp otlpSpan) map[string]string {
+	values := make(map[string]string, len(sp.Attributes))
+	for _, kv := range sp.Attributes {
+		switch {
+		case kv.Value.StringValue != nil:
+			values[kv.Key] = *kv.Value.StringValue
+		case kv.Value.BoolValue != nil:
+			values[kv.Key] = map[bool]string{true: "true", false: "false"}[*kv.Value.BoolValue]
+		default:
+			values[kv.Key] = kv.Value.IntValue
+		}
+	}
+	return values
+}
+
+func TestTracerFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	traces, err := newTracer(slog.New(slog.DiscardHandler), "", path)
+	if err != nil {
+		t.Fatalf("new tracer: %v", err)
+	}
+	srv, err := newServer(slog.New(slog.DiscardHandler), NewSequentialThinkingServer(), serverOptions{})
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	srv.AddReceivingMiddleware(traces.middleware)
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	ss, err := srv.Connect(t.Context(), serverTransport, nil)
+	if err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+	t.Cleanup(func() { ss.Close() })
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "v0.0.0"}, nil)
+	cs, err := client.Connect(t.Context(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("connect client: %v", err)
+	}
+	t.Cleanup(func() { cs.Close() })
+
+	calls := []*mcp.CallToolParams{
+		{
+			Meta:      mcp.Meta{traceparentKey: testTraceparent},
+			Name:      "sequentialthinking",
+			Arguments: resourceThoughts[0],
+		},
+		{
+			Name:      "sequentialthinking",
+			Arguments: resourceThoughts[1],
+		},
+		{
+			Name:      "sequentialthinking",
+			Arguments: ThoughtData{Thought: "revise nothing", ThoughtNumber: 3, TotalThoughts: 3, IsRevision: true, RevisesThought: 9},
+		},
+		{
+			Meta:      mcp.Meta{traceparentKey: testOtherTraceparent},
+			Name:      "get_thought",
+			Arguments: GetThoughtInput{ThoughtNumber: 1},
+		},
+	}
+	for _, params := range calls {
+		if _, err := cs.CallTool(t.Context(), params); err != nil {
+			t.Fatalf("call %s: %v", params.Name, err)
+		}
+	}
+	if err := traces.shutdown(t.Context()); err != nil {
+		t.Fatalf("shutdown tracer: %v", err)
+	}
+
+	spans := readTraceFile(t, path)
+	if diff := cmp.Diff(5, len(spans)); diff != "" {
+		t.Fatalf("spans length mismatch (-want +got):\n%s", diff)
+	}
+
+	// the session span ends last and continues the trace of the first call
+	session := spans[len(spans)-1]
+	remote, _ := parseTraceparent(testTraceparent)
+	other, _ := parseTraceparent(testOtherTraceparent)
+	wantSession := otlpSpan{
+		TraceID:      "4bf92f3577b34da6a3ce929d0e0e4736",
+		ParentSpanID: "00f067aa0ba902b7",
+		Flags:        1,
+		Name:         "mcp.session",
+		Kind:         spanKindInternal,
+	}
+	ignoreSpan := func(sp otlpSpan) otlpSpan {
+		sp.SpanID, sp.StartTimeUnixNano, sp.EndTimeUnixNano, sp.Attributes = "", "", "", nil
+		return sp
+	}
+	if diff := cmp.Diff(wantSession, ignoreSpan(session)); diff != "" {
+		t.Fatalf("session span mismatch (-want +got):\n%s", diff)
+	}
+
+	wantCalls := []otlpSpan{
+		{Name: "tools/call sequentialthinking"},
+		{Name: "tools/call sequentialthinking"},
+		{Name: "tools/call sequentialthinking", Status: otlpStatus{Code: spanStatusError}},
+		{Name: "tools/call get_thought", Links: []otlpLink{{TraceID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331", Flags: 1}}},
+	}
+	for i := range wantCalls {
+		wantCalls[i].TraceID = session.TraceID
+		wantCalls[i].ParentSpanID = session.SpanID
+		wantCalls[i].Flags = uint32(remote.flags)
+		wantCalls[i].Kind = spanKindServer
+	}
+	gotCalls := make([]otlpSpan, 0, len(spans)-1)
+	for _, sp := range spans[:len(spans)-1] {
+		gotCalls = append(gotCalls, ignoreSpan(sp))
+	}
+	if diff := cmp.Diff(wantCalls, gotCalls); diff != "" {
+		t.Fatalf("call spans mismatch (-want +got):\n%s", diff)
+	}
+	if other.traceID == remote.traceID {
+		t.Fatal("test traceparents share a trace")
+	}
+
+	wantAttrs := []map[string]string{
+		{
+			"mcp.method.name":                         "tools/call",
+			"mcp.session.id":                          defaultSessionID,
+			"gen_ai.tool.name":                        "sequentialthinking",
+			"sequential_thinking.thought_number":      "1",
+			"sequential_thinking.total_thoughts":      "3",
+			"sequential_thinking.next_thought_needed": "true",
+			"sequential_thinking.is_revision":         "false",
+		},
+		{
+			"mcp.method.name":                         "tools/call",
+			"mcp.session.id":                          defaultSessionID,
+			"gen_ai.tool.name":                        "sequentialthinking",
+			"sequential_thinking.thought_number":      "2",
+			"sequential_thinking.total_thoughts":      "3",
+			"sequential_thinking.next_thought_needed": "true",
+			"sequential_thinking.is_revision":         "false",
+			"sequential_thinking.branch_id":           "alt/1",
+			"sequential_thinking.branch_from_thought": "1",
+		},
+		{
+			"mcp.method.name":                         "tools/call",
+			"mcp.session.id":                          defaultSessionID,
+			"gen_ai.tool.name":                        "sequentialthinking",
+			"sequential_thinking.thought_number":      "3",
+			"sequential_thinking.total_thoughts":      "3",
+			"sequential_thinking.next_thought_needed": "false",
+			"sequential_thinking.is_revision":         "true",
+			"sequential_thinking.revises_thought":     "9",
+			"error.type":                              reasonRevisionTargetNotFound,
+		},
+		{
+			"mcp.method.name":  "tools/call",
+			"mcp.session.id":   defaultSessionID,
+			"gen_ai.tool.name": "get_thought",
+		},
+	}
+	for i, want := range wantAttrs {
+		if diff := cmp.Diff(want, spanAttrValues(spans[i])); diff != "" {
+			t.Errorf("span %d attributes mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+func TestNewOTLPExporter(t *testing.T) {
+	tests := map[string]struct {
+		endpoint string
+		want     string
+		wantErr  bool
+	}{
+		"success: base URL": {
+			endpoint: "http://localhost:4318",
+			want:     "http://localhost:4318/v1/traces",
+		},
+		"success: base URL with slash": {
+			endpoint: "https://collector.example.com/",
+			want:     "https://collector.example.com/v1/traces",
+		},
+		"success: full traces URL": {
+			endpoint: "http://localhost:4318/custom/traces",
+			want:     "http://localhost:4318/custom/traces",
+		},
+		"error: no scheme": {
+			endpoint: "localhost:4318",
+			wantErr:  true,
+		},
+		"error: unsupported scheme": {
+			endpoint: "grpc://localhost:4317",
+			wantErr:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			e, err := newOTLPExporter(tt.endpoint)
+			if diff := cmp.Diff(tt.wantErr, err != nil); diff != "" {
+				t.Fatalf("error presence mismatch %v (-want +got):\n%s", err, diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tt.want, e.url); diff != "" {
+				t.Fatalf("url mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOTLPExporterExportSpans(t *testing.T) {
+	type received struct {
+		path        string
+		contentType string
+		body        otlpRequest
+	}
+	got := make(chan received, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body otlpRequest
+		if err := json.Unmarshal(data, &body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		got <- received{path: r.URL.Path, contentType: r.Header.Get("Content-Type"), body: body}
+	}))
+	t.Cleanup(ts.Close)
+
+	e, err := newOTLPExporter(ts.URL)
+	if err != nil {
+		t.Fatalf("new exporter: %v", err)
+	}
+	sc, _ := parseTraceparent(testTraceparent)
+	start := time.Unix(1700000000, 0)
+	payload, err := encodeSpans([]*span{{
+		sc:    sc,
+		name:  "mcp.session",
+		kind:  spanKindInternal,
+		start: start,
+		end:   start.Add(time.Second),
+		attrs: []spanAttr{{"mcp.session.id", "s1"}},
+	}})
+	if err != nil {
+		t.Fatalf("encode spans: %v", err)
+	}
+	if err := e.exportSpans(t.Context(), payload); err != nil {
+		t.Fatalf("export spans: %v", err)
+	}
+
+	r := <-got
+	if diff := cmp.Diff(otlpTracesPath, r.path); diff != "" {
+		t.Fatalf("path mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("application/json", r.contentType); diff != "" {
+		t.Fatalf("content type mismatch (-want +got):\n%s", diff)
+	}
+	sessionID := "s1"
+	want := []otlpSpan{{
+		TraceID:           "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:            "00f067aa0ba902b7",
+		Flags:             1,
+		Name:              "mcp.session",
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: "1700000000000000000",
+		EndTimeUnixNano:   "1700000001000000000",
+		Attributes:        []otlpKeyValue{{Key: "mcp.session.id", Value: otlpAnyValue{StringValue: &sessionID}}},
+	}}
+	if diff := cmp.Diff(want, r.body.ResourceSpans[0].ScopeSpans[0].Spans); diff != "" {
+		t.Fatalf("spans mismatch (-want +got):\n%s", diff)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(failing.Close)
+	e, err = newOTLPExporter(failing.URL)
+	if err != nil {
+		t.Fatalf("new exporter: %v", err)
+	}
+	if err := e.exportSpans(t.Context(), payload); err == nil {
+		t.Fatal("export to failing collector: got nil error")
+	}
+}
+
+func TestEncodeSpansOTLPJSON(t *testing.T) {
+	sc, _ := parseTraceparent(testTraceparent)
+	link, _ := parseTraceparent(testOtherTraceparent)
+	start := time.Unix(1700000000, 0)
+	payload, err := encodeSpans([]*span{{
+		sc:            spanContext{traceID: sc.traceID, spanID: spanID{1, 2, 3, 4, 5, 6, 7, 8}, flags: sc.flags},
+		parent:        sc.spanID,
+		name:          "tools/call sequentialthinking",
+		kind:          spanKindServer,
+		start:         start,
+		end:           start.Add(time.Millisecond),
+		attrs:         []spanAttr{{"gen_ai.tool.name", "sequentialthinking"}, {"sequential_thinking.thought_number", 2}, {"sequential_thinking.is_revision", true}},
+		links:         []spanContext{link},
+		statusCode:    spanStatusError,
+		statusMessage: "boom",
+	}})
+	if err != nil {
+		t.Fatalf("encode spans: %v", err)
+	}
+
+	// the field names of the OTLP/JSON encoding of ExportTraceServiceRequest, with hex IDs and
+	// 64-bit integers as strings
+	want := map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": []any{
+					map[string]any{"key": "service.name", "value": map[string]any{"stringValue": traceServiceName}},
+				},
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": traceScopeName},
+				"spans": []any{map[string]any{
+					"traceId":           "4bf92f3577b34da6a3ce929d0e0e4736",
+					"spanId":            "0102030405060708",
+					"parentSpanId":      "00f067aa0ba902b7",
+					"flags":             float64(1),
+					"name":              "tools/call sequentialthinking",
+					"kind":              float64(spanKindServer),
+					"startTimeUnixNano": "1700000000000000000",
+					"endTimeUnixNano":   "1700000000001000000",
+					"attributes": []any{
+						map[string]any{"key": "gen_ai.tool.name", "value": map[string]any{"stringValue": "sequentialthinking"}},
+						map[string]any{"key": "sequential_thinking.thought_number", "value": map[string]any{"intValue": "2"}},
+						map[string]any{"key": "sequential_thinking.is_revision", "value": map[string]any{"boolValue": true}},
+					},
+					"links": []any{
+						map[string]any{"traceId": "0af7651916cd43dd8448eb211c80319c", "spanId": "b7ad6b7169203331", "flags": float64(1)},
+					},
+					"status": map[string]any{"code": float64(spanStatusError), "message": "boom"},
+				}},
+			}},
+		}},
+	}
+	var got map[string]any
+	if err := json.Unmarshal(payload, &got); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("OTLP JSON mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTracerSampling(t *testing.T) {
+	tests := map[string]struct {
+		meta      mcp.Meta
+		wantSpans int
+		wantFlags uint32
+	}{
+		"success: sampled traceparent": {
+			meta:      mcp.Meta{traceparentKey: testTraceparent},
+			wantSpans: 3,
+			wantFlags: 1,
+		},
+		"success: no traceparent": {
+			wantSpans: 3,
+			wantFlags: 1,
+		},
+		"success: not sampled traceparent": {
+			meta: mcp.Meta{traceparentKey: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "traces.jsonl")
+			traces, err := newTracer(slog.New(slog.DiscardHandler), "", path)
+			if err != nil {
+				t.Fatalf("new tracer: %v", err)
+			}
+			srv, err := newServer(slog.New(slog.DiscardHandler), NewSequentialThinkingServer(), serverOptions{})
+			if err != nil {
+				t.Fatalf("new server: %v", err)
+			}
+			srv.AddReceivingMiddleware(traces.middleware)
+			serverTransport, clientTransport := mcp.NewInMemoryTransports()
+			ss, err := srv.Connect(t.Context(), serverTransport, nil)
+			if err != nil {
+				t.Fatalf("connect server: %v", err)
+			}
+			t.Cleanup(func() { ss.Close() })
+			client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "v0.0.0"}, nil)
+			cs, err := client.Connect(t.Context(), clientTransport, nil)
+			if err != nil {
+				t.Fatalf("connect client: %v", err)
+			}
+			t.Cleanup(func() { cs.Close() })
+
+			// the second call inherits the sampling decision of the session
+			for _, params := range []*mcp.CallToolParams{
+				{Meta: tt.meta, Name: "sequentialthinking", Arguments: resourceThoughts[0]},
+				{Name: "get_thought", Arguments: GetThoughtInput{ThoughtNumber: 1}},
+			} {
+				if _, err := cs.CallTool(t.Context(), params); err != nil {
+					t.Fatalf("call %s: %v", params.Name, err)
+				}
+			}
+			if err := traces.shutdown(t.Context()); err != nil {
+				t.Fatalf("shutdown tracer: %v", err)
+			}
+
+			var spans []otlpSpan
+			if tt.wantSpans > 0 {
+				spans = readTraceFile(t, path)
+			} else if data, err := os.ReadFile(path); err != nil || len(data) != 0 {
+				t.Fatalf("trace file of a trace not sampled: %q, %v", data, err)
+			}
+			if diff := cmp.Diff(tt.wantSpans, len(spans)); diff != "" {
+				t.Fatalf("spans length mismatch (-want +got):\n%s", diff)
+			}
+			for _, sp := range spans {
+				if diff := cmp.Diff(tt.wantFlags, sp.Flags); diff != "" {
+					t.Fatalf("span %s flags mismatch (-want +got):\n%s", sp.Name, diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ANSI escapes redrawing the tree of a session in place.
+const (
+	// ansiPreviousLine moves the cursor to the start of the line the given number of lines up.
+	ansiPreviousLine = "\x1b[%dF"
+	// ansiClearBelow clears the screen from the cursor to the end.
+	ansiClearBelow = "\x1b[J"
+)
+
+// treeConsole is a [sessionObserver] that draws every session as a tree of its thoughts:
+// branches fork from the thought they branch from, and revisions are linked to the thought they revise.
+//
+// On a terminal that nothing else writes to, the tree of the session that was drawn last is redrawn in
+// place from its first changed line, as long as it fits on the screen. Otherwise the whole tree is
+// written again after every thought.
+type treeConsole struct {
+	mu    sync.Mutex
+	w     io.Writer
+	style consoleStyle
+	trees map[string]*thoughtTree
+	// last is the tree drawn last, whose lines end at the cursor.
+	last *thoughtTree
+}
+
+var _ sessionObserver = (*treeConsole)(nil)
+
+// newTreeConsole returns a tree console writing to w in style.
+func newTreeConsole(w io.Writer, style consoleStyle) *treeConsole {
+	return &treeConsole{
+		w:     w,
+		style: style,
+		trees: make(map[string]*thoughtTree),
+	}
+}
+
+func (c *treeConsole) sessionOpened(string) {}
+
+// sessionClosed implements [sessionObserver].
+func (c *treeConsole) sessionClosed(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.last == c.trees[id] {
+		c.last = nil
+	}
+	delete(c.trees, id)
+}
+
+// thoughtAppended implements [sessionObserver].
+func (c *treeConsole) thoughtAppended(record ThoughtRecord, _ bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, ok := c.trees[record.SessionID]
+	if !ok {
+		t = newThoughtTree(record.SessionID)
+		c.trees[record.SessionID] = t
+	}
+	t.add(record)
+
+	rendered := t.render(c.style)
+	lines := rendered
+	var b strings.Builder
+	// the cursor cannot move up into the scrollback, so the drawn tree and the cursor line must fit on the screen
+	if c.style.redraw && c.last == t && len(t.drawn) < c.style.height {
+		first := 0
+		for first < len(lines) && first < len(t.drawn) && lines[first] == t.drawn[first] {
+			first++
+		}
+		if up := len(t.drawn) - first; up > 0 {
+			fmt.Fprintf(&b, ansiPreviousLine, up)
+		}
+		b.WriteString(ansiClearBelow)
+		lines = lines[first:]
+	} else {
+		b.WriteString("\n")
+	}
+	for _, line := range lines {
+		b.WriteString(line + "\n")
+	}
+	io.WriteString(c.w, b.String())
+
+	t.drawn = rendered
+	c.last = t
+}
+
+// thoughtTree is the tree of the thoughts recorded in a session.
+type thoughtTree struct {
+	id string
+	// main is the sequence of thoughts outside of any branch.
+	main []*treeNode
+	// detached holds the branches whose fork point was not recorded in the session.
+	detached []*treeBranch
+	branches map[string]*treeBranch
+	// nodes holds every thought, in the order recorded.
+	nodes []*treeNode
+	// drawn is the last rendering of the tree written to the console.
+	drawn []string
+}
+
+// treeNode is a thought of a [thoughtTree].
+type treeNode struct {
+	record ThoughtRecord
+	// revisedBy holds the thought numbers of the revisions of the thought.
+	revisedBy []int
+	// branches holds the branches forked from the thought.
+	branches []*treeBranch
+}
+
+// treeBranch is a branch of a [thoughtTree].
+type treeBranch struct {
+	id          string
+	fromThought int
+	nodes       []*treeNode
+}
+
+// newThoughtTree returns the empty tree of the session id.
+func newThoughtTree(id string) *thoughtTree {
+	return &thoughtTree{
+		id:       id,
+		branches: make(map[string]*treeBranch),
+	}
+}
+
+// find returns the latest recorded thought numbered n, preferring the thoughts of the branch
+// branchID, or the main sequence if empty. It returns nil if no thought is numbered n.
+func (t *thoughtTree) find(n int, branchID string) *treeNode {
+	var found *treeNode
+	for i := len(t.nodes) - 1; i >= 0; i-- {
+		if t.nodes[i].record.ThoughtNumber != n {
+			continue
+		}
+		if t.nodes[i].record.BranchID == branchID {
+			return t.nodes[i]
+		}
+		if found == nil {
+			found = t.nodes[i]
+		}
+	}
+	return found
+}
+
+// add places record in the tree.
+func (t *thoughtTree) add(record ThoughtRecord) {
+	node := &treeNode{record: record}
+	if record.IsRevision {
+		if target := t.find(record.RevisesThought, record.BranchID); target != nil {
+			target.revisedBy = append(target.revisedBy, record.ThoughtNumber)
+		}
+	}
+
+	if record.BranchID == "" {
+		t.main = append(t.main, node)
+	} else {
+		b, ok := t.branches[record.BranchID]
+		if !ok {
+			b = &treeBranch{id: record.BranchID, fromThought: record.BranchFromThought}
+			t.branches[record.BranchID] = b
+			if fork := t.find(record.BranchFromThought, ""); fork != nil {
+				fork.branches = append(fork.branches, b)
+			} else {
+				t.detached = append(t.detached, b)
+			}
+		}
+		b.nodes = append(b.nodes, node)
+	}
+	t.nodes = append(t.nodes, node)
+}
+
+// treeItem is a line of a rendered tree and the items nested under it.
+type treeItem struct {
+	label    string
+	color    string
+	text     string
+	children []treeItem
+}
+
+// nodeItem returns the item of the thought n, with its branches nested under it.
+func nodeItem(n *treeNode) treeItem {
+	label, color, _ := thoughtKind(n.record)
+
+	var text strings.Builder
+	fmt.Fprintf(&text, " %d/%d", n.record.ThoughtNumber, n.record.TotalThoughts)
+	if n.record.IsRevision {
+		fmt.Fprintf(&text, " (revises %d)", n.record.RevisesThought)
+	}
+	for _, r := range n.revisedBy {
+		fmt.Fprintf(&text, " (revised by %d)", r)
+	}
+	text.WriteString(": " + strings.Join(strings.Fields(sanitizeText(n.record.Thought)), " "))
+
+	item := treeItem{label: label, color: color, text: text.String()}
+	for _, b := range n.branches {
+		item.children = append(item.children, branchItem(b))
+	}
+	return item
+}
+
+// branchItem returns the item of the branch b, with its thoughts nested under it.
+func branchItem(b *treeBranch) treeItem {
+	item := treeItem{label: "🌿 " + sanitizeText(b.id), color: ansiGreen}
+	if b.fromThought > 0 {
+		item.text = fmt.Sprintf(" (from thought %d)", b.fromThought)
+	}
+	for _, n := range b.nodes {
+		item.children = append(item.children, nodeItem(n))
+	}
+	return item
+}
+
+// render returns the lines drawing the tree in style. Lines are truncated to the style width.
+func (t *thoughtTree) render(style consoleStyle) []string {
+	var items []treeItem
+	for _, n := range t.main {
+		items = append(items, nodeItem(n))
+	}
+	for _, b := range t.detached {
+		items = append(items, branchItem(b))
+	}
+
+	lines := []string{displayWidth.Truncate("🧠 Session "+sanitizeText(t.id), style.width, "…")}
+	return renderItems(lines, "", items, style)
+}
+
+// renderItems appends the lines of items, indented by prefix, to lines.
+func renderItems(lines []string, prefix string, items []treeItem, style consoleStyle) []string {
+	for i, item := range items {
+		connector, indent := "├─ ", "│  "
+		if i == len(items)-1 {
+			connector, indent = "└─ ", "   "
+		}
+
+		line := displayWidth.Truncate(prefix+connector+item.label+item.text, style.width, "…")
+		if lead := prefix + connector + item.label; style.color && strings.HasPrefix(line, lead) {
+			line = prefix + connector + item.color + item.label + ansiReset + strings.TrimPrefix(line, lead)
+		}
+		lines = append(lines, line)
+		lines = renderItems(lines, prefix+indent, item.children, style)
+	}
+	return lines
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// treeRecords are the thoughts of a session with a branch, a revision and a branch from an unknown thought.
+var treeRecords = []ThoughtRecord{
+	{SessionID: "s1", Thought: "Frame the problem", ThoughtNumber: 1, TotalThoughts: 4, NextThoughtNeeded: true},
+	{SessionID: "s1", Thought: "Assume the cache is stale\nand measure it", ThoughtNumber: 2, TotalThoughts: 4, NextThoughtNeeded: true},
+	{SessionID: "s1", Thought: "Try the opposite assumption", ThoughtNumber: 2, TotalThoughts: 4, NextThoughtNeeded: true, BranchFromThought: 1, BranchID: "alt"},
+	{SessionID: "s1", Thought: "The cache is fine, the clock is skewed", ThoughtNumber: 3, TotalThoughts: 4, NextThoughtNeeded: true, BranchID: "alt"},
+	{SessionID: "s1", Thought: "The measurement disproves the stale cache theory entirely, so revise it", ThoughtNumber: 3, TotalThoughts: 4, NextThoughtNeeded: true, IsRevision: true, RevisesThought: 2},
+	{SessionID: "s1", Thought: "Picked up from an earlier run", ThoughtNumber: 5, TotalThoughts: 5, BranchFromThought: 9, BranchID: "resumed"},
+}
+
+func TestThoughtTreeRender(t *testing.T) {
+	tests := map[string]struct {
+		style consoleStyle
+	}{
+		"plain": {
+			style: consoleStyle{width: 60},
+		},
+		"color": {
+			style: consoleStyle{width: 60, height: 24, tty: true, color: true},
+		},
+		"narrow": {
+			style: consoleStyle{width: minConsoleWidth},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tree := newThoughtTree("s1")
+			for _, record := range treeRecords {
+				tree.add(record)
+			}
+			lines := tree.render(tt.style)
+			checkGolden(t, "tree_"+name, strings.Join(lines, "\n")+"\n")
+
+			if !tt.style.color {
+				for _, line := range lines {
+					if w := displayWidth.StringWidth(line); w > tt.style.width {
+						t.Errorf("line %q is %d cells wide, want at most %d", line, w, tt.style.width)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTreeConsole(t *testing.T) {
+	tests := map[string]struct {
+		style  consoleStyle
+		golden string
+	}{
+		"appended": {
+			style:  consoleStyle{width: 60},
+			golden: "tree_console_appended",
+		},
+		"redrawn": {
+			style:  consoleStyle{width: 60, height: 24, tty: true, redraw: true},
+			golden: "tree_console_redrawn",
+		},
+		"appended: terminal shared with the log": {
+			style:  consoleStyle{width: 60, height: 24, tty: true},
+			golden: "tree_console_appended",
+		},
+		"appended: taller than the terminal": {
+			style:  consoleStyle{width: 60, height: 1, tty: true, redraw: true},
+			golden: "tree_console_appended",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var b strings.Builder
+			c := newTreeConsole(&b, tt.style)
+			for _, record := range treeRecords[:3] {
+				c.thoughtAppended(record, false)
+			}
+			// another session interrupts the redrawing of s1
+			c.thoughtAppended(ThoughtRecord{SessionID: "s2", Thought: "Unrelated", ThoughtNumber: 1, TotalThoughts: 1}, false)
+			c.thoughtAppended(treeRecords[3], false)
+			checkGolden(t, tt.golden, b.String())
+		})
+	}
+}
+
+func TestTreeConsoleSessionClosed(t *testing.T) {
+	c := newTreeConsole(&strings.Builder{}, consoleStyle{width: 60, height: 24, tty: true, redraw: true})
+	c.thoughtAppended(treeRecords[0], false)
+	c.sessionClosed("s1")
+
+	if diff := cmp.Diff(0, len(c.trees)); diff != "" {
+		t.Fatalf("trees mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(true, c.last == nil); diff != "" {
+		t.Fatalf("last tree mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// staleSocketDialTimeout bounds the probe that decides whether an existing socket still has a server.
+const staleSocketDialTimeout = time.Second
+
+// parseFileMode parses an octal permission mode such as "0660".
+func parseFileMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode&^uint64(fs.ModePerm) != 0 {
+		return 0, fmt.Errorf("invalid file mode %q: must be octal permission bits such as 0600", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// listenUnix listens on a Unix domain socket at path and sets its file mode.
+//
+// A socket left behind at path by a server that is no longer running is removed first.
+// A socket with a live server, or any other kind of file, at path is an error.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("chmod unix socket %q: %w", path, err)
+	}
+	return ln, nil
+}
+
+// removeStaleSocket removes the socket at path if no server accepts connections on it.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("stat unix socket %q: %w", path, err)
+	}
+	if fi.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("unix socket %q: file exists and is not a socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, staleSocketDialTimeout)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("unix socket %q is in use by another server", path)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("probe unix socket %q: %w", path, err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("remove stale unix socket %q: %w", path, err)
+	}
+	return nil
+}
+
+// listenerURL returns the URL clients use to reach the HTTP server on ln.
+func listenerURL(ln net.Listener, tls bool) string {
+	if ln.Addr().Network() == "unix" {
+		return "unix://" + ln.Addr().String()
+	}
+	if tls {
+		return "https://" + ln.Addr().String()
+	}
+	return "http://" + ln.Addr().String()
+}
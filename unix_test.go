@@ -0,0 +1,215 @@
+// Copyright 2025 The mcp-sequential-thinking Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// socketPath returns a path for a Unix socket in a short temporary directory,
+// since socket paths are limited to about 100 bytes.
+func socketPath(t *testing.T) string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "mcp")
+	if err != nil {
+		t.Fatalf("make temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "thinking.sock")
+}
+
+func TestParseFileMode(t *testing.T) {
+	tests := map[string]struct {
+		s       string
+		want    os.FileMode
+		wantErr bool
+	}{
+		"success: owner only": {
+			s:    "0600",
+			want: 0o600,
+		},
+		"success: group": {
+			s:    "660",
+			want: 0o660,
+		},
+		"error: not octal": {
+			s:       "0689",
+			wantErr: true,
+		},
+		"error: beyond permission bits": {
+			s:       "4755",
+			wantErr: true,
+		},
+		"error: empty": {
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseFileMode(tt.s)
+			if diff := cmp.Diff(tt.wantErr, err != nil); diff != "" {
+				t.Fatalf("error presence mismatch %v (-want +got):\n%s", err, diff)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("mode mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	tests := map[string]struct {
+		setup   func(t *testing.T, path string)
+		wantErr string
+	}{
+		"success: no file": {
+			setup: func(t *testing.T, path string) {},
+		},
+		"success: stale socket": {
+			setup: func(t *testing.T, path string) {
+				ln, err := net.Listen("unix", path)
+				if err != nil {
+					t.Fatalf("listen: %v", err)
+				}
+				ln.(*net.UnixListener).SetUnlinkOnClose(false)
+				ln.Close()
+			},
+		},
+		"error: socket in use": {
+			setup: func(t *testing.T, path string) {
+				ln, err := net.Listen("unix", path)
+				if err != nil {
+					t.Fatalf("listen: %v", err)
+				}
+				t.Cleanup(func() { ln.Close() })
+			},
+			wantErr: "in use",
+		},
+		"error: regular file": {
+			setup: func(t *testing.T, path string) {
+				if err := os.WriteFile(path, []byte("keep me"), 0o600); err != nil {
+					t.Fatalf("write file: %v", err)
+				}
+			},
+			wantErr: "not a socket",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := socketPath(t)
+			tt.setup(t, path)
+
+			ln, err := listenUnix(path, 0o660)
+			if diff := cmp.Diff(tt.wantErr != "", err != nil); diff != "" {
+				t.Fatalf("error presence mismatch %v (-want +got):\n%s", err, diff)
+			}
+			if err != nil {
+				if diff := cmp.Diff(true, strings.Contains(err.Error(), tt.wantErr)); diff != "" {
+					t.Fatalf("error text mismatch %q (-want +got):\n%s", err, diff)
+				}
+				return
+			}
+			defer ln.Close()
+
+			fi, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("stat socket: %v", err)
+			}
+			if diff := cmp.Diff(fs.ModeSocket|0o660, fi.Mode()); diff != "" {
+				t.Fatalf("socket mode mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnixSocketMCP(t *testing.T) {
+	path := socketPath(t)
+	ln, err := listenUnix(path, 0o600)
+	if err != nil {
+		t.Fatalf("listen unix: %v", err)
+	}
+	httpSrv := &http.Server{Handler: newAuthHandler(t, NewSequentialThinkingServer(), &authConfig{})}
+	go httpSrv.Serve(ln)
+	t.Cleanup(func() { httpSrv.Close() })
+
+	if diff := cmp.Diff("unix://"+path, listenerURL(ln, false)); diff != "" {
+		t.Fatalf("listener URL mismatch (-want +got):\n%s", diff)
+	}
+
+	var dialer net.Dialer
+	httpClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		},
+	}}
+	cs, err := connectAuthClient(t, "http://localhost", httpClient)
+	if err != nil {
+		t.Fatalf("connect over unix socket: %v", err)
+	}
+	recordThoughts(t, cs, resourceThoughts[0])
+}
+
+func TestRunUnixError(t *testing.T) {
+	tests := map[string]struct {
+		mode       string
+		regular    bool
+		wantSubstr string
+	}{
+		"error: invalid mode": {
+			mode:       "rw-------",
+			wantSubstr: "-unix-mode",
+		},
+		"error: path is a regular file": {
+			mode:       "0600",
+			regular:    true,
+			wantSubstr: "not a socket",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Cleanup(restoreDefaultLogger(t))
+
+			path := socketPath(t)
+			if tt.regular {
+				if err := os.WriteFile(path, nil, 0o600); err != nil {
+					t.Fatalf("write file: %v", err)
+				}
+			}
+			cfg := defaultConfig()
+			cfg.Transport.Unix = path
+			cfg.Transport.UnixMode = fileMode(tt.mode)
+
+			err := run(cfg)
+			if diff := cmp.Diff(true, err != nil && strings.Contains(err.Error(), tt.wantSubstr)); diff != "" {
+				t.Fatalf("error %v does not contain %q (-want +got):\n%s", err, tt.wantSubstr, diff)
+			}
+		})
+	}
+}